@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bakape/meguca/common"
@@ -13,13 +15,26 @@ type ModLogEntry struct {
 	ID      uint64    `json:"id"`
 	Created time.Time `json:"created"`
 	Board   string    `json:"board"`
+	// Network prefix length of CIDR range bans. Zero for all other entries.
+	Prefix uint8 `json:"prefix"`
 }
 
-// Ban holds an entry of an IP being banned from a board
+// Ban holds an entry of an IP or CIDR range being banned from a board
 type Ban struct {
 	IP, Board string
 }
 
+// Prefix returns the network prefix length of a CIDR range ban or zero for
+// bans of a single IP
+func (b Ban) Prefix() int {
+	i := strings.IndexByte(b.IP, '/')
+	if i == -1 {
+		return 0
+	}
+	p, _ := strconv.Atoi(b.IP[i+1:])
+	return p
+}
+
 // BanRecord stores information about a specific ban
 type BanRecord struct {
 	Ban
@@ -36,8 +51,17 @@ type Report struct {
 	Illegal       bool
 }
 
+// Appeal is a banned user's request to lift a ban together with the ban
+// appealed against
+type Appeal struct {
+	ID      uint64
+	Created time.Time
+	Body    string
+	BanRecord
+}
+
 // DisconnectByBoardAndIP disconnects all banned
-// websocket clients matching IP from board.
+// websocket clients matching IP or CIDR range from board.
 // /all/ board disconnects all clients globally.
 func DisconnectByBoardAndIP(ip, board string) {
 	msg, err := common.EncodeMessage(common.MessageInvalid,
@@ -0,0 +1,124 @@
+// Package backup exports boards into portable archives and imports them into
+// the same or another meguca instance.
+//
+// An archive is a gzipped tarball. Its first entry is a JSON manifest
+// describing the board, its threads, posts and moderation log. It is followed
+// by the uploaded files referenced by the posts, stored under their storage
+// keys prefixed with "images/", with each source file directly followed by
+// its thumbnail.
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bakape/meguca/assets"
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+)
+
+const (
+	// Version of the archive format. Incremented on incompatible changes.
+	formatVersion = 1
+
+	manifestName = "manifest.json"
+	imagePrefix  = "images/"
+)
+
+var errNoManifest = errors.New("backup: archive has no manifest")
+
+// Describes an exported board
+type manifest struct {
+	Version          int                 `json:"version"`
+	Exported         time.Time           `json:"exported"`
+	Created          time.Time           `json:"created"`
+	Board            config.BoardConfigs `json:"board"`
+	Threads          []common.Thread     `json:"threads"`
+	ModLog           []auth.ModLogEntry  `json:"mod_log"`
+	Banners          []assets.File       `json:"banners"`
+	LoadingAnimation assets.File         `json:"loading_animation"`
+}
+
+// Call fn for every post in the archive in thread order
+func (m *manifest) forEachPost(fn func(t *common.Thread, p *common.Post)) {
+	for i := range m.Threads {
+		t := &m.Threads[i]
+		fn(t, &t.Post)
+		for j := range t.Posts {
+			fn(t, &t.Posts[j])
+		}
+	}
+}
+
+// Returns all uploaded files referenced in the archive in order of first use
+func (m *manifest) images() (images []common.ImageCommon) {
+	seen := make(map[string]bool)
+	add := func(img *common.Image) {
+		if !seen[img.SHA1] {
+			seen[img.SHA1] = true
+			images = append(images, img.ImageCommon)
+		}
+	}
+	m.forEachPost(func(_ *common.Thread, p *common.Post) {
+		if p.Image != nil {
+			add(p.Image)
+		}
+		for i := range p.Files {
+			add(&p.Files[i])
+		}
+	})
+	return
+}
+
+// Write a regular file to the archive
+func writeFile(tw *tar.Writer, name string, modTime time.Time, buf []byte,
+) (err error) {
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(buf)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return
+	}
+	_, err = tw.Write(buf)
+	return
+}
+
+func writeManifest(tw *tar.Writer, m manifest) (err error) {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	return writeFile(tw, manifestName, m.Exported, buf)
+}
+
+// Read the manifest from the first entry of the archive
+func readManifest(tr *tar.Reader) (m manifest, err error) {
+	h, err := tr.Next()
+	switch {
+	case err == io.EOF:
+		err = errNoManifest
+		return
+	case err != nil:
+		return
+	case h.Name != manifestName:
+		err = errNoManifest
+		return
+	}
+
+	err = json.NewDecoder(tr).Decode(&m)
+	if err != nil {
+		return
+	}
+	if m.Version != formatVersion {
+		err = fmt.Errorf("backup: unsupported archive version: %d", m.Version)
+	}
+	return
+}
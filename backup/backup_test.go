@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestRemapBody(t *testing.T) {
+	t.Parallel()
+
+	ids := map[uint64]uint64{
+		1: 101,
+		2: 102,
+	}
+	cases := [...]struct {
+		name, in, out string
+	}{
+		{"no links", "foo bar", "foo bar"},
+		{"single link", ">>1", ">>101"},
+		{"multiple links", ">>1 foo >>2\n>>1", ">>101 foo >>102\n>>101"},
+		{"unknown post", ">>3 >>1", ">>3 >>101"},
+		{"longer ID", ">>12", ">>12"},
+		{"cross-board link", ">>>/a/1", ">>>/a/1"},
+		{"quote", ">>foo>>", ">>foo>>"},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			AssertEquals(t, remapBody(c.in, ids), c.out)
+		})
+	}
+}
+
+func TestRemapLinks(t *testing.T) {
+	t.Parallel()
+
+	ids := map[uint64]uint64{
+		1: 101,
+		2: 102,
+	}
+	links := []common.Link{
+		{ID: 2, OP: 1, Board: "a"},
+		{ID: 3, OP: 3, Board: "c"},
+	}
+	AssertEquals(t, remapLinks(links, ids, "b"), []common.Link{
+		{ID: 102, OP: 101, Board: "b"},
+	})
+}
+
+func TestValidateBoardID(t *testing.T) {
+	t.Parallel()
+
+	for _, id := range [...]string{"a", "abc123"} {
+		if err := validateBoardID(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range [...]string{"", "all", "api", "A", "a/../b",
+		"abcdefghijk"} {
+		if err := validateBoardID(id); err == nil {
+			t.Fatalf("expected error for %q", id)
+		}
+	}
+}
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	std := manifest{
+		Version:  formatVersion,
+		Exported: time.Now().UTC().Round(time.Second),
+		Threads: []common.Thread{
+			{
+				Subject: "foo",
+				Post: common.Post{
+					ID:   1,
+					Body: "bar",
+				},
+				Posts: []common.Post{
+					{
+						ID:   2,
+						Body: ">>1",
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := writeManifest(tw, std); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readManifest(tar.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, m.Threads[0].Posts[0].Body, ">>1")
+	AssertEquals(t, m.Exported, std.Exported)
+}
+
+func TestManifestVersion(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := writeManifest(tw, manifest{Version: formatVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+
+	if _, err := readManifest(tar.NewReader(&buf)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNoManifest(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := writeFile(tw, "images/src/foo.jpg", time.Now(), []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+
+	_, err := readManifest(tar.NewReader(&buf))
+	AssertEquals(t, err, errNoManifest)
+}
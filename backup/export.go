@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+	imgassets "github.com/bakape/meguca/imager/assets"
+)
+
+// Export writes a board with all its threads, including archived ones, as an
+// archive to w
+func Export(w io.Writer, board string) (err error) {
+	m, err := readBoard(board)
+	if err != nil {
+		return
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	err = writeManifest(tw, m)
+	if err != nil {
+		return
+	}
+	for _, img := range m.images() {
+		err = exportImage(tw, img, m.Exported)
+		if err != nil {
+			return
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return
+	}
+	return gw.Close()
+}
+
+// Read all exported board data from the database
+func readBoard(board string) (m manifest, err error) {
+	m = manifest{
+		Version:  formatVersion,
+		Exported: time.Now().UTC(),
+	}
+
+	m.Board, err = db.GetBoardConfigs(board)
+	if err != nil {
+		return
+	}
+	m.Created, err = db.GetBoardCreationTime(board)
+	if err != nil {
+		return
+	}
+
+	var ids []uint64
+	b, err := db.GetBoardCatalog(board)
+	if err != nil {
+		return
+	}
+	for _, t := range b.Threads {
+		ids = append(ids, t.ID)
+	}
+	for page := 0; ; page++ {
+		b, err = db.GetArchive(board, page)
+		if err != nil {
+			return
+		}
+		for _, t := range b.Threads {
+			ids = append(ids, t.ID)
+		}
+		if page+1 >= b.Pages {
+			break
+		}
+	}
+	m.Threads = make([]common.Thread, 0, len(ids))
+	for _, id := range ids {
+		var t common.Thread
+		t, err = db.GetThread(id, 0)
+		if err != nil {
+			return
+		}
+		m.Threads = append(m.Threads, t)
+	}
+
+	m.ModLog, err = db.GetModLog(board)
+	if err != nil {
+		return
+	}
+	m.Banners, err = db.GetBanners(board)
+	if err != nil {
+		return
+	}
+	m.LoadingAnimation, err = db.GetLoadingAnimation(board)
+	return
+}
+
+// Write the source file and thumbnail of an upload to the archive. Files
+// missing from storage are skipped.
+func exportImage(tw *tar.Writer, img common.ImageCommon, modTime time.Time,
+) (err error) {
+	for i, key := range imgassets.FileKeys(img.SHA1, img.FileType,
+		img.ThumbType) {
+		if i == 1 && img.ThumbType == common.NoFile {
+			break
+		}
+
+		var buf []byte
+		buf, err = readAsset(key)
+		switch {
+		case os.IsNotExist(err):
+			err = nil
+			continue
+		case err != nil:
+			return
+		}
+		err = writeFile(tw, imagePrefix+key, modTime, buf)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func readAsset(key string) (buf []byte, err error) {
+	r, err := imgassets.Open(key)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
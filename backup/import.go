@@ -0,0 +1,424 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/util"
+)
+
+var (
+	boardIDValidation = regexp.MustCompile(`^[a-z0-9]{1,10}$`)
+	sha1Validation    = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// Post to be imported together with its parent thread
+type importedPost struct {
+	thread *common.Thread
+	post   *common.Post
+}
+
+// Identifies moderation applied to a post for matching moderation log
+// entries to post moderation
+type moderationKey struct {
+	post uint64
+	common.ModerationEntry
+}
+
+// Import restores a board from an archive read from r. The board is created
+// under the ID board or, if empty, under its original ID and assigned owner as
+// its owner. All posts are assigned new IDs. Posts linking to posts outside
+// the archive lose these links.
+func Import(r io.Reader, board, owner string) (err error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	m, err := readManifest(tr)
+	if err != nil {
+		return
+	}
+	if board == "" {
+		board = m.Board.ID
+	}
+	err = validateBoardID(board)
+	if err != nil {
+		return
+	}
+
+	conf := m.Board
+	conf.ID = board
+	err = db.InTransaction(false, func(tx *sql.Tx) (err error) {
+		err = db.WriteBoard(tx, db.BoardConfigs{
+			BoardConfigs: conf,
+			Created:      m.Created,
+		})
+		switch {
+		case err == nil:
+		case db.IsConflictError(err):
+			return fmt.Errorf("backup: board already exists: %s", board)
+		default:
+			return
+		}
+		return db.WriteStaff(tx, board, map[common.ModerationLevel][]string{
+			common.BoardOwner: {owner},
+		})
+	})
+	if err != nil {
+		return
+	}
+
+	// Don't leave partially imported boards behind
+	defer func() {
+		if err == nil {
+			return
+		}
+		if delErr := db.DeleteBoard(board, owner); delErr != nil {
+			err = util.WrapError(err.Error(), delErr)
+		}
+	}()
+
+	err = importImages(tr, m.images())
+	if err != nil {
+		return
+	}
+	ids, err := importPosts(&m, board)
+	if err != nil {
+		return
+	}
+	err = importModeration(&m, board, ids)
+	if err != nil {
+		return
+	}
+
+	// Restore bump order, after it was changed by inserting posts
+	for _, t := range m.Threads {
+		err = db.SetThreadBumpTime(ids[t.ID], t.BumpTime)
+		if err != nil {
+			return
+		}
+	}
+
+	err = db.SetBanners(board, m.Banners)
+	if err != nil {
+		return
+	}
+	return db.SetLoadingAnimation(board, m.LoadingAnimation)
+}
+
+func validateBoardID(id string) error {
+	switch id {
+	case "html", "json", "api", "assets", "all", "b":
+	default:
+		if boardIDValidation.MatchString(id) {
+			return nil
+		}
+	}
+	return fmt.Errorf("backup: invalid board ID: %s", id)
+}
+
+// Allocate uploaded files read from the remainder of the archive, that are
+// referenced by posts and not yet present on this instance
+func importImages(tr *tar.Reader, images []common.ImageCommon) (err error) {
+	bySHA1 := make(map[string]common.ImageCommon, len(images))
+	for _, img := range images {
+		if validImage(img) {
+			bySHA1[img.SHA1] = img
+		}
+	}
+
+	// Source files are buffered until their thumbnail is read
+	var (
+		pending    common.ImageCommon
+		pendingSrc []byte
+	)
+	flush := func(thumb []byte) error {
+		if pendingSrc == nil {
+			return nil
+		}
+		src := pendingSrc
+		pendingSrc = nil
+		return allocateImage(pending, src, thumb)
+	}
+
+	for {
+		var h *tar.Header
+		h, err = tr.Next()
+		switch {
+		case err == io.EOF:
+			return flush(nil)
+		case err != nil:
+			return
+		}
+
+		dir, file := path.Split(strings.TrimPrefix(h.Name, imagePrefix))
+		img, ok := bySHA1[strings.TrimSuffix(file, path.Ext(file))]
+		if !ok || h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		var buf []byte
+		buf, err = ioutil.ReadAll(tr)
+		if err != nil {
+			return
+		}
+		switch dir {
+		case "src/":
+			err = flush(nil)
+			if err != nil {
+				return
+			}
+			pending = img
+			pendingSrc = buf
+		case "thumb/":
+			if pendingSrc != nil && pending.SHA1 == img.SHA1 {
+				err = flush(buf)
+				if err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Guard against manifests, that would produce invalid storage keys
+func validImage(img common.ImageCommon) bool {
+	if !sha1Validation.MatchString(img.SHA1) {
+		return false
+	}
+	_, ok := common.Extensions[img.FileType]
+	return ok
+}
+
+func allocateImage(img common.ImageCommon, src, thumb []byte) error {
+	return db.InTransaction(false, func(tx *sql.Tx) (err error) {
+		exists, err := db.ImageExists(tx, img.SHA1)
+		if err != nil || exists {
+			return
+		}
+		var thumbR io.ReadSeeker
+		if thumb != nil {
+			thumbR = bytes.NewReader(thumb)
+		}
+		return db.AllocateImage(tx, bytes.NewReader(src), thumbR, img)
+	})
+}
+
+// Write all threads and posts to board under new post IDs in their original
+// order. Returns a map of original to new post IDs.
+func importPosts(m *manifest, board string) (
+	ids map[uint64]uint64, err error,
+) {
+	var posts []importedPost
+	m.forEachPost(func(t *common.Thread, p *common.Post) {
+		posts = append(posts, importedPost{t, p})
+	})
+	// Links and thread OPs always precede the posts referencing them
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].post.ID < posts[j].post.ID
+	})
+
+	ids = make(map[uint64]uint64, len(posts))
+	for _, p := range posts {
+		ids[p.post.ID], err = db.NewPostID()
+		if err != nil {
+			return
+		}
+	}
+
+	// Images already present on this instance need not be in the archive
+	hasImage := make(map[string]bool)
+	err = db.InTransaction(true, func(tx *sql.Tx) (err error) {
+		check := func(sha1 string) (err error) {
+			if _, ok := hasImage[sha1]; !ok {
+				hasImage[sha1], err = db.ImageExists(tx, sha1)
+			}
+			return
+		}
+		for _, p := range posts {
+			if p.post.Image != nil {
+				err = check(p.post.Image.SHA1)
+				if err != nil {
+					return
+				}
+			}
+			for _, f := range p.post.Files {
+				err = check(f.SHA1)
+				if err != nil {
+					return
+				}
+			}
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	for _, p := range posts {
+		post := db.Post{
+			StandalonePost: common.StandalonePost{
+				Post:  *p.post,
+				OP:    ids[p.thread.ID],
+				Board: board,
+			},
+		}
+		post.ID = ids[p.post.ID]
+		// Open posts can not be carried over
+		post.Editing = false
+		post.Body = remapBody(post.Body, ids)
+		post.Links = remapLinks(post.Links, ids, board)
+		if post.Image != nil && !hasImage[post.Image.SHA1] {
+			post.Image = nil
+		}
+		post.Files = nil
+		for _, f := range p.post.Files {
+			if hasImage[f.SHA1] {
+				post.Files = append(post.Files, f)
+			}
+		}
+
+		if p.post.ID == p.thread.ID {
+			t := p.thread
+			err = db.WriteThread(db.Thread{
+				ID:         post.ID,
+				Board:      board,
+				Subject:    t.Subject,
+				UpdateTime: t.UpdateTime,
+				BumpTime:   t.BumpTime,
+				Sticky:     t.Sticky,
+				Locked:     t.Locked,
+				Archived:   t.Archived,
+			}, post)
+		} else {
+			err = db.InTransaction(false, func(tx *sql.Tx) error {
+				return db.WritePost(tx, post)
+			})
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Write the moderation log and any post moderation not recorded in it
+func importModeration(m *manifest, board string, ids map[uint64]uint64,
+) error {
+	return db.InTransaction(false, func(tx *sql.Tx) (err error) {
+		logged := make(map[moderationKey]int, len(m.ModLog))
+
+		// Exported newest first
+		for i := len(m.ModLog) - 1; i >= 0; i-- {
+			e := m.ModLog[i]
+			e.Board = board
+			if e.ID != 0 {
+				id, ok := ids[e.ID]
+				if ok {
+					// Also applies the moderation to the post
+					logged[moderationKey{e.ID, e.ModerationEntry}]++
+				}
+				e.ID = id
+			}
+			err = db.WriteModLogEntry(tx, e)
+			if err != nil {
+				return
+			}
+		}
+
+		// Such as automatic deletion of posts by shadow banned IPs
+		m.forEachPost(func(_ *common.Thread, p *common.Post) {
+			for _, e := range p.Moderation {
+				if err != nil {
+					return
+				}
+				k := moderationKey{p.ID, e}
+				if logged[k] != 0 {
+					logged[k]--
+					continue
+				}
+				err = db.WritePostModeration(tx, ids[p.ID], e)
+			}
+		})
+		return
+	})
+}
+
+// Rewrite >>links to posts in the archive to their new IDs
+func remapBody(body string, ids map[uint64]uint64) string {
+	var (
+		w    strings.Builder
+		last int
+	)
+	for i := 0; i < len(body); {
+		j := strings.Index(body[i:], ">>")
+		if j == -1 {
+			break
+		}
+		i += j + 2
+
+		// Skip cross-board >>>/board/ links and longer quote chains
+		if i > 2 && body[i-3] == '>' {
+			continue
+		}
+		end := i
+		for end < len(body) && body[end] >= '0' && body[end] <= '9' {
+			end++
+		}
+		if end == i {
+			continue
+		}
+		id, err := strconv.ParseUint(body[i:end], 10, 64)
+		if err != nil {
+			i = end
+			continue
+		}
+		if newID, ok := ids[id]; ok {
+			w.WriteString(body[last:i])
+			w.WriteString(strconv.FormatUint(newID, 10))
+			last = end
+		}
+		i = end
+	}
+	if last == 0 {
+		return body
+	}
+	w.WriteString(body[last:])
+	return w.String()
+}
+
+// Rewrite links to posts in the archive to their new IDs and drop all others
+func remapLinks(links []common.Link, ids map[uint64]uint64, board string,
+) []common.Link {
+	if len(links) == 0 {
+		return nil
+	}
+	remapped := make([]common.Link, 0, len(links))
+	for _, l := range links {
+		id, ok := ids[l.ID]
+		if !ok {
+			continue
+		}
+		remapped = append(remapped, common.Link{
+			ID:    id,
+			OP:    ids[l.OP],
+			Board: board,
+		})
+	}
+	return remapped
+}
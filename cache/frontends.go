@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"sort"
 
 	"github.com/bakape/meguca/common"
 	"github.com/bakape/meguca/config"
@@ -11,6 +12,14 @@ import (
 	"github.com/bakape/meguca/templates"
 )
 
+const (
+	// Number of threads per board page, if none is configured
+	defaultThreadsPerPage = 15
+
+	// Maximum number of threads in a board's Atom feed
+	boardFeedSize = 30
+)
+
 // ErrPageOverflow is an error detailing that the page was not found
 var ErrPageOverflow = errors.New("page not found")
 
@@ -87,7 +96,8 @@ var CatalogFEMod = FrontEnd{
 	},
 }
 
-// BoardFE is for accessing cached board pages
+// BoardFE is for accessing the cached thread ID order of a board, split into
+// pages
 var BoardFE = FrontEnd{
 	GetCounter: func(k Key) (uint64, error) {
 		if k.Board == "all" {
@@ -98,8 +108,6 @@ var BoardFE = FrontEnd{
 		return db.BoardCounter(k.Board)
 	},
 
-	// Board pages are built as a list of individually fetched and cached
-	// threads with up to 5 replies each
 	GetFresh: func(k Key) (interface{}, error) {
 		// Get thread IDs in the right order
 		var (
@@ -116,102 +124,13 @@ var BoardFE = FrontEnd{
 		if err != nil {
 			return nil, err
 		}
-
-		// Empty board
-		if len(ids) == 0 {
-			data := common.Board{Threads: []common.Thread{}}
-			buf, err := json.Marshal(data)
-			if err != nil {
-				return nil, err
-			}
-			return []PageStore{
-				{
-					PageNumber: 1,
-					JSON:       buf,
-					Data:       data,
-				},
-			}, nil
-		}
-
-		// Get data and JSON for these views and paginate
-		var (
-			pages = make([]PageStore, 0, len(ids)/15+1)
-			page  PageStore
-		)
-		closePage := func() {
-			if page.Data.Threads != nil {
-				pages = append(pages, page)
-			}
-		}
-
-		// Hide threads from NSFW boards, if enabled
-		var (
-			confs    map[string]config.BoardConfContainer
-			hideNSFW bool
-		)
-		if (k.Board == "all" || k.Board == "b") && config.Get().HideNSFW {
-			hideNSFW = true
-			confs = config.GetAllBoardConfigs()
-		}
-
-		for i, id := range ids {
-			// Start a new page
-			if i%15 == 0 {
-				closePage()
-				page = PageStore{
-					PageNumber: len(pages),
-					Data: common.Board{
-						Threads: make([]common.Thread, 0, 15),
-					},
-				}
-			}
-
-			k := ThreadKey(id, 5)
-			_, data, _, err := GetJSONAndData(k, ThreadFE)
-			if err != nil {
-				return nil, err
-			}
-			t := data.(common.Thread)
-
-			if hideNSFW && confs[t.Board].NSFW {
-				continue
-			}
-
-			page.Data.Threads = append(page.Data.Threads, t)
-		}
-		closePage()
-
-		// Record total page count in all stores and generate JSON
-		l := len(pages)
-		if l == 0 { // Empty board
-			l = 1
-			pages = []PageStore{
-				{
-					JSON: []byte(`{"threads":[],"pages":1}`),
-				},
-			}
-		}
-		for i := range pages {
-			p := &pages[i]
-			p.Data.Pages = l
-			p.JSON, err = json.Marshal(p.Data)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		return pages, nil
-	},
-
-	Size: func(data interface{}, _, _ []byte) (s int) {
-		for _, p := range data.([]PageStore) {
-			s += len(p.JSON) * 2
-		}
-		return
+		return paginate(ids, threadsPerPage()), nil
 	},
 }
 
-// BoardPageFE is for individual pages of a board index page
+// BoardPageFE is for individual pages of a board index page. Pages are built
+// as a list of individually fetched and cached threads with up to 5 replies
+// each.
 var BoardPageFE = FrontEnd{
 	GetCounter: func(k Key) (uint64, error) {
 		// Get the counter of the parent board
@@ -228,11 +147,30 @@ var BoardPageFE = FrontEnd{
 			return nil, err
 		}
 
-		pages := data.([]PageStore)
-		if i > len(pages)-1 {
+		pages := data.([][]uint64)
+		if i < 0 || i > len(pages)-1 {
 			return nil, ErrPageOverflow
 		}
-		return pages[i], nil
+
+		page := PageStore{
+			PageNumber: i,
+			Data: common.Board{
+				Pages:   len(pages),
+				Threads: make([]common.Thread, 0, len(pages[i])),
+			},
+		}
+		for _, id := range pages[i] {
+			_, data, _, err := GetJSONAndData(ThreadKey(id, 5), ThreadFE)
+			if err != nil {
+				return nil, err
+			}
+			page.Data.Threads = append(page.Data.Threads, data.(common.Thread))
+		}
+		page.JSON, err = json.Marshal(page.Data)
+		if err != nil {
+			return nil, err
+		}
+		return page, nil
 	},
 
 	EncodeJSON: func(data interface{}) ([]byte, error) {
@@ -245,9 +183,81 @@ var BoardPageFE = FrontEnd{
 		return b.Bytes()
 	},
 
-	Size: func(_ interface{}, _, html []byte) int {
-		// Only the HTML is owned by this store. All other data is just
-		// borrowed from board
-		return len(html)
+	Size: func(_ interface{}, json, html []byte) int {
+		// Thread data is borrowed from the thread stores
+		return len(json) + len(html)
+	},
+}
+
+// FeedFE is for accessing cached Atom feeds of boards and threads. Board feeds
+// contain the newest threads of the board and thread feeds the newest replies
+// of the thread. Feeds are rendered on retrieval, as rendering can fail.
+var FeedFE = FrontEnd{
+	GetCounter: func(k Key) (uint64, error) {
+		if k.ID != 0 {
+			return db.ThreadCounter(k.ID)
+		}
+		return CatalogFE.GetCounter(k)
+	},
+
+	GetFresh: func(k Key) (interface{}, error) {
+		if k.ID != 0 {
+			_, data, _, err := GetJSONAndData(ThreadKey(k.ID, 100), ThreadFE)
+			if err != nil {
+				return nil, err
+			}
+			return templates.ThreadFeed(data.(common.Thread))
+		}
+
+		_, data, _, err := GetJSONAndData(BoardKey(k.Board, 0, false),
+			CatalogFE)
+		if err != nil {
+			return nil, err
+		}
+
+		// Copy to not mutate the catalog's cached thread order
+		cat := data.(common.Board).Threads
+		threads := make([]common.Thread, len(cat))
+		copy(threads, cat)
+		sort.Slice(threads, func(i, j int) bool {
+			return threads[i].ID > threads[j].ID
+		})
+		if len(threads) > boardFeedSize {
+			threads = threads[:boardFeedSize]
+		}
+		return templates.BoardFeed(k.Board, threads)
+	},
+
+	EncodeJSON: func(_ interface{}) ([]byte, error) {
+		// Feeds are only ever served as XML
+		return nil, nil
+	},
+
+	RenderHTML: func(data interface{}, _ []byte) []byte {
+		return data.([]byte)
+	},
+
+	Size: func(_ interface{}, _, xml []byte) int {
+		// Data and HTML are the same rendered feed
+		return len(xml)
 	},
 }
+
+// Returns the configured number of threads per board page
+func threadsPerPage() int {
+	if n := config.Get().ThreadsPerPage; n != 0 {
+		return int(n)
+	}
+	return defaultThreadsPerPage
+}
+
+// Split thread IDs into pages of n threads. An empty board still has a
+// single empty page.
+func paginate(ids []uint64, n int) [][]uint64 {
+	pages := make([][]uint64, 0, len(ids)/n+1)
+	for len(ids) > n {
+		pages = append(pages, ids[:n])
+		ids = ids[n:]
+	}
+	return append(pages, ids)
+}
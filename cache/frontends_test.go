@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"testing"
+
+	. "github.com/bakape/meguca/test"
+)
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+
+	cases := [...]struct {
+		name string
+		ids  []uint64
+		std  [][]uint64
+	}{
+		{
+			name: "empty board",
+			ids:  []uint64{},
+			std:  [][]uint64{{}},
+		},
+		{
+			name: "partial page",
+			ids:  []uint64{1, 2},
+			std:  [][]uint64{{1, 2}},
+		},
+		{
+			name: "full page",
+			ids:  []uint64{1, 2, 3},
+			std:  [][]uint64{{1, 2, 3}},
+		},
+		{
+			name: "multiple pages",
+			ids:  []uint64{1, 2, 3, 4, 5, 6, 7},
+			std:  [][]uint64{{1, 2, 3}, {4, 5, 6}, {7}},
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			AssertEquals(t, paginate(c.ids, 3), c.std)
+		})
+	}
+}
@@ -51,6 +51,7 @@ func getData(s *store, f FrontEnd) (
 	if s.data != nil {
 		if s.isFresh() {
 			// No freshness check needed yet
+			hits.Inc()
 			return s.data, s.json, s.updateCounter, false, nil
 		}
 		ctr, err = f.GetCounter(s.key)
@@ -60,10 +61,12 @@ func getData(s *store, f FrontEnd) (
 		if ctr == s.updateCounter {
 			// Still fresh
 			s.lastChecked = time.Now()
+			hits.Inc()
 			return s.data, s.json, s.updateCounter, false, nil
 		}
 	}
 
+	misses.Inc()
 	fresh = true
 	if ctr == 0 {
 		ctr, err = f.GetCounter(s.key)
@@ -142,3 +145,13 @@ func BoardKey(b string, page int64, index bool) Key {
 		LastN: lastN,
 	}
 }
+
+// FeedKey encodes a key for the Atom feed of a board or, if thread is not 0,
+// of a thread on that board
+func FeedKey(board string, thread uint64) Key {
+	return Key{
+		Feed:  true,
+		Board: board,
+		ID:    thread,
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/metrics"
 )
 
 // Time for the cache to expire and need counter comparison
@@ -18,11 +19,29 @@ var (
 	ll        = list.New()
 	totalUsed int
 	mu        sync.Mutex
+
+	hits, misses metrics.Counter
 )
 
+func init() {
+	metrics.Register("meguca_cache_hits_total",
+		"Cache lookups served without fetching fresh data", &hits)
+	metrics.Register("meguca_cache_misses_total",
+		"Cache lookups, that required fetching fresh data", &misses)
+	metrics.Register("meguca_cache_used_bytes",
+		"Estimated memory used by the cache",
+		metrics.GaugeFunc(func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return float64(totalUsed)
+		}))
+}
+
 // Key stores the ID of either a thread or board page
 type Key struct {
 	LastN uint8
+	// Resource is an Atom feed of a board or thread
+	Feed  bool
 	Board string
 	ID    uint64
 	Page  int64
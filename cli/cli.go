@@ -0,0 +1,446 @@
+// Package cli implements subcommands of the meguca binary for offline
+// maintenance, that run without starting the web server
+package cli
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/backup"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/imager/assets"
+	mlog "github.com/bakape/meguca/log"
+)
+
+// Account recorded in the moderation log for actions performed through the
+// command line
+const operator = "admin"
+
+var errNeedsMigration = errors.New(
+	"database schema missing or outdated: run `meguca migrate` first")
+
+// Subcommand of the meguca binary
+type command struct {
+	usage, description string
+	// Skip asserting the database schema is up to date before running
+	skipVersionCheck bool
+	run              func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"migrate": {
+		description:      "initialize or upgrade the database schema",
+		skipVersionCheck: true,
+		run: func(fs *flag.FlagSet, args []string) error {
+			if err := fs.Parse(args); err != nil {
+				return err
+			}
+			return db.Migrate()
+		},
+	},
+	"create-admin": {
+		usage:       "[-password password]",
+		description: "create the admin account",
+		run:         createAdmin,
+	},
+	"reset-password": {
+		usage:       "-account id -password password",
+		description: "set a new password for an account",
+		run:         resetPassword,
+	},
+	"ban": {
+		usage: "-board board -post id -duration 48h -reason reason " +
+			"[-ipv4-prefix 24] [-ipv6-prefix 64]",
+		description: "ban the author of a post from a board",
+		run:         ban,
+	},
+	"unban": {
+		usage:       "-board board -post id",
+		description: "lift a ban issued for a post",
+		run:         unban,
+	},
+	"delete-board": {
+		usage:       "-board board",
+		description: "delete a board and all of its threads",
+		run:         deleteBoard,
+	},
+	"export-board": {
+		usage:       "-board board [-o file]",
+		description: "write a board with all its threads as an archive",
+		run:         exportBoard,
+	},
+	"import-board": {
+		usage:       "[-board board] [-owner account] [-i file]",
+		description: "restore a board from an archive",
+		run:         importBoard,
+	},
+	"prune-images": {
+		description: "delete image files not used in any posts",
+		run: func(fs *flag.FlagSet, args []string) (err error) {
+			if err = fs.Parse(args); err != nil {
+				return
+			}
+			err = assets.Init()
+			if err != nil {
+				return
+			}
+			return db.DeleteUnusedImages()
+		},
+	},
+	"export-config": {
+		usage:       "[-o file]",
+		description: "write global server configuration as JSON",
+		run:         exportConfig,
+	},
+	"import-config": {
+		usage:       "[-i file]",
+		description: "overwrite global server configuration from JSON",
+		run:         importConfig,
+	},
+}
+
+// IsCommand returns, if name is a maintenance subcommand
+func IsCommand(name string) bool {
+	if name == "help" {
+		return true
+	}
+	_, ok := commands[name]
+	return ok
+}
+
+// Run executes the subcommand named by args[0] with the remaining arguments
+func Run(args []string) (err error) {
+	name := args[0]
+	cmd, ok := commands[name]
+	if !ok {
+		printUsage(os.Stderr)
+		if name == "help" {
+			return nil
+		}
+		return fmt.Errorf("unknown command: %s", name)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: meguca %s %s\n\n%s\n", name,
+			cmd.usage, cmd.description)
+		fs.PrintDefaults()
+	}
+
+	err = config.Server.Load()
+	if err != nil {
+		return
+	}
+	mlog.Init(mlog.Console)
+	err = db.Connect()
+	if err != nil {
+		return
+	}
+	if !cmd.skipVersionCheck {
+		var need bool
+		need, err = db.NeedsMigration()
+		if err != nil {
+			return
+		}
+		if need {
+			return errNeedsMigration
+		}
+	}
+
+	err = cmd.run(fs, args[1:])
+	if err == flag.ErrHelp {
+		err = nil
+	}
+	return
+}
+
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: meguca [command] [arguments]")
+	fmt.Fprintln(w, "\nStarts the web server, if no command is given.")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, n := range names {
+		fmt.Fprintf(w, "  %-16s%s\n", n, commands[n].description)
+	}
+}
+
+// Parse flags and assert all of required are set
+func parseFlags(fs *flag.FlagSet, args []string, required ...string,
+) (err error) {
+	err = fs.Parse(args)
+	if err != nil {
+		return
+	}
+	set := make(map[string]bool, fs.NFlag())
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, r := range required {
+		if !set[r] {
+			fs.Usage()
+			return fmt.Errorf("missing required flag: -%s", r)
+		}
+	}
+	return
+}
+
+func createAdmin(fs *flag.FlagSet, args []string) (err error) {
+	password := fs.String("password", "",
+		"password to set instead of the default one")
+	err = parseFlags(fs, args)
+	if err != nil {
+		return
+	}
+
+	err = db.InTransaction(false, func(tx *sql.Tx) error {
+		return db.CreateAdminAccount(tx)
+	})
+	if err != nil || *password == "" {
+		return
+	}
+	return setPassword("admin", *password)
+}
+
+func resetPassword(fs *flag.FlagSet, args []string) (err error) {
+	var (
+		account  = fs.String("account", "", "ID of the account")
+		password = fs.String("password", "", "new password")
+	)
+	err = parseFlags(fs, args, "account", "password")
+	if err != nil {
+		return
+	}
+
+	// Assert account exists
+	_, err = db.GetPassword(*account)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("account not found: %s", *account)
+		}
+		return
+	}
+	err = setPassword(*account, *password)
+	if err != nil {
+		return
+	}
+	// Existing sessions were authenticated with the old password
+	return db.LogOutAll(*account)
+}
+
+func setPassword(account, password string) (err error) {
+	hash, err := auth.BcryptHash(password, 10)
+	if err != nil {
+		return
+	}
+	return db.ChangePassword(account, hash)
+}
+
+func ban(fs *flag.FlagSet, args []string) (err error) {
+	var (
+		board    = fs.String("board", "all", "board to ban from")
+		post     = fs.Uint64("post", 0, "ID of post, whose author to ban")
+		duration = fs.Duration("duration", 0, "duration of the ban")
+		reason   = fs.String("reason", "", "reason for the ban")
+		ipv4     = fs.Int("ipv4-prefix", 0,
+			"ban the IPv4 range of this network prefix length")
+		ipv6 = fs.Int("ipv6-prefix", 0,
+			"ban the IPv6 range of this network prefix length")
+	)
+	err = parseFlags(fs, args, "post", "duration", "reason")
+	if err != nil {
+		return
+	}
+	if *duration <= 0 {
+		return errors.New("ban duration must be positive")
+	}
+	if *board != "all" {
+		if err = assertBoard(*board); err != nil {
+			return
+		}
+	}
+
+	return db.BanRange(*board, *reason, operator, *duration, *post, *ipv4,
+		*ipv6)
+}
+
+func unban(fs *flag.FlagSet, args []string) (err error) {
+	var (
+		board = fs.String("board", "all", "board to lift the ban from")
+		post  = fs.Uint64("post", 0, "ID of post the ban was issued for")
+	)
+	err = parseFlags(fs, args, "post")
+	if err != nil {
+		return
+	}
+	return db.Unban(*board, *post, operator)
+}
+
+func deleteBoard(fs *flag.FlagSet, args []string) (err error) {
+	board := fs.String("board", "", "ID of the board")
+	err = parseFlags(fs, args, "board")
+	if err != nil {
+		return
+	}
+	if err = assertBoard(*board); err != nil {
+		return
+	}
+	return db.DeleteBoard(*board, operator)
+}
+
+// Assert board exists in the database. The board config cache is not loaded
+// in offline mode.
+func assertBoard(board string) (err error) {
+	_, err = db.GetBoardConfigs(board)
+	if err == sql.ErrNoRows {
+		err = fmt.Errorf("board not found: %s", board)
+	}
+	return
+}
+
+func exportBoard(fs *flag.FlagSet, args []string) (err error) {
+	var (
+		board = fs.String("board", "", "ID of the board")
+		out   = fs.String("o", "", "file to write to instead of stdout")
+	)
+	err = parseFlags(fs, args, "board")
+	if err != nil {
+		return
+	}
+	if err = assertBoard(*board); err != nil {
+		return
+	}
+	err = assets.Init()
+	if err != nil {
+		return
+	}
+
+	if *out == "" {
+		return backup.Export(os.Stdout, *board)
+	}
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	err = backup.Export(f, *board)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return
+}
+
+func importBoard(fs *flag.FlagSet, args []string) (err error) {
+	var (
+		board = fs.String("board", "",
+			"ID to import the board under instead of its original one")
+		owner = fs.String("owner", "admin", "account to assign as board owner")
+		in    = fs.String("i", "", "file to read from instead of stdin")
+	)
+	err = parseFlags(fs, args)
+	if err != nil {
+		return
+	}
+	err = assets.Init()
+	if err != nil {
+		return
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		var f *os.File
+		f, err = os.Open(*in)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		r = f
+	}
+	return backup.Import(r, *board, *owner)
+}
+
+func exportConfig(fs *flag.FlagSet, args []string) (err error) {
+	out := fs.String("o", "", "file to write to instead of stdout")
+	err = parseFlags(fs, args)
+	if err != nil {
+		return
+	}
+
+	conf, err := db.GetConfigs()
+	if err != nil {
+		return
+	}
+	buf, err := json.MarshalIndent(conf, "", "\t")
+	if err != nil {
+		return
+	}
+	buf = append(buf, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(buf)
+		return
+	}
+	return writeFile(*out, buf)
+}
+
+func writeFile(path string, buf []byte) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	_, err = f.Write(buf)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return
+}
+
+func importConfig(fs *flag.FlagSet, args []string) (err error) {
+	in := fs.String("i", "", "file to read from instead of stdin")
+	err = parseFlags(fs, args)
+	if err != nil {
+		return
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		var f *os.File
+		f, err = os.Open(*in)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		r = f
+	}
+
+	conf, err := decodeConfigs(r)
+	if err != nil {
+		return
+	}
+	return db.WriteConfigs(conf)
+}
+
+// Decode and validate exported global configuration
+func decodeConfigs(r io.Reader) (conf config.Configs, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&conf)
+	if err != nil {
+		return
+	}
+	if len(conf.CaptchaTags) < 3 {
+		err = errors.New("too few captcha tags")
+	}
+	return
+}
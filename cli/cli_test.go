@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDecodeConfigs(t *testing.T) {
+	cases := [...]struct {
+		name, in string
+		err      bool
+	}{
+		{"valid", `{"captchaTags":["a","b","c"],"maxSize":10}`, false},
+		{"unknown field", `{"captchaTags":["a","b","c"],"foo":1}`, true},
+		{"too few captcha tags", `{"captchaTags":["a"]}`, true},
+		{"invalid JSON", `{`, true},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			conf, err := decodeConfigs(strings.NewReader(c.in))
+			if c.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conf.MaxSize != 10 {
+				t.Fatalf("unexpected max size: %d", conf.MaxSize)
+			}
+		})
+	}
+}
+
+func TestParseFlagsRequired(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.String("foo", "", "")
+	fs.String("bar", "", "")
+
+	if err := parseFlags(fs, []string{"-foo", "1"}, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseFlags(fs, []string{"-foo", "1"}, "foo", "bar"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -9,6 +9,7 @@ import { incrementPostCount } from "./page"
 import { posterName } from "./options"
 import { OverlayNotification } from "./ui"
 import { setCookie } from './util';
+import lang from './lang'
 
 // Message for splicing the contents of the current line
 export type SpliceResponse = {
@@ -24,6 +25,18 @@ type CloseMessage = {
 	commands: Command[] | null
 }
 
+// Edited body of a closed post
+interface EditMessage extends CloseMessage {
+	body: string
+	edited: number
+}
+
+// Updated vote tallies of a #poll
+type PollVotesMessage = {
+	id: number
+	votes: number[]
+}
+
 // Message for inserting images into an open post
 interface ImageMessage extends ImageData {
 	id: number
@@ -33,6 +46,11 @@ interface ModerationMessage extends ModerationEntry {
 	id: number
 }
 
+// Link to a post of the thread from another board
+interface Backlink extends PostLink {
+	target: number
+}
+
 interface CookieMessage {
 	key: string;
 	value: string;
@@ -102,9 +120,7 @@ export default () => {
 	handlers[message.insertImage] = (msg: ImageMessage) =>
 		handle(msg.id, m => {
 			delete msg.id
-			if (!m.image) {
-				incrementPostCount(false, true)
-			}
+			incrementPostCount(false, true)
 			m.insertImage(msg)
 		})
 
@@ -136,6 +152,20 @@ export default () => {
 			m.closePost()
 		})
 
+	handlers[message.editPost] = (msg: EditMessage) =>
+		handle(msg.id, m => {
+			m.links = msg.links
+			m.commands = msg.commands
+			m.applyEdit(msg.body, msg.edited)
+			if (msg.links) {
+				m.propagateLinks()
+			}
+		})
+
+	handlers[message.votePoll] = (msg: PollVotesMessage) =>
+		handle(msg.id, m =>
+			m.applyPollVotes(msg.votes))
+
 	handlers[message.moderatePost] = (msg: ModerationMessage) =>
 		handle(msg.id, m =>
 			m.applyModeration(msg))
@@ -146,6 +176,13 @@ export default () => {
 	handlers[message.notification] = (text: string) =>
 		new OverlayNotification(text)
 
+	handlers[message.backlink] = ({ id, op, board, target }: Backlink) =>
+		handle(target, m =>
+			m.insertBacklink({ id, op, board }))
+
+	handlers[message.rateLimited] = () =>
+		new OverlayNotification(lang.ui["rateLimited"])
+
 	handlers[message.setCookie] = ({ key, value }: CookieMessage) =>
 		setCookie(key, value, 30)
 }
@@ -23,6 +23,10 @@ export const enum ModerationAction {
 	meidoVision,
 	purgePost,
 	shadowBinPost,
+	acceptAppeal,
+	denyAppeal,
+	selfDeletePost,
+	selfDeleteImage,
 }
 
 // Contains fields of a post moderation log entry
@@ -51,7 +55,9 @@ export interface PostData {
 	sticky: boolean
 	locked: boolean
 	image?: ImageData
+	files?: ImageData[]
 	time: number
+	edited?: number
 	id: number
 	op: number
 	body: string
@@ -80,11 +86,32 @@ export type TextState = {
 	haveSyncwatch: boolean
 	successive_newlines: number
 	iDice: number // Index of the next dice array item to use
+	pollLines: number // Number of following lines rendered by a #poll
 }
 
 // Types of hash command entries
 export const enum commandType {
-	dice, flip, eightBall, syncWatch, pyu, pcount, roulette, rcount,
+	dice, flip, eightBall, syncWatch, pyu, pcount, roulette, rcount, poll,
+}
+
+// Options of a #poll and the number of votes cast for each of them
+export interface PollState {
+	options: string[]
+	votes: number[]
+}
+
+// Results of a dice expression with modifiers or multiple terms
+export interface DiceRoll {
+	terms: DiceTermRoll[]
+	total: number
+}
+
+// Results of a single term of a dice expression. Constant terms have no rolls.
+export interface DiceTermRoll {
+	negative?: boolean
+	rolls?: number[]
+	dropped?: boolean[] // Dice not counted towards the total
+	constant?: number
 }
 
 // Single hash command result delivered from the server
@@ -50,6 +50,47 @@ export const enum message {
 
 	// Set a cookie on the client
 	setCookie,
+
+	// Sequence number of the last thread feed message received
+	feedSequence,
+
+	// Missed thread feed messages can not be replayed. Resynchronise from
+	// scratch.
+	resync,
+
+	// Catalog entry of a thread created or modified on the current board
+	boardThread,
+
+	// Thread deleted from the current board
+	boardThreadDeleted,
+
+	// Register watched threads and receive their post count changes
+	watchThreads,
+
+	// Own post in a watched thread was quoted
+	quoted,
+
+	// Messages are being slowed down for exceeding a rate limit
+	rateLimited,
+
+	// Server is shutting down. Reconnect after the passed number of
+	// milliseconds.
+	reconnect,
+
+	// A post in a thread was linked to from another board
+	backlink,
+
+	// Body of a closed post was edited by its author
+	editPost,
+
+	// Vote in a #poll or updated vote tallies of a poll
+	votePoll,
+
+	// Deletion of a post or its image by the post's author
+	selfDelete,
+
+	// Change to the open post's body was rejected by a word filter
+	rejectBody,
 }
 
 export type MessageHandler = (msg: {}) => void
@@ -0,0 +1,318 @@
+// MessagePack encoding of websocket message payloads in protocol version 2.
+// Only the subset of the format produced by the server is supported.
+
+const encoder = new TextEncoder(),
+	decoder = new TextDecoder()
+
+// Grows as needed, while encoding a value
+class Writer {
+	private buf = new Uint8Array(64)
+	private view = new DataView(this.buf.buffer)
+	private pos = 0
+
+	public bytes(): Uint8Array {
+		return this.buf.subarray(0, this.pos)
+	}
+
+	private reserve(n: number) {
+		if (this.pos + n <= this.buf.length) {
+			return
+		}
+		let size = this.buf.length * 2
+		while (size < this.pos + n) {
+			size *= 2
+		}
+		const buf = new Uint8Array(size)
+		buf.set(this.buf.subarray(0, this.pos))
+		this.buf = buf
+		this.view = new DataView(buf.buffer)
+	}
+
+	public byte(b: number) {
+		this.reserve(1)
+		this.buf[this.pos++] = b
+	}
+
+	public raw(b: Uint8Array) {
+		this.reserve(b.length)
+		this.buf.set(b, this.pos)
+		this.pos += b.length
+	}
+
+	// Write a type byte followed by an integer of size bytes. Negative
+	// integers are written in two's complement.
+	public uint(type: number, u: number, size: number) {
+		this.byte(type)
+		this.reserve(size)
+		switch (size) {
+			case 1:
+				this.view.setUint8(this.pos, u)
+				break
+			case 2:
+				this.view.setUint16(this.pos, u)
+				break
+			case 4:
+				this.view.setUint32(this.pos, u)
+				break
+			case 8:
+				this.view.setUint32(this.pos, Math.floor(u / 0x100000000))
+				this.view.setUint32(this.pos + 4, u >>> 0)
+				break
+		}
+		this.pos += size
+	}
+
+	public float(f: number) {
+		this.byte(0xcb)
+		this.reserve(8)
+		this.view.setFloat64(this.pos, f)
+		this.pos += 8
+	}
+}
+
+// Encode a JSON-compatible value as MessagePack
+export function encode(val: any): Uint8Array {
+	const w = new Writer()
+	write(w, val)
+	return w.bytes()
+}
+
+function write(w: Writer, val: any) {
+	switch (typeof val) {
+		case "boolean":
+			w.byte(val ? 0xc3 : 0xc2)
+			return
+		case "number":
+			writeNumber(w, val)
+			return
+		case "string":
+			writeString(w, val)
+			return
+		case "object":
+			if (val === null) {
+				break
+			}
+			if (typeof val.toJSON === "function") {
+				write(w, val.toJSON())
+				return
+			}
+			if (Array.isArray(val)) {
+				writeHeader(w, val.length, 0x90, 0xdc)
+				for (let v of val) {
+					// Same as JSON.stringify()
+					write(w, v === undefined ? null : v)
+				}
+				return
+			}
+			const keys = Object.keys(val).filter(k =>
+				val[k] !== undefined && typeof val[k] !== "function")
+			writeHeader(w, keys.length, 0x80, 0xde)
+			for (let k of keys) {
+				writeString(w, k)
+				write(w, val[k])
+			}
+			return
+	}
+	w.byte(0xc0)
+}
+
+function writeNumber(w: Writer, n: number) {
+	if (!isFinite(n)) {
+		// Same as JSON.stringify()
+		w.byte(0xc0)
+	} else if (!Number.isSafeInteger(n)) {
+		w.float(n)
+	} else if (n >= 0) {
+		if (n <= 0x7f) {
+			w.byte(n)
+		} else if (n <= 0xff) {
+			w.uint(0xcc, n, 1)
+		} else if (n <= 0xffff) {
+			w.uint(0xcd, n, 2)
+		} else if (n <= 0xffffffff) {
+			w.uint(0xce, n, 4)
+		} else {
+			w.uint(0xcf, n, 8)
+		}
+	} else if (n >= -32) {
+		w.byte(n & 0xff)
+	} else if (n >= -0x80) {
+		w.uint(0xd0, n, 1)
+	} else if (n >= -0x8000) {
+		w.uint(0xd1, n, 2)
+	} else if (n >= -0x80000000) {
+		w.uint(0xd2, n, 4)
+	} else {
+		w.uint(0xd3, n, 8)
+	}
+}
+
+function writeString(w: Writer, s: string) {
+	const b = encoder.encode(s)
+	if (b.length < 32) {
+		w.byte(0xa0 | b.length)
+	} else if (b.length <= 0xff) {
+		w.uint(0xd9, b.length, 1)
+	} else if (b.length <= 0xffff) {
+		w.uint(0xda, b.length, 2)
+	} else {
+		w.uint(0xdb, b.length, 4)
+	}
+	w.raw(b)
+}
+
+// Write an array or map header for n elements
+function writeHeader(w: Writer, n: number, fix: number, wide: number) {
+	if (n < 16) {
+		w.byte(fix | n)
+	} else if (n <= 0xffff) {
+		w.uint(wide, n, 2)
+	} else {
+		w.uint(wide + 1, n, 4)
+	}
+}
+
+// Decode a single MessagePack value. Binary values are returned as
+// Uint8Array.
+export function decode(buf: Uint8Array): any {
+	const r = new Reader(buf),
+		val = r.read()
+	if (r.pos !== buf.length) {
+		throw new Error("trailing bytes after MessagePack value")
+	}
+	return val
+}
+
+class Reader {
+	private view: DataView
+	public pos = 0
+
+	constructor(private buf: Uint8Array) {
+		this.view = new DataView(buf.buffer, buf.byteOffset, buf.byteLength)
+	}
+
+	private next(n: number): number {
+		if (this.pos + n > this.buf.length) {
+			throw new Error("unexpected end of MessagePack value")
+		}
+		const i = this.pos
+		this.pos += n
+		return i
+	}
+
+	private uint(size: number): number {
+		const i = this.next(size)
+		switch (size) {
+			case 1:
+				return this.view.getUint8(i)
+			case 2:
+				return this.view.getUint16(i)
+			case 4:
+				return this.view.getUint32(i)
+			default:
+				return this.view.getUint32(i) * 0x100000000
+					+ this.view.getUint32(i + 4)
+		}
+	}
+
+	private int(size: number): number {
+		const i = this.next(size)
+		switch (size) {
+			case 1:
+				return this.view.getInt8(i)
+			case 2:
+				return this.view.getInt16(i)
+			case 4:
+				return this.view.getInt32(i)
+			default:
+				return this.view.getInt32(i) * 0x100000000
+					+ this.view.getUint32(i + 4)
+		}
+	}
+
+	private bytes(n: number): Uint8Array {
+		const i = this.next(n)
+		return this.buf.subarray(i, i + n)
+	}
+
+	private string(n: number): string {
+		return decoder.decode(this.bytes(n))
+	}
+
+	private array(n: number): any[] {
+		const arr = new Array(n)
+		for (let i = 0; i < n; i++) {
+			arr[i] = this.read()
+		}
+		return arr
+	}
+
+	private map(n: number): { [key: string]: any } {
+		const obj: { [key: string]: any } = {}
+		for (let i = 0; i < n; i++) {
+			const key = this.read()
+			obj[key] = this.read()
+		}
+		return obj
+	}
+
+	public read(): any {
+		const c = this.uint(1)
+		if (c <= 0x7f) {
+			return c
+		}
+		if (c >= 0xe0) {
+			return c - 0x100
+		}
+		switch (c & 0xf0) {
+			case 0x80:
+				return this.map(c & 0x0f)
+			case 0x90:
+				return this.array(c & 0x0f)
+		}
+		if ((c & 0xe0) === 0xa0) {
+			return this.string(c & 0x1f)
+		}
+
+		switch (c) {
+			case 0xc0:
+				return null
+			case 0xc2:
+				return false
+			case 0xc3:
+				return true
+			case 0xc4:
+			case 0xc5:
+			case 0xc6:
+				return this.bytes(this.uint(1 << (c - 0xc4)))
+			case 0xca:
+				return this.view.getFloat32(this.next(4))
+			case 0xcb:
+				return this.view.getFloat64(this.next(8))
+			case 0xcc:
+			case 0xcd:
+			case 0xce:
+			case 0xcf:
+				return this.uint(1 << (c - 0xcc))
+			case 0xd0:
+			case 0xd1:
+			case 0xd2:
+			case 0xd3:
+				return this.int(1 << (c - 0xd0))
+			case 0xd9:
+			case 0xda:
+			case 0xdb:
+				return this.string(this.uint(1 << (c - 0xd9)))
+			case 0xdc:
+				return this.array(this.uint(2))
+			case 0xdd:
+				return this.array(this.uint(4))
+			case 0xde:
+				return this.map(this.uint(2))
+			case 0xdf:
+				return this.map(this.uint(4))
+			default:
+				throw new Error(`unsupported MessagePack type: ${c}`)
+		}
+	}
+}
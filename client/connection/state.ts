@@ -3,14 +3,30 @@ import { debug, page } from "../state"
 import { message, handlers } from "./messages"
 import { renderStatus } from "./ui"
 import { synchronise } from "./synchronization"
+import { encode, decode } from "./msgpack"
 
 const path =
 	(location.protocol === 'https:' ? 'wss' : 'ws')
 	+ `://${location.host}/api/socket`
 
+// Failed websocket connection attempts before falling back to read-only
+// updates over Server-Sent Events
+const eventStreamThreshold = 4
+
+// Latest websocket protocol version supported by the client. Version 2 encodes
+// messages as a type byte followed by a MessagePack payload.
+const protocolVersion = 2
+
 let socket: WebSocket,
+	events: EventSource,
+	// A websocket connection was established at least once
+	opened = false,
 	attempts: number,
-	attemptTimer: number
+	attemptTimer: number,
+	// Delay requested by the server before reconnecting on shutdown
+	reconnectDelay = 0,
+	// Protocol version negotiated with the server for the current connection
+	protocol = 1
 
 // Websocket connection and synchronization with server states
 export const enum syncStatus {
@@ -36,12 +52,19 @@ function connect() {
 		console.error("Page downloaded locally. Refusing to sync.")
 		return
 	}
+	protocol = 1
 	socket = new WebSocket(path)
+	socket.binaryType = "arraybuffer"
 	socket.onopen = connSM.feeder(connEvent.open)
 	socket.onclose = connSM.feeder(connEvent.close)
 	socket.onerror = connSM.feeder(connEvent.close)
-	socket.onmessage = ({ data }) =>
-		onMessage(data, false)
+	socket.onmessage = ({ data }) => {
+		if (typeof data === "string") {
+			onMessage(data, false)
+		} else {
+			onBinaryMessage(new Uint8Array(data), false)
+		}
+	}
 	if (debug) {
 		(window as any).socket = socket
 	}
@@ -55,6 +78,25 @@ function close_socket() {
 	}
 }
 
+// Fall back to read-only thread updates over Server-Sent Events, if websocket
+// connections can not be established at all
+function startEventStream() {
+	if (events || !page.thread || !("EventSource" in window)) {
+		return
+	}
+	// Resumption after reconnects is handled by the browser
+	events = new EventSource(`/api/events/${page.thread}`)
+	events.onmessage = ({ data }) =>
+		onMessage(data, false)
+}
+
+function closeEventStream() {
+	if (events) {
+		events.close()
+		events = null
+	}
+}
+
 // Send a message to the server. If msg is null, it is omitted from sent
 // websocket message.
 export function send(type: message, msg: any) {
@@ -63,6 +105,18 @@ export function send(type: message, msg: any) {
 		return
 	}
 
+	if (protocol > 1) {
+		if (debug) {
+			console.log('<', type, msg)
+		}
+		const payload = msg !== null ? encode(msg) : new Uint8Array(0),
+			buf = new Uint8Array(payload.length + 1)
+		buf[0] = type
+		buf.set(payload, 1)
+		socket.send(buf)
+		return
+	}
+
 	let str = leftPad(type)
 	if (msg !== null) {
 		str += JSON.stringify(msg)
@@ -107,9 +161,37 @@ function onMessage(data: string, extracted: boolean) {
 	}
 }
 
+// Routes messages encoded in protocol version 2 from the server to the
+// respective handler. The first byte of a message defines its type and the
+// rest is an optional MessagePack payload.
+function onBinaryMessage(data: Uint8Array, extracted: boolean) {
+	const type = data[0],
+		payload = data.length > 1 ? decode(data.subarray(1)) : null
+
+	if (debug) {
+		console.log(extracted ? "\t>" : ">", type, payload)
+	}
+
+	// Split several concatenated messages
+	if (type === message.concat) {
+		for (let msg of payload) {
+			onBinaryMessage(msg, true)
+		}
+		return
+	}
+
+	const handler = handlers[type]
+	if (handler) {
+		handler(payload)
+	}
+}
+
 function prepareToSync(): connState {
+	opened = true
+	closeEventStream()
 	renderStatus(syncStatus.connecting)
-	synchronise()
+	// Negotiate the protocol version first. Synchronises on confirmation.
+	send(message.configs, { protocolVersion })
 	attemptTimer = setTimeout(resetAttempts, 10000) as any
 	return connState.syncing
 }
@@ -154,6 +236,19 @@ export function start() {
 	connSM.feed(connEvent.start)
 }
 
+handlers[message.reconnect] = (delay: number) =>
+	reconnectDelay = delay
+
+// Confirmation of the negotiated protocol version. Board configurations sent
+// on synchronisation are received under the same message type.
+handlers[message.configs] = (msg: { protocolVersion?: number }) => {
+	if (msg.protocolVersion === undefined) {
+		return
+	}
+	protocol = msg.protocolVersion
+	synchronise()
+}
+
 connSM.act(connState.loading, connEvent.start, () => {
 	renderStatus(syncStatus.connecting)
 	attempts = 0
@@ -180,7 +275,17 @@ connSM.wildAct(connEvent.close, event => {
 	}
 
 	// Wait maxes out at ~1min
-	const wait = 500 * Math.pow(1.5, Math.min(Math.floor(++attempts / 2), 12))
+	let wait = 500 * Math.pow(1.5, Math.min(Math.floor(++attempts / 2), 12))
+	if (reconnectDelay) {
+		// Server is restarting. Spread out reconnections and do not count
+		// this as a failed attempt.
+		wait = reconnectDelay * (1 + Math.random())
+		reconnectDelay = 0
+		attempts = 0
+	}
+	if (!opened && attempts >= eventStreamThreshold) {
+		startEventStream()
+	}
 	setTimeout(connSM.feeder(connEvent.retry), wait)
 
 	return connSM.state === connState.desynced
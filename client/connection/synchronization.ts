@@ -19,16 +19,23 @@ type SyncData = {
 type PostState = {
 	hash_image: boolean
 	spoilered: boolean
+	files: number // Number of additional files
 	closed: boolean
 	body: string
 }
 
+// Sequence number of the last thread feed message received and the thread it
+// was received for. Used to resume after reconnecting.
+let lastSeq = 0,
+	seqThread = 0
+
 // Send a requests to the server to synchronise to the current page and
 // subscribe to the appropriate event feeds
 export function synchronise() {
 	send(message.synchronise, {
 		board: page.board,
 		thread: page.thread,
+		seq: page.thread && page.thread === seqThread ? lastSeq : 0,
 	})
 
 	// Reclaim a post lost after disconnecting, going on standby, resuming
@@ -68,6 +75,11 @@ async function syncRecentPost(id: number, p: PostState) {
 			model.view.renderImage(false)
 		}
 	}
+	if (p.files > (model.files ? model.files.length : 0)) {
+		// Missed insertion of additional files
+		model.files = (await fetchPost(id)).files
+		model.view.renderFiles()
+	}
 	if (p.spoilered && model.image && !model.image.spoiler) {
 		model.image.spoiler = true
 		model.view.renderImage(false)
@@ -99,6 +111,23 @@ async function fetchPost(id: number): Promise<PostData> {
 	return r.json()
 }
 
+// Remember position in the thread feed for resuming after reconnection
+handlers[message.feedSequence] = (seq: number) => {
+	lastSeq = seq
+	seqThread = page.thread
+}
+
+// Messages missed while disconnected can no longer be replayed. Reload the
+// thread, unless that would discard a post being written.
+handlers[message.resync] = () => {
+	switch (postSM.state) {
+		case postState.ready:
+		case postState.locked:
+		case postState.threadLocked:
+			location.reload()
+	}
+}
+
 // Handle response to a open post reclaim request
 handlers[message.reclaim] = (code: number) => {
 	switch (code) {
@@ -108,6 +108,20 @@ export abstract class AccountForm extends FormView {
 			}
 		}
 
+		// Read all word filter forms
+		for (let form of this.el.querySelectorAll(".filter-form")) {
+			const field = (el: Element, cls: string) =>
+				el.querySelector(cls) as HTMLInputElement
+			req[form.getAttribute("name")] =
+				[...form.querySelectorAll(".word-filter")].map(el => ({
+					pattern: field(el, ".filter-pattern").value,
+					regex: field(el, ".filter-regex").checked,
+					scope: field(el, ".filter-scope").value,
+					action: field(el, ".filter-action").value,
+					replacement: field(el, ".filter-replacement").value,
+				}))
+		}
+
 		return req
 	}
 
@@ -213,6 +213,8 @@ class BanForm extends HidableForm {
 		const data = {
 			duration: this.extractDuration(),
 			reason: this.inputElement("reason").value,
+			ipv4Prefix: parseInt(this.inputElement("ipv4Prefix").value) || 0,
+			ipv6Prefix: parseInt(this.inputElement("ipv6Prefix").value) || 0,
 		}
 		const g = this.inputElement("global")
 		if (g) {
@@ -7,6 +7,7 @@ import {
 	extractConfigs, extractPost, reparseOpenPosts, extractPageData, hidePosts,
 } from "./common"
 import { BoardData, ThreadData } from "../common"
+import { handlers, message } from "../connection"
 
 type SortFunction = (a: Post, b: Post) => number
 
@@ -26,6 +27,15 @@ export const threads: { [id: number]: ThreadData } = {};
 // Unix time of last board page render. Used for automatic refreshes.
 let lastFetchTime = Date.now() / 1000
 
+// Threads created on the board since the last render
+let newThreads = 0
+
+// Thread fields updated live by the server
+const liveFields = [
+	"post_count", "image_count", "update_time", "bump_time", "sticky",
+	"locked",
+]
+
 // Sort threads by embedded data
 function subtract(attr: string): (a: Post, b: Post) => number {
 	return (a, b) =>
@@ -35,6 +45,7 @@ function subtract(attr: string): (a: Post, b: Post) => number {
 // Render a fresh board page
 export function renderFresh(html: string) {
 	lastFetchTime = Math.floor(Date.now() / 1000)
+	newThreads = 0
 	threadsEl.innerHTML = html
 	if (isBanned()) {
 		return
@@ -160,6 +171,9 @@ function renderRefreshButton(el: Element) {
 	if (text === lang.posts["justNow"]) {
 		text = lang.ui["refresh"]
 	}
+	if (newThreads) {
+		text += ` (+${newThreads})`
+	}
 	el.textContent = text
 }
 
@@ -209,6 +223,60 @@ async function refreshBoard() {
 	}
 }
 
+// Remove a deleted or archived thread from the page
+function removeThread(id: number) {
+	delete threads[id]
+	const model = posts.get(id)
+	if (model) {
+		posts.remove(model)
+	}
+	const el = threadsEl.querySelector(
+		`section[data-id="${id}"], article[data-id="${id}"]`)
+	if (el) {
+		el.remove()
+	}
+}
+
+// Apply thread creation and modification pushed by the server
+handlers[message.boardThread] = (data: ThreadData) => {
+	if (page.thread || isBanned()) {
+		return
+	}
+
+	if (!(data.id in threads)) {
+		// Ignore bumps of threads not rendered on the current index page
+		if (data.post_count === 1 && !data.archived) {
+			newThreads++
+			renderRefreshButton(threadsEl.querySelector("#refresh > a"))
+		}
+		return
+	}
+	if (data.archived) {
+		removeThread(data.id)
+		return
+	}
+
+	const model = posts.get(data.id)
+	for (let key of liveFields) {
+		threads[data.id][key] = data[key]
+		if (model) {
+			model[key] = data[key]
+		}
+	}
+	const counters = threadsEl.querySelector(
+		`article[data-id="${data.id}"] .counters`)
+	if (counters) {
+		counters.textContent = `${data.post_count} / ${data.image_count}`
+	}
+	sortThreads(false)
+}
+
+handlers[message.boardThreadDeleted] = (id: number) => {
+	if (!page.thread) {
+		removeThread(id)
+	}
+}
+
 // Update refresh timer or refresh board, if document hidden, each minute
 // TODO: Replace with SSE
 setInterval(() => {
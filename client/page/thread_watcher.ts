@@ -6,6 +6,7 @@ import * as options from "../options";
 import * as posts from "../posts";
 import * as util from "../util";
 import * as board from "./board";
+import { handlers, message, send, connSM, connState } from "../connection";
 
 interface OpenThreadRecord {
 	id: number;
@@ -24,6 +25,17 @@ type ThreadPostCountDiff = {
 	deleted: number[];
 }
 
+// Reply to an own post in a watched thread
+type QuoteData = {
+	id: number;
+	op: number;
+	target: number;
+}
+
+// Maximum number of threads and own posts registered with the server
+const maxWatchedThreads = 1000,
+	maxWatchedPosts = 1000;
+
 async function putExpiring(store: string,
 	thread: number,
 	data: { [key: string]: any },
@@ -55,6 +67,10 @@ async function getOpenedThreads(): Promise<Set<number>> {
 }
 
 async function fetchWatchedThreads() {
+	// Updates are pushed to connected clients
+	if (connSM.state === connState.synced) {
+		return;
+	}
 	const last = localStorage.getItem("last_watched_fetched");
 	if (last && parseInt(last) > Date.now() - 60 * 1000) {
 		return;
@@ -81,7 +97,33 @@ async function fetchWatchedThreads() {
 		throw Error("watched threads: " + await res.text());
 	}
 
-	const diff: ThreadPostCountDiff = await res.json();
+	return await applyThreadDiff(watched, await res.json());
+}
+
+// Register watched threads and own posts in them with the server to receive
+// post count changes and replies
+async function subscribeToWatched() {
+	if (connSM.state !== connState.synced) {
+		return;
+	}
+	const watched = await getWatchedThreads();
+	const ids = Object.keys(watched).slice(0, maxWatchedThreads);
+	const threads = {};
+	for (let id of ids) {
+		threads[id] = watched[id].postCount;
+	}
+	const mine = await db.readIDs("mine", ids.map(id => parseInt(id)));
+	send(message.watchThreads, {
+		threads,
+		posts: mine.sort((a, b) => b - a).slice(0, maxWatchedPosts),
+	});
+}
+
+// Update watched thread records and notify the user of new posts
+async function applyThreadDiff(
+	watched: { [id: number]: WatchedThreadRecord },
+	diff: ThreadPostCountDiff,
+) {
 	const proms = [];
 	const toNotify = [];
 	const opened = await getOpenedThreads();
@@ -91,6 +133,9 @@ async function fetchWatchedThreads() {
 	}
 	for (let k in diff.changed) {
 		const id = parseInt(k);
+		if (!watched[id]) {
+			continue;
+		}
 
 		// Update post count of watched thread
 		proms.push(watchThread(id, diff.changed[id], watched[id].subject));
@@ -138,6 +183,21 @@ async function fetchWatchedThreads() {
 	return await Promise.all(proms);
 }
 
+// Notify the user of a reply to one of their posts outside the current thread
+function notifyAboutQuote({ id, op }: QuoteData) {
+	if (op === state.page.thread || !options.canNotify()) {
+		return;
+	}
+	const opts = options.notificationOpts();
+	opts.tag = `quoted:${id}`;
+	opts.data = { id, op }; // Persist target, even if browser tab closed
+	const n = new Notification(lang.ui["quoted"], opts);
+	n.onclick = function () {
+		const { id, op } = this.data;
+		window.open(`/all/${op}#p${id}`);
+	};
+}
+
 function markThreadOpened() {
 	if (!state.page.thread) {
 		return;
@@ -154,6 +214,15 @@ export function init() {
 	setInterval(fetchWatchedThreads, 60 * 1000);
 	fetchWatchedThreads();
 
+	handlers[message.watchThreads] = (diff: ThreadPostCountDiff) =>
+		getWatchedThreads()
+			.then(watched => applyThreadDiff(watched, diff))
+			.catch(console.error);
+	handlers[message.quoted] = notifyAboutQuote;
+	connSM.on(connState.synced, () =>
+		subscribeToWatched().catch(console.error));
+	subscribeToWatched().catch(console.error);
+
 	localizeThreadWatchToggles();
 
 	// Handle toggle clicks
@@ -178,7 +247,7 @@ export function init() {
 				}
 				augmentToggle(el, true);
 			}
-			p.catch(console.error);
+			p.then(subscribeToWatched).catch(console.error);
 		},
 		{
 			selector:
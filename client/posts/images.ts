@@ -1,5 +1,5 @@
 import { Post } from "./model"
-import { fileTypes, isExpandable } from "../common"
+import { fileTypes, isExpandable, ImageData } from "../common"
 import { View } from "../base"
 import {
 	setAttrs, on, trigger, firstChild, importTemplate, escape, pad, makeEl,
@@ -57,9 +57,52 @@ export default class ImageHandler extends View<Post> {
 		if (el) {
 			el.remove()
 		}
+		el = this.getFiles()
+		if (el) {
+			el.remove()
+		}
 		this.uncheckModerationBox()
 	}
 
+	// Need to find direct descendant, otherwise inlined posts might match
+	private getFiles(): HTMLElement {
+		return firstChild(this.el.querySelector(".post-container"), ch =>
+			ch.classList.contains("post-files"))
+	}
+
+	// Render the additional files of a post below its body
+	public renderFiles() {
+		let el = this.getFiles()
+		const { files } = this.model
+		if (!files || !files.length) {
+			if (el) {
+				el.remove()
+			}
+			return
+		}
+		if (!el) {
+			el = document.createElement("div")
+			el.classList.add("post-files")
+			this.el.querySelector(".post-container > blockquote").after(el)
+		}
+
+		let html = ""
+		for (let f of files) {
+			const [thumb, width, height] = resolveThumbnail(f),
+				ext = fileTypes[f.file_type],
+				name = `${escape(f.name)}.${ext}`
+			html += HTML`<span class="post-file">
+				<a target="_blank" href="${sourcePath(f.sha1, f.file_type)}">
+					<img loading="lazy" src="${thumb}" width="${width.toString()}" height="${height.toString()}">
+				</a>
+				<a href="/assets/images/src/${f.sha1}.${ext}" download="${name}">
+					${name}
+				</a>
+			</span>`
+		}
+		el.innerHTML = html
+	}
+
 	// Uncheck moderation box, if any.
 	// This prevents staff from moderating posts, that have already been
 	// moderated. At least to some extent.
@@ -73,44 +116,10 @@ export default class ImageHandler extends View<Post> {
 	// Render the actual thumbnail image
 	private renderThumbnail() {
 		const el = this.el.querySelector("figure a"),
-			{ sha1, file_type: file_type, thumb_type: thumbType, dims, spoiler } = this
-				.model
-				.image,
-			src = sourcePath(sha1, file_type)
-		let thumb: string,
-			[, , thumbWidth, thumbHeight] = dims
-
-		if (thumbType === fileTypes.noFile) {
-			// No thumbnail exists
-			let file: string
-			switch (file_type) {
-				case fileTypes.webm:
-				case fileTypes.mp4:
-				case fileTypes.mp3:
-				case fileTypes.ogg:
-				case fileTypes.flac:
-					file = "audio"
-					break
-				case fileTypes.swf:
-					file = "flash"
-					break
-				default:
-					file = "file"
-			}
-			thumb = `/assets/${file}.png`
-			thumbHeight = thumbWidth = 150
-		} else if (spoiler && options.spoilers) {
-			// Spoilered and spoilers enabled
-			thumb = '/assets/spoil/default.jpg'
-			thumbHeight = thumbWidth = 150
-		} else if (options.autogif && file_type === fileTypes.gif) {
-			// Animated GIF thumbnails
-			thumb = src
-		} else {
-			thumb = thumbPath(sha1, thumbType)
-		}
+			{ image } = this.model,
+			[thumb, thumbWidth, thumbHeight] = resolveThumbnail(image)
 
-		el.setAttribute("href", src)
+		el.setAttribute("href", sourcePath(image.sha1, image.file_type))
 		setAttrs(el.firstElementChild, {
 			src: thumb,
 			width: thumbWidth.toString(),
@@ -518,6 +527,41 @@ export function sourcePath(sha1: string, fileType: fileTypes): string {
 	return `${imageRoot()}/src/${sha1}.${fileTypes[fileType]}`
 }
 
+// Resolve the thumbnail URL and dimensions to render an upload with
+function resolveThumbnail(img: ImageData): [string, number, number] {
+	const { sha1, file_type, thumb_type: thumbType, dims, spoiler } = img
+	let [, , width, height] = dims
+
+	if (thumbType === fileTypes.noFile) {
+		// No thumbnail exists
+		let file: string
+		switch (file_type) {
+			case fileTypes.webm:
+			case fileTypes.mp4:
+			case fileTypes.mp3:
+			case fileTypes.ogg:
+			case fileTypes.flac:
+				file = "audio"
+				break
+			case fileTypes.swf:
+				file = "flash"
+				break
+			default:
+				file = "file"
+		}
+		return [`/assets/${file}.png`, 150, 150]
+	}
+	if (spoiler && options.spoilers) {
+		// Spoilered and spoilers enabled
+		return ['/assets/spoil/default.jpg', 150, 150]
+	}
+	if (options.autogif && file_type === fileTypes.gif) {
+		// Animated GIF thumbnails
+		return [sourcePath(sha1, file_type), width, height]
+	}
+	return [thumbPath(sha1, thumbType), width, height]
+}
+
 // Delegate image clicks to views. More performant than dedicated listeners for
 // each view.
 function handleImageClick(event: MouseEvent) {
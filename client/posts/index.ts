@@ -15,6 +15,7 @@ import initMenu from "./menu"
 import initInlineExpansion from "./inlineExpansion"
 import initHover from "./hover"
 import initHidePost from "./hidepost"
+import initPoll from "./poll"
 
 export default () => {
 	initEtc()
@@ -23,5 +24,6 @@ export default () => {
 	initInlineExpansion()
 	initHover()
 	initHidePost()
+	initPoll()
 }
 
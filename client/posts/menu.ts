@@ -1,6 +1,6 @@
 import { View } from "../base"
 import { Post } from "./model"
-import { getModel/*, mine*/ } from "../state"
+import { getModel, mine, boardConfig } from "../state"
 import { on, postJSON, HTML } from "../util"
 import { FormView } from "../ui"
 import lang from "../lang"
@@ -9,6 +9,7 @@ import { position } from "../mod"
 import CollectionView from "./collectionView"
 import { PostData, ModerationLevel } from "../common"
 import ReportForm from "./report"
+import identity from "./posting/identity"
 
 interface ControlButton extends Element {
 	_popup_menu: MenuView
@@ -116,6 +117,33 @@ class DeleteByIPForm extends MenuForm {
 	}
 }
 
+// Form for editing the body of one's own closed post
+class EditForm extends MenuForm {
+	constructor(parent: Element, model: Post) {
+		super(parent, model.id,
+			HTML`
+			<br>
+			<textarea name="body" rows="8" class="full-width"></textarea>`);
+		this.el.style.padding = "0.5em";
+		const input = this.el.querySelector("textarea");
+		input.value = model.body;
+		input.focus();
+	}
+
+	protected async send() {
+		const res = await postJSON("/api/edit-post", {
+			id: this.parentID,
+			password: identity.postPassword,
+			body: this.el.querySelector("textarea").value,
+		});
+		if (res.status !== 200) {
+			return this.renderFormResponse(await res.text());
+		}
+		this.closeMenu();
+		this.remove();
+	}
+}
+
 // Actions to be performed by the items in the popup menu
 const actions: { [key: string]: ItemSpec } = {
 	hide: {
@@ -126,6 +154,30 @@ const actions: { [key: string]: ItemSpec } = {
 		},
 		handler: hidePost,
 	},
+	edit: {
+		text: lang.posts["edit"],
+		keepOpen: true,
+		shouldRender: canEdit,
+		handler(m, el) {
+			new EditForm(el, m)
+		},
+	},
+	selfDelete: {
+		text: lang.posts["selfDelete"],
+		shouldRender: canSelfDelete,
+		handler(m) {
+			selfDelete(m, false)
+		},
+	},
+	selfDeleteImage: {
+		text: lang.posts["selfDeleteImage"],
+		shouldRender(m) {
+			return !!m.image && canSelfDelete(m)
+		},
+		handler(m) {
+			selfDelete(m, true)
+		},
+	},
 	report: {
 		text: lang.ui["report"],
 		shouldRender(m) {
@@ -207,6 +259,39 @@ const actions: { [key: string]: ItemSpec } = {
 	},
 }
 
+// Returns, if the post is one of the user's own closed posts and the board's
+// editing window has not passed yet
+function canEdit(m: Post): boolean {
+	const minutes = boardConfig.allowEdits
+	return !!minutes
+		&& mine.has(m.id)
+		&& !m.editing
+		&& m.time > Date.now() / 1000 - minutes * 60
+}
+
+// Returns, if the post is one of the user's own closed posts and the board's
+// self-deletion window has not passed yet
+function canSelfDelete(m: Post): boolean {
+	const minutes = boardConfig.allowSelfDelete
+	return !!minutes
+		&& mine.has(m.id)
+		&& !m.editing
+		&& !m.isDeleted()
+		&& m.time > Date.now() / 1000 - minutes * 60
+}
+
+// Delete one of the user's own posts or only its image
+async function selfDelete(m: Post, image: boolean) {
+	const res = await postJSON("/api/self-delete", {
+		id: m.id,
+		password: identity.postPassword,
+		image,
+	})
+	if (res.status !== 200) {
+		alert(await res.text())
+	}
+}
+
 // Returns, if the post still likely has an IP attached and the client is
 // logged in
 function canModerateIP(m: Post): boolean {
@@ -7,7 +7,7 @@ import { mine, seenPosts, storeSeenPost, posts, hidden } from "../state"
 import { notifyAboutReply } from "../ui"
 import {
 	PostData, TextState, PostLink, Command, ImageData,
-	ModerationEntry, ModerationAction, ModerationLevel,
+	ModerationEntry, ModerationAction, ModerationLevel, commandType,
 } from "../common"
 import { hideRecursively } from "./hide"
 import options from "../options"
@@ -25,7 +25,9 @@ export class Post extends Model implements PostData {
 	public seenOnce: boolean
 	public hidden: boolean
 	public image: ImageData
+	public files: ImageData[]
 	public time: number
+	public edited: number
 	public body: string
 	public name: string
 	public trip: string
@@ -72,6 +74,7 @@ export class Post extends Model implements PostData {
 			haveSyncwatch: false,
 			successive_newlines: 0,
 			iDice: 0,
+			pollLines: 0,
 		}
 	}
 
@@ -193,8 +196,17 @@ export class Post extends Model implements PostData {
 		this.view.renderBacklinks()
 	}
 
-	// Insert an image into an existing post
+	// Insert an image into an existing post. If the post already has an image,
+	// the file is appended to the post's additional files.
 	public insertImage(img: ImageData) {
+		if (this.image) {
+			if (!this.files) {
+				this.files = []
+			}
+			this.files.push(img)
+			this.view.renderFiles()
+			return
+		}
 		this.image = img
 		this.view.renderImage(false)
 		this.view.autoExpandImage()
@@ -215,6 +227,28 @@ export class Post extends Model implements PostData {
 		this.view.closePost()
 	}
 
+	// Replace the body of a closed post with an edited version
+	public applyEdit(body: string, edited: number) {
+		this.body = body
+		this.edited = edited
+		this.view.reparseBody()
+		this.view.renderEdited()
+	}
+
+	// Update the vote tallies of the post's #poll
+	public applyPollVotes(votes: number[]) {
+		if (!this.commands) {
+			return
+		}
+		for (let c of this.commands) {
+			if (c.type === commandType.poll) {
+				c.val.votes = votes
+				this.view.reparseBody()
+				return
+			}
+		}
+	}
+
 	// Return if post has no content and can be hidden
 	public isEmpty() {
 		return !this.editing
@@ -232,6 +266,7 @@ export class Post extends Model implements PostData {
 		const { type, data } = entry;
 		switch (type) {
 			case ModerationAction.deletePost:
+			case ModerationAction.selfDeletePost:
 				//if (!mine.has(this.id)) {
 					this.view.el.classList.add("deleted");
 					if (options.hideBinned) {
@@ -240,8 +275,10 @@ export class Post extends Model implements PostData {
 				//}
 				break;
 			case ModerationAction.deleteImage:
+			case ModerationAction.selfDeleteImage:
 				if (this.image) {
 					this.image = null;
+					this.files = null;
 					this.view.removeImage();
 				}
 				break;
@@ -250,6 +287,12 @@ export class Post extends Model implements PostData {
 					this.image.spoiler = true;
 					this.view.renderImage(false);
 				}
+				if (this.files) {
+					for (let f of this.files) {
+						f.spoiler = true;
+					}
+					this.view.renderFiles();
+				}
 				break;
 			case ModerationAction.lockThread:
 				this.locked = data === 'true';
@@ -257,6 +300,7 @@ export class Post extends Model implements PostData {
 			case ModerationAction.purgePost:
 				if (this.image) {
 					this.image = null;
+					this.files = null;
 					this.view.removeImage();
 				}
 				this.body = "";
@@ -272,8 +316,10 @@ export class Post extends Model implements PostData {
 			return false;
 		}
 		for (let { type } of this.moderation) {
-			if (type === ModerationAction.deletePost) {
-				return true;
+			switch (type) {
+				case ModerationAction.deletePost:
+				case ModerationAction.selfDeletePost:
+					return true;
 			}
 		}
 		return false;
@@ -281,6 +327,7 @@ export class Post extends Model implements PostData {
 
 	public removeImage() {
 		this.image = null
+		this.files = null
 		this.view.removeImage()
 	}
 
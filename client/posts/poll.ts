@@ -0,0 +1,19 @@
+// Voting in #poll hash commands
+
+import { on, getClosestID } from "../util"
+import { send, message } from "../connection"
+
+// Vote for the clicked option of a #poll
+function vote(e: Event) {
+	const el = e.target as Element
+	send(message.votePoll, {
+		id: getClosestID(el),
+		option: parseInt(el.getAttribute("data-option")),
+	})
+}
+
+export default () =>
+	on(document, "click", vote, {
+		passive: true,
+		selector: ".poll-option",
+	})
@@ -12,6 +12,7 @@ import initPaste from "./paste"
 import initImageErr from "./image"
 import initThreads from "./threads"
 import { renderCaptchaForm, captchaLoaded } from "../../ui/captcha";
+import { OverlayNotification } from "../../ui";
 import * as page from "../../page";
 import options from "../../options";
 
@@ -19,6 +20,11 @@ export { default as FormModel } from "./model"
 export { default as identity } from "./identity"
 export { expandThreadForm } from "./threads"
 
+type RejectBodyMessage = {
+	id: number
+	body: string
+}
+
 type Selection = {
 	start: Node
 	end: Node
@@ -212,6 +218,14 @@ export default () => {
 	// The server notified a captcha will be required on the next post
 	handlers[message.captcha] = postSM.feeder(postEvent.captchaRequested);
 
+	// Change to the post body was rejected by a word filter
+	handlers[message.rejectBody] = ({ id, body }: RejectBodyMessage) => {
+		if (postModel && postModel.id === id) {
+			postModel.revertBody(body)
+		}
+		new OverlayNotification(lang.ui["wordFiltered"])
+	}
+
 	// Initial synchronization
 	postSM.act(postState.none, postEvent.sync, () =>
 		postState.ready)
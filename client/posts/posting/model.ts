@@ -44,6 +44,7 @@ export default class FormModel extends Post {
 				haveSyncwatch: false,
 				successive_newlines: 0,
 				iDice: 0,
+				pollLines: 0,
 			},
 		})
 	}
@@ -64,6 +65,12 @@ export default class FormModel extends Post {
 		this.spliceText(msg)
 	}
 
+	// Revert the input to the body last accepted by the server
+	public revertBody(body: string) {
+		this.inputBody = body
+		this.view.replaceText(body, body.length, false)
+	}
+
 	// Compare new value to old and generate appropriate commands
 	public parseInput(val: string): void {
 		// Handle live update toggling
@@ -353,7 +360,7 @@ export default class FormModel extends Post {
 
 	// Upload the file and request its allocation
 	public async uploadFile(file: File) {
-		if (!boardConfig.textOnly && !this.image && identity.live || boardConfig.forcedLive ) { // ?
+		if (!boardConfig.textOnly && this.canUpload() && identity.live || boardConfig.forcedLive ) { // ?
 			//console.log("upload!!! identity.live", identity.live);
 			//console.log("upload!!! this", this);
 			const pr = this.view.upload.uploadFile(file);
@@ -363,8 +370,8 @@ export default class FormModel extends Post {
 	}
 
 	private handleUploadResponse(data: FileData | null) {
-		// Upload failed, canceled or image added while thumbnailing
-		if (!data || this.image || this.allocatingImage) {
+		// Upload failed, canceled or file limit reached while thumbnailing
+		if (!data || !this.canUpload() || this.allocatingImage) {
 			return
 		}
 
@@ -393,9 +400,28 @@ export default class FormModel extends Post {
 		}
 	}
 
-	// Insert the uploaded image into the model
+	// Returns, if more files can be attached to the post
+	public canUpload(): boolean {
+		if (!this.image) {
+			return true
+		}
+		const files = this.files ? this.files.length : 0
+		return 1 + files < (boardConfig.maxFiles || 1)
+	}
+
+	// Insert the uploaded image into the model. If the post already has an
+	// image, the file is appended to the post's additional files.
 	public insertImage(img: ImageData) {
-		this.image = img
+		this.allocatingImage = false
+		if (this.image && this.image !== img) {
+			if (!this.files) {
+				this.files = []
+			}
+			this.files.push(img)
+			this.view.renderFiles()
+		} else {
+			this.image = img
+		}
 		this.view.insertImage()
 	}
 
@@ -247,10 +247,15 @@ export default class FormView extends PostView {
 
         // temporally hack
         if (this.upload) {
-            this.upload.hideButton();
+            if (this.model.canUpload()) {
+                this.upload.reset();
+            } else {
+                this.upload.hideButton();
+            }
         }
 
-        if (postSM.state !== postState.alloc) {
+        // Spoiler toggle only applies to the first file
+        if (postSM.state !== postState.alloc || this.model.files) {
             return;
         }
         if (this.model.image.spoiler) {
@@ -1,10 +1,25 @@
 import { config, boards, boardConfig, posts } from '../../state'
 import { renderPostLink, renderTempLink } from './etc'
-import { PostData, PostLink, TextState, commandType } from '../../common'
+import {
+    PostData, PostLink, TextState, commandType, PollState, DiceRoll,
+} from '../../common'
 import { escape, makeAttrs } from '../../util'
 import { parseEmbeds } from "../embed"
 import highlightSyntax from "./code"
 
+// Dice expressions consist of a roll of one or more dice followed by any
+// number of dice rolls or constants to add or subtract, like "2d20kh1+5"
+const diceTerm = "\\d*d\\d+!?(?:[kd][hl]\\d+)?",
+    diceExpr = `${diceTerm}(?:[+-](?:${diceTerm}|\\d+))*`,
+    diceRegexp = new RegExp(`^${diceExpr}$`),
+    commandRegexp = new RegExp(`^#(flip|${diceExpr}|8ball|pyu|pcount|`
+        + `sw(?:\\d+:)?\\d+:\\d+(?:[+-]\\d+)?|roulette|rcount|poll)$`)
+
+// Dice expression limits
+const maxDiceSides = 10000,
+    maxNumDice = 10,
+    maxDiceTerms = 10
+
 // URLs supported for linkification
 const urlPrefixes = {
     'h': "http",
@@ -27,11 +42,18 @@ export default function renderBody(data: PostData): string {
         haveSyncwatch: false,
         successive_newlines: 0,
         iDice: 0,
+        pollLines: 0,
     }
     let html = ""
 
     const fn = data.editing ? parseOpenLine : parseTerminatedLine
     for (let l of data.body.split("\n")) {
+        // Options of a #poll are rendered by the command itself
+        if (state.pollLines) {
+            state.pollLines--
+            continue
+        }
+
         state.quote = false
 
         // Prevent successive empty lines
@@ -417,7 +439,15 @@ function parseFragment(frag: string, data: PostData): string {
                 if (data.state.quote) {
                     break
                 }
-                m = word.match(/^#(flip|\d*d\d+|8ball|pyu|pcount|sw(?:\d+:)?\d+:\d+(?:[+-]\d+)?|roulette|rcount)$/)
+                m = word.match(commandRegexp)
+                // Exploding dice end with '!'
+                if (trailPunct === "!") {
+                    const ext = (word + "!").match(commandRegexp)
+                    if (ext) {
+                        m = ext
+                        trailPunct = ""
+                    }
+                }
                 if (m) {
                     html += parseCommand(m[1], data)
                     matched = true
@@ -433,6 +463,14 @@ function parseFragment(frag: string, data: PostData): string {
                     break
                 }
 
+                // Post links with board verification
+                m = word.match(/^>>>(>*)\/(\w+)\/(\d+)$/)
+                if (m) {
+                    html += parseCrossBoardLink(m, data.links)
+                    matched = true
+                    break
+                }
+
                 // Internal and custom reference URLs
                 m = word.match(/^>>>(>*)\/(\w+)\/$/)
                 if (m) {
@@ -495,6 +533,25 @@ function parsePostLink(m: string[], links: PostLink[]): string {
     return m[1] + renderPostLink(data)
 }
 
+// Verify and render a link to a post on a specific board
+function parseCrossBoardLink(m: string[], links: PostLink[]): string {
+    if (!links) {
+        return m[0]
+    }
+    const id = parseInt(m[3])
+    let data: PostLink
+    for (let l of links) {
+        if (l.id === id && l.board === m[2]) {
+            data = l
+            break
+        }
+    }
+    if (!data) {
+        return m[0]
+    }
+    return m[1] + renderPostLink(data, true)
+}
+
 // Parse internal or customly set reference URL
 function parseReference(m: string[]): string {
     let href: string
@@ -579,6 +636,11 @@ function parseCommand(bit: string, { commands, state }: PostData): string {
             }
 
             break
+        case "poll":
+            if (commands[state.iDice].type !== commandType.poll) {
+                return "#" + bit
+            }
+            return formatPoll(commands[state.iDice++].val, state)
         case "roulette":
             let val = commands[state.iDice++].val
             inner = val[0].toString() + "/" + val[1].toString()
@@ -601,11 +663,17 @@ function parseCommand(bit: string, { commands, state }: PostData): string {
             if (commands[state.iDice].type !== commandType.dice) {
                 return "#" + bit;
             }
-            const m = bit.match(/^(\d*)d(\d+)$/)
-            if (parseInt(m[1]) > 10 || parseInt(m[2]) > 10000) {
+            const dice = parseDice(bit)
+            if (!dice
+                || dice.simple !== Array.isArray(commands[state.iDice].val)
+            ) {
                 return "#" + bit
             }
-            const sides = parseInt(m[2])
+            if (!dice.simple) {
+                inner = formatDiceRoll(commands[state.iDice++].val)
+                break
+            }
+            const { sides } = dice
 
             const rolls = commands[state.iDice++].val as number[]
             inner = ""
@@ -642,6 +710,87 @@ function parseCommand(bit: string, { commands, state }: PostData): string {
     return `${formatting}#${bit} (${inner})</strong>`
 }
 
+// Validate a dice expression. Returns, if it is a plain "NdM" roll and the
+// number of sides of its first die or null, if the expression is invalid.
+function parseDice(bit: string): { simple: boolean, sides: number } | null {
+    if (!diceRegexp.test(bit)) {
+        return null
+    }
+    const re = /([+-]?)(?:(\d*)d(\d+)(!?)(?:([kd][hl])(\d+))?|(\d+))/g
+    let m: RegExpExecArray,
+        terms = 0,
+        simple = true,
+        sides = 0
+    while (m = re.exec(bit)) {
+        terms++
+        if (m[7] !== undefined) {
+            if (parseInt(m[7]) > maxDiceSides) {
+                return null
+            }
+            simple = false
+            continue
+        }
+
+        const count = m[2] ? parseInt(m[2]) : 1,
+            s = parseInt(m[3])
+        if (count > maxNumDice || s > maxDiceSides || (m[4] && s < 2)) {
+            return null
+        }
+        if (m[5]) {
+            const n = parseInt(m[6]),
+                max = m[5][0] === "k" ? count : count - 1
+            if (n < 1 || n > max) {
+                return null
+            }
+        }
+        if (terms === 1) {
+            sides = s
+        }
+        if (m[4] || m[5]) {
+            simple = false
+        }
+    }
+    if (terms > maxDiceTerms) {
+        return null
+    }
+    return { simple: simple && terms === 1, sides }
+}
+
+// Render the breakdown of each term of a dice expression and its total.
+// Rolls of multiple dice are grouped in brackets with dropped dice struck out.
+function formatDiceRoll({ terms, total }: DiceRoll): string {
+    let s = ""
+    for (let i = 0; i < terms.length; i++) {
+        const { negative, rolls, dropped, constant } = terms[i]
+        if (negative) {
+            s += " - "
+        } else if (i) {
+            s += " + "
+        }
+        if (!rolls) {
+            s += constant || 0
+            continue
+        }
+
+        const parts = rolls.map((r, j) =>
+            dropped && dropped[j] ? `<s>${r}</s>` : r.toString())
+        s += rolls.length > 1 ? `[${parts.join(", ")}]` : parts[0]
+    }
+    return `${s} = ${total}`
+}
+
+// Render a #poll with its options on the following lines and skip the lines
+// of the options in the body
+function formatPoll({ options, votes }: PollState, state: TextState): string {
+    let html = `<span class="poll"><strong>#poll</strong>`
+    for (let i = 0; i < options.length; i++) {
+        html += `<br><a class="poll-option" data-option=${i}>`
+            + `${escape(options[i])}</a> <strong>(${votes[i] || 0})</strong>`
+    }
+    state.pollLines = options.length
+    return html + "</span>"
+}
+
 function getRollFormatting(numberOfDice: number, facesPerDie: number, sum: number): string {
     const maxRoll = numberOfDice * facesPerDie
     // no special formatting for small rolls
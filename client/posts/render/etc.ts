@@ -5,11 +5,16 @@ import lang from '../../lang'
 import { makeAttrs, pluralize } from "../../util"
 import { PostLink } from "../../common"
 
-// Render a link to other posts
-export function renderPostLink(link: PostLink): string {
+// Render a link to other posts. crossBoard also displays the board of the
+// linked post.
+export function renderPostLink(link: PostLink, crossBoard = false): string {
     const cross = link.op !== page.thread,
         url = `${cross ? `/${link.board}/${link.op}` : ""}#p${link.id}`
-    let html = `<a class="post-link" data-id="${link.id}" href="${url}">>>${link.id}`
+    let html = `<a class="post-link" data-id="${link.id}" href="${url}">>>`
+    if (crossBoard) {
+        html += `>/${link.board}/`
+    }
+    html += link.id
     if (cross && page.thread) {
         html += " ➡"
     }
@@ -78,6 +78,9 @@ export default class PostView extends ImageHandler {
         if (this.model.image) {
             this.renderImage(false)
         }
+        if (this.model.files) {
+            this.renderFiles()
+        }
     }
 
     // Get the current Element for text to be written to
@@ -155,7 +158,7 @@ export default class PostView extends ImageHandler {
                     id: id,
                     op: bl.op,
                     board: bl.board,
-                })
+                }, bl.board !== this.model.board)
                 + "</em>"
         }
 
@@ -166,6 +169,9 @@ export default class PostView extends ImageHandler {
     protected renderHeader() {
         this.renderTime()
         this.renderName()
+        if (this.model.edited) {
+            this.renderEdited()
+        }
         if (this.model.sticky) {
             this.renderSticky()
         }
@@ -209,6 +215,24 @@ export default class PostView extends ImageHandler {
         }
     }
 
+    // Render a link to the edit history of the post, if it was edited
+    public renderEdited() {
+        const old = this.el.querySelector(".edited")
+        if (old) {
+            old.remove()
+        }
+        if (!this.model.edited) {
+            return
+        }
+        const el = document.createElement("a")
+        el.classList.add("edited")
+        el.href = `/html/post-history/${this.model.id}`
+        el.target = "_blank"
+        el.title = relativeTime(this.model.edited)
+        el.textContent = lang.posts["edited"]
+        this.el.querySelector("time").after(el)
+    }
+
     // Renders classic absolute timestamp
     private readableTime(): string {
         const d = new Date(this.model.time * 1000)
@@ -304,6 +328,12 @@ export default class PostView extends ImageHandler {
                 case ModerationAction.deleteImage:
                     s = this.format('imageDeleted', by);
                     break;
+                case ModerationAction.selfDeletePost:
+                    s = this.format('selfDeleted');
+                    break;
+                case ModerationAction.selfDeleteImage:
+                    s = this.format('imageSelfDeleted');
+                    break;
                 case ModerationAction.spoilerImage:
                     s = this.format("imageSpoilered", by)
                     break;
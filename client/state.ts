@@ -13,6 +13,7 @@ interface Configs {
 	pruneThreads: boolean
 	threadExpiryMin: number
 	threadExpiryMax: number
+	threadsPerPage: number
 	maxSize: number
 	defaultLang: string
 	defaultCSS: string
@@ -28,6 +29,9 @@ export interface BoardConfigs {
 	forcedLive: boolean
 	rbText: boolean
 	pyu: boolean
+	allowEdits: number
+	allowSelfDelete: number
+	maxFiles: number
 	title: string
 	notice: string
 	rules: string
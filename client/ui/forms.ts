@@ -21,12 +21,14 @@ abstract class FormView extends View<Model> {
 		this.onClick({
 			"input[name=cancel]": () =>
 				this.remove(),
-			".map-remove, .array-remove": e =>
+			".map-remove, .array-remove, .filter-remove": e =>
 				this.removeInput(e),
 			".map-add": e =>
 				this.addInput(e, "keyValue"),
 			".array-add": e =>
 				this.addInput(e, "arrayItem"),
+			".filter-add": e =>
+				this.addInput(e, "wordFilter"),
 		})
 		this.on("submit", e =>
 			this.submit(e))
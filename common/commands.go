@@ -1,6 +1,7 @@
 package common
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -36,17 +37,32 @@ const (
 
 	// Rcount - number of bans handed out from #roulette
 	Rcount
+
+	// Poll is a vote on options listed on the lines following the command
+	Poll
 )
 
+// MaxPollOptions is the maximum number of options a #poll can have
+const MaxPollOptions = 10
+
+// PollState contains the options of a #poll and the number of votes cast for
+// each of them
+type PollState struct {
+	Options []string `json:"options"`
+	Votes   []uint32 `json:"votes"`
+}
+
 // Command contains the type and value array of hash commands, such as dice
 // rolls, #flip, #8ball, etc. The Val field depends on the Type field.
-// Dice: []uint16
+// Dice: []uint16 or DiceRoll, if the expression has modifiers or multiple
+// terms. The rolled expression is kept in DiceExpr.
 // Flip: bool
 // EightBall: string
 // SyncWatch: [5]uint64
 // Pyu: uint64
 // Pcount: uint64
 // Roulette: [2]uint8
+// Poll: PollState
 type Command struct {
 	Type      CommandType
 	Flip      bool
@@ -54,7 +70,10 @@ type Command struct {
 	SyncWatch [5]uint64
 	Eightball string
 	Dice      []uint16
+	DiceRoll  *DiceRoll
+	DiceExpr  string
 	Roulette  [2]uint8
+	Poll      PollState
 }
 
 // MarshalJSON implements json.Marshaler
@@ -91,14 +110,28 @@ func (c Command) MarshalJSON() ([]byte, error) {
 	case EightBall:
 		b = strconv.AppendQuote(b, c.Eightball)
 	case Dice:
-		appendByte('[')
-		for i, v := range c.Dice {
-			if i != 0 {
-				appendByte(',')
+		if c.DiceRoll != nil {
+			buf, err := json.Marshal(c.DiceRoll)
+			if err != nil {
+				return nil, err
 			}
-			appendUint(uint64(v))
+			b = append(b, buf...)
+		} else {
+			appendByte('[')
+			for i, v := range c.Dice {
+				if i != 0 {
+					appendByte(',')
+				}
+				appendUint(uint64(v))
+			}
+			appendByte(']')
+		}
+		if c.DiceExpr != "" {
+			// Dice expressions never contain characters in need of escaping
+			appendStr(`,"expr":"`)
+			appendStr(c.DiceExpr)
+			appendByte('"')
 		}
-		appendByte(']')
 	case Roulette:
 		appendByte('[')
 		for i, v := range c.Roulette {
@@ -108,6 +141,12 @@ func (c Command) MarshalJSON() ([]byte, error) {
 			appendUint(uint64(v))
 		}
 		appendByte(']')
+	case Poll:
+		buf, err := json.Marshal(c.Poll)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, buf...)
 	}
 
 	b = append(b, '}')
@@ -115,6 +154,9 @@ func (c Command) MarshalJSON() ([]byte, error) {
 	return b, nil
 }
 
+// Key of the rolled expression following the value of a dice command
+var diceExprKey = []byte(`,"expr":`)
+
 // UnmarshalJSON decodes a dynamically-typed JSON-encoded command into the
 // statically-typed Command struct
 func (c *Command) UnmarshalJSON(data []byte) error {
@@ -146,13 +188,29 @@ func (c *Command) UnmarshalJSON(data []byte) error {
 		err = json.Unmarshal(data, &c.Eightball)
 	case Dice:
 		c.Type = Dice
-		err = json.Unmarshal(data, &c.Dice)
+		if i := bytes.LastIndex(data, diceExprKey); i != -1 {
+			err = json.Unmarshal(data[i+len(diceExprKey):], &c.DiceExpr)
+			if err != nil {
+				return err
+			}
+			data = data[:i]
+		}
+		if len(data) != 0 && data[0] == '{' {
+			c.DiceRoll = new(DiceRoll)
+			err = json.Unmarshal(data, c.DiceRoll)
+			c.Dice = c.DiceRoll.rolls()
+		} else {
+			err = json.Unmarshal(data, &c.Dice)
+		}
 	case Roulette:
 		c.Type = Roulette
 		err = json.Unmarshal(data, &c.Roulette)
 	case Rcount:
 		c.Type = Rcount
 		err = json.Unmarshal(data, &c.Pyu)
+	case Poll:
+		c.Type = Poll
+		err = json.Unmarshal(data, &c.Poll)
 	default:
 		return fmt.Errorf("unknown command type: %d", typ)
 	}
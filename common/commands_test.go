@@ -20,6 +20,41 @@ func TestCommandsMarshaling(t *testing.T) {
 			Type: Pcount,
 			Pyu:  1,
 		}},
+		{"dice", Command{
+			Type: Dice,
+			Dice: []uint16{3, 5},
+		}},
+		{"dice with expression", Command{
+			Type:     Dice,
+			Dice:     []uint16{3, 5},
+			DiceExpr: "2d6",
+		}},
+		{"extended dice", Command{
+			Type:     Dice,
+			Dice:     []uint16{3, 5, 6},
+			DiceExpr: "2d6kh1-d6+2",
+			DiceRoll: &DiceRoll{
+				Terms: []DiceTermRoll{
+					{
+						Rolls:   []uint16{3, 5},
+						Dropped: []bool{true, false},
+					},
+					{
+						Negative: true,
+						Rolls:    []uint16{6},
+					},
+					{Constant: 2},
+				},
+				Total: 1,
+			},
+		}},
+		{"poll", Command{
+			Type: Poll,
+			Poll: PollState{
+				Options: []string{"foo", "bar \"baz\""},
+				Votes:   []uint32{2, 0},
+			},
+		}},
 	}
 
 	for i := range cases {
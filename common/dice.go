@@ -0,0 +1,201 @@
+package common
+
+import (
+	"strconv"
+)
+
+const (
+	// A roll of one or more dice with optional exploding and keep/drop
+	// modifiers, like "4d6!kh3"
+	diceTerm = `\d*d\d+!?(?:[kd][hl]\d+)?`
+
+	// A dice roll followed by any number of dice rolls or constants to add or
+	// subtract, like "2d20kh1+5"
+	diceExpr = diceTerm + `(?:[+-](?:` + diceTerm + `|\d+))*`
+)
+
+// Errors of invalid dice expressions
+var (
+	ErrTooManyRolls = ErrInvalidInput("too many rolls")
+	ErrDieTooBig    = ErrInvalidInput("die too big")
+	ErrInvalidDice  = ErrInvalidInput("invalid dice expression")
+)
+
+// DiceSelection specifies, which of the rolled dice of a term are counted
+// towards the total
+type DiceSelection uint8
+
+const (
+	// KeepAll counts all rolled dice
+	KeepAll DiceSelection = iota
+
+	// KeepHighest counts only the N highest dice
+	KeepHighest
+
+	// KeepLowest counts only the N lowest dice
+	KeepLowest
+
+	// DropHighest counts all dice except the N highest
+	DropHighest
+
+	// DropLowest counts all dice except the N lowest
+	DropLowest
+)
+
+// DiceTerm is a single parsed term of a dice expression. Either a roll of one
+// or more dice or a constant.
+type DiceTerm struct {
+	Negative, Dice, Explode bool
+	Count, Sides, Constant  int
+	Select                  DiceSelection
+	SelectN                 int
+}
+
+// DiceExpr is a parsed dice expression, such as "2d20kh1+5"
+type DiceExpr []DiceTerm
+
+// IsSimple returns, if the expression is a plain "NdM" roll without any
+// modifiers. The results of these are encoded as a flat array of rolls for
+// backwards compatibility.
+func (e DiceExpr) IsSimple() bool {
+	if len(e) != 1 {
+		return false
+	}
+	t := e[0]
+	return !t.Explode && t.Select == KeepAll
+}
+
+// ParseDice parses and validates a dice expression
+func ParseDice(s string) (expr DiceExpr, err error) {
+	if !DiceRegexp.MatchString(s) {
+		return nil, ErrInvalidDice
+	}
+
+	var i int
+	// Read a decimal number at the current position, if any
+	readNum := func() (n int, ok bool, err error) {
+		j := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == j {
+			return
+		}
+		n, err = strconv.Atoi(s[j:i])
+		if err != nil {
+			err = ErrInvalidDice
+		}
+		ok = err == nil
+		return
+	}
+
+	for i < len(s) {
+		var t DiceTerm
+		switch s[i] {
+		case '-':
+			t.Negative = true
+			fallthrough
+		case '+':
+			i++
+		}
+
+		var (
+			n  int
+			ok bool
+		)
+		n, ok, err = readNum()
+		if err != nil {
+			return
+		}
+		if i == len(s) || s[i] != 'd' {
+			if n > MaxDiceSides {
+				return nil, ErrInvalidDice
+			}
+			t.Constant = n
+			expr = append(expr, t)
+			continue
+		}
+
+		t.Dice = true
+		t.Count = 1
+		if ok {
+			t.Count = n
+		}
+		i++ // 'd'
+		t.Sides, _, err = readNum()
+		if err != nil {
+			return
+		}
+		if i < len(s) && s[i] == '!' {
+			t.Explode = true
+			i++
+		}
+		if i+1 < len(s) && (s[i] == 'k' || s[i] == 'd') {
+			switch s[i : i+2] {
+			case "kh":
+				t.Select = KeepHighest
+			case "kl":
+				t.Select = KeepLowest
+			case "dh":
+				t.Select = DropHighest
+			case "dl":
+				t.Select = DropLowest
+			}
+			i += 2
+			t.SelectN, _, err = readNum()
+			if err != nil {
+				return
+			}
+		}
+
+		switch {
+		case t.Count > MaxNumDice:
+			return nil, ErrTooManyRolls
+		case t.Sides > MaxDiceSides:
+			return nil, ErrDieTooBig
+		case t.Explode && t.Sides < 2:
+			return nil, ErrInvalidDice
+		}
+		switch t.Select {
+		case KeepHighest, KeepLowest:
+			if t.SelectN < 1 || t.SelectN > t.Count {
+				return nil, ErrInvalidDice
+			}
+		case DropHighest, DropLowest:
+			if t.SelectN < 1 || t.SelectN >= t.Count {
+				return nil, ErrInvalidDice
+			}
+		}
+		expr = append(expr, t)
+	}
+
+	if len(expr) > MaxDiceTerms {
+		return nil, ErrTooManyRolls
+	}
+	return
+}
+
+// DiceRoll contains the breakdown and total of a rolled dice expression
+type DiceRoll struct {
+	Terms []DiceTermRoll `json:"terms"`
+	Total int            `json:"total"`
+}
+
+// DiceTermRoll contains the results of a single term of a dice expression.
+// Constant terms have no rolls. Dropped is either empty or marks the dice in
+// Rolls, that are not counted towards the total.
+type DiceTermRoll struct {
+	Negative bool     `json:"negative,omitempty"`
+	Rolls    []uint16 `json:"rolls,omitempty"`
+	Dropped  []bool   `json:"dropped,omitempty"`
+	Constant int      `json:"constant,omitempty"`
+}
+
+// Returns all rolled dice of the expression in order
+func (d *DiceRoll) rolls() []uint16 {
+	var rolls []uint16
+	for _, t := range d.Terms {
+		rolls = append(rolls, t.Rolls...)
+	}
+	return rolls
+}
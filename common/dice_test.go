@@ -0,0 +1,105 @@
+package common
+
+import (
+	"testing"
+
+	. "github.com/bakape/meguca/test"
+)
+
+func TestParseDice(t *testing.T) {
+	t.Parallel()
+
+	cases := [...]struct {
+		name, in string
+		err      error
+		expr     DiceExpr
+	}{
+		{
+			name: "single die",
+			in:   "d20",
+			expr: DiceExpr{{Dice: true, Count: 1, Sides: 20}},
+		},
+		{
+			name: "keep highest with modifier",
+			in:   "2d20kh1+5",
+			expr: DiceExpr{
+				{
+					Dice:    true,
+					Count:   2,
+					Sides:   20,
+					Select:  KeepHighest,
+					SelectN: 1,
+				},
+				{Constant: 5},
+			},
+		},
+		{
+			name: "exploding and drop lowest",
+			in:   "4d6!dl1-d4",
+			expr: DiceExpr{
+				{
+					Dice:    true,
+					Explode: true,
+					Count:   4,
+					Sides:   6,
+					Select:  DropLowest,
+					SelectN: 1,
+				},
+				{Negative: true, Dice: true, Count: 1, Sides: 4},
+			},
+		},
+		{
+			name: "invalid syntax",
+			in:   "2d20kx1",
+			err:  ErrInvalidDice,
+		},
+		{
+			name: "constant first",
+			in:   "5+d20",
+			err:  ErrInvalidDice,
+		},
+		{
+			name: "too many dice",
+			in:   "11d6",
+			err:  ErrTooManyRolls,
+		},
+		{
+			name: "too many sides",
+			in:   "d10001",
+			err:  ErrDieTooBig,
+		},
+		{
+			name: "keep more than rolled",
+			in:   "2d6kh3",
+			err:  ErrInvalidDice,
+		},
+		{
+			name: "drop all",
+			in:   "2d6dl2",
+			err:  ErrInvalidDice,
+		},
+		{
+			name: "exploding single sided die",
+			in:   "d1!",
+			err:  ErrInvalidDice,
+		},
+		{
+			name: "too many terms",
+			in:   "d6+1+1+1+1+1+1+1+1+1+1",
+			err:  ErrTooManyRolls,
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseDice(c.in)
+			if err != c.err {
+				LogUnexpected(t, c.err, err)
+			}
+			AssertEquals(t, expr, c.expr)
+		})
+	}
+}
@@ -48,6 +48,8 @@ func (e StatusError) Error() string {
 		prefix = "not found"
 	case 500:
 		prefix = "internal server error"
+	case 503:
+		prefix = "service unavailable"
 	}
 	return fmt.Sprintf("%s: %s", prefix, e.Err)
 }
@@ -20,6 +20,10 @@ const (
 	MeidoVision
 	PurgePost
 	ShadowBinPost
+	AcceptAppeal
+	DenyAppeal
+	SelfDeletePost
+	SelfDeleteImage
 )
 
 // Contains fields of a post moderation log entry
@@ -0,0 +1,442 @@
+package common
+
+// Conversion between the JSON payloads of protocol version 1 and the
+// MessagePack payloads of protocol version 2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Deepest nesting of arrays and maps allowed in transcoded payloads. Guards
+// against stack exhaustion by client-sent messages.
+const maxTranscodingDepth = 32
+
+var (
+	errInvalidMsgpack  = errors.New("invalid MessagePack")
+	errTooDeeplyNested = errors.New("payload too deeply nested")
+)
+
+// Convert a single JSON value to MessagePack
+func jsonToMsgpack(w *bytes.Buffer, data []byte) (err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = transcodeJSONValue(w, dec, 0)
+	if err != nil {
+		return
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return errors.New("trailing data after JSON value")
+	}
+	return nil
+}
+
+func transcodeJSONValue(w *bytes.Buffer, dec *json.Decoder, depth int,
+) (err error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		w.WriteByte(0xc0)
+	case bool:
+		if tok {
+			w.WriteByte(0xc3)
+		} else {
+			w.WriteByte(0xc2)
+		}
+	case string:
+		writeMsgpackString(w, tok)
+	case json.Number:
+		return writeMsgpackNumber(w, tok)
+	case json.Delim:
+		if depth == maxTranscodingDepth {
+			return errTooDeeplyNested
+		}
+
+		// Element count must precede the elements. Write a placeholder header
+		// and patch it in, once the count is known.
+		var (
+			start = w.Len()
+			n     int
+		)
+		w.WriteByte(0)
+		for dec.More() {
+			if tok == '{' {
+				var key json.Token
+				key, err = dec.Token()
+				if err != nil {
+					return
+				}
+				writeMsgpackString(w, key.(string))
+			}
+			err = transcodeJSONValue(w, dec, depth+1)
+			if err != nil {
+				return
+			}
+			n++
+		}
+		// Closing delimiter
+		if _, err = dec.Token(); err != nil {
+			return
+		}
+
+		if tok == '{' {
+			patchMsgpackHeader(w, start, n, 0x80, 0xde)
+		} else {
+			patchMsgpackHeader(w, start, n, 0x90, 0xdc)
+		}
+	}
+	return
+}
+
+func writeMsgpackNumber(w *bytes.Buffer, n json.Number) error {
+	s := string(n)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if i >= 0 {
+			writeMsgpackUint(w, uint64(i))
+		} else {
+			writeMsgpackInt(w, i)
+		}
+		return nil
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		writeMsgpackUint(w, u)
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	w.WriteByte(0xcb)
+	writeBigEndian(w, math.Float64bits(f), 8)
+	return nil
+}
+
+func writeMsgpackUint(w *bytes.Buffer, u uint64) {
+	switch {
+	case u <= 0x7f:
+		w.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		w.WriteByte(0xcc)
+		w.WriteByte(byte(u))
+	case u <= math.MaxUint16:
+		w.WriteByte(0xcd)
+		writeBigEndian(w, u, 2)
+	case u <= math.MaxUint32:
+		w.WriteByte(0xce)
+		writeBigEndian(w, u, 4)
+	default:
+		w.WriteByte(0xcf)
+		writeBigEndian(w, u, 8)
+	}
+}
+
+func writeMsgpackInt(w *bytes.Buffer, i int64) {
+	switch {
+	case i >= -32:
+		w.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		w.WriteByte(0xd0)
+		w.WriteByte(byte(i))
+	case i >= math.MinInt16:
+		w.WriteByte(0xd1)
+		writeBigEndian(w, uint64(i), 2)
+	case i >= math.MinInt32:
+		w.WriteByte(0xd2)
+		writeBigEndian(w, uint64(i), 4)
+	default:
+		w.WriteByte(0xd3)
+		writeBigEndian(w, uint64(i), 8)
+	}
+}
+
+func writeMsgpackString(w *bytes.Buffer, s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		w.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		w.WriteByte(0xd9)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(0xda)
+		writeBigEndian(w, uint64(n), 2)
+	default:
+		w.WriteByte(0xdb)
+		writeBigEndian(w, uint64(n), 4)
+	}
+	w.WriteString(s)
+}
+
+func writeMsgpackBinary(w *bytes.Buffer, buf []byte) {
+	n := len(buf)
+	switch {
+	case n <= math.MaxUint8:
+		w.WriteByte(0xc4)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(0xc5)
+		writeBigEndian(w, uint64(n), 2)
+	default:
+		w.WriteByte(0xc6)
+		writeBigEndian(w, uint64(n), 4)
+	}
+	w.Write(buf)
+}
+
+// Write the header of an array or map. fix is the type code of the fixed
+// length variant and wide the one of the 16 bit length variant.
+func writeMsgpackHeader(w *bytes.Buffer, n int, fix, wide byte) {
+	hdr, size := encodeMsgpackHeader(n, fix, wide)
+	w.Write(hdr[:size])
+}
+
+// Replace the 1 byte placeholder at start with the array or map header for n
+// elements. Shifts the elements right, if a wider header is needed.
+func patchMsgpackHeader(w *bytes.Buffer, start, n int, fix, wide byte) {
+	hdr, size := encodeMsgpackHeader(n, fix, wide)
+	if size > 1 {
+		end := w.Len()
+		w.Write(hdr[1:size]) // Grow the buffer by the extra header bytes
+		buf := w.Bytes()
+		copy(buf[start+size:], buf[start+1:end])
+	}
+	copy(w.Bytes()[start:], hdr[:size])
+}
+
+// Encode an array or map header for n elements. Returns the header and its
+// length.
+func encodeMsgpackHeader(n int, fix, wide byte) (hdr [5]byte, size int) {
+	switch {
+	case n < 16:
+		hdr[0] = fix | byte(n)
+		return hdr, 1
+	case n <= math.MaxUint16:
+		hdr[0] = wide
+		binary.BigEndian.PutUint16(hdr[1:], uint16(n))
+		return hdr, 3
+	default:
+		hdr[0] = wide + 1
+		binary.BigEndian.PutUint32(hdr[1:], uint32(n))
+		return hdr, 5
+	}
+}
+
+// Write the lowest size bytes of u in big endian order
+func writeBigEndian(w *bytes.Buffer, u uint64, size int) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], u)
+	w.Write(buf[8-size:])
+}
+
+// Reads MessagePack values from a buffer
+type msgpackReader struct {
+	buf []byte
+	pos int
+}
+
+// Convert a single MessagePack value to JSON
+func msgpackToJSON(data []byte) ([]byte, error) {
+	var w bytes.Buffer
+	r := msgpackReader{buf: data}
+	err := r.transcode(&w, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.pos != len(data) {
+		return nil, errInvalidMsgpack
+	}
+	return w.Bytes(), nil
+}
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || len(r.buf)-r.pos < n {
+		return nil, errInvalidMsgpack
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *msgpackReader) readUint(size int) (u uint64, err error) {
+	b, err := r.next(size)
+	if err != nil {
+		return
+	}
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return
+}
+
+func (r *msgpackReader) transcode(w *bytes.Buffer, depth int) (err error) {
+	b, err := r.next(1)
+	if err != nil {
+		return
+	}
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		w.WriteString(strconv.Itoa(int(c)))
+		return
+	case c >= 0xe0:
+		w.WriteString(strconv.Itoa(int(int8(c))))
+		return
+	case c&0xf0 == 0x80:
+		return r.transcodeMap(w, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return r.transcodeArray(w, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return r.transcodeString(w, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		w.WriteString("null")
+	case 0xc2:
+		w.WriteString("false")
+	case 0xc3:
+		w.WriteString("true")
+	case 0xca, 0xcb:
+		var u uint64
+		var f float64
+		if c == 0xca {
+			u, err = r.readUint(4)
+			f = float64(math.Float32frombits(uint32(u)))
+		} else {
+			u, err = r.readUint(8)
+			f = math.Float64frombits(u)
+		}
+		if err != nil {
+			return
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return errInvalidMsgpack
+		}
+		w.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		var u uint64
+		u, err = r.readUint(1 << (c - 0xcc))
+		if err != nil {
+			return
+		}
+		w.WriteString(strconv.FormatUint(u, 10))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		var u uint64
+		u, err = r.readUint(size)
+		if err != nil {
+			return
+		}
+		// Sign extend
+		shift := uint(64 - size*8)
+		w.WriteString(strconv.FormatInt(int64(u<<shift)>>shift, 10))
+	case 0xd9, 0xda, 0xdb:
+		var n uint64
+		n, err = r.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return
+		}
+		return r.transcodeString(w, int(n))
+	case 0xdc, 0xdd, 0xde, 0xdf:
+		var n uint64
+		n, err = r.readUint(2 << ((c - 0xdc) % 2))
+		if err != nil {
+			return
+		}
+		if c < 0xde {
+			return r.transcodeArray(w, int(n), depth)
+		}
+		return r.transcodeMap(w, int(n), depth)
+	default:
+		// Binary and extension types have no JSON equivalent
+		return errInvalidMsgpack
+	}
+	return
+}
+
+func (r *msgpackReader) transcodeString(w *bytes.Buffer, n int) error {
+	b, err := r.next(n)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(string(b))
+	if err != nil {
+		return err
+	}
+	w.Write(buf)
+	return nil
+}
+
+func (r *msgpackReader) transcodeArray(w *bytes.Buffer, n, depth int,
+) (err error) {
+	if depth == maxTranscodingDepth {
+		return errTooDeeplyNested
+	}
+	w.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			w.WriteByte(',')
+		}
+		err = r.transcode(w, depth+1)
+		if err != nil {
+			return
+		}
+	}
+	w.WriteByte(']')
+	return
+}
+
+func (r *msgpackReader) transcodeMap(w *bytes.Buffer, n, depth int,
+) (err error) {
+	if depth == maxTranscodingDepth {
+		return errTooDeeplyNested
+	}
+	w.WriteByte('{')
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			w.WriteByte(',')
+		}
+		err = r.transcodeKey(w)
+		if err != nil {
+			return
+		}
+		w.WriteByte(':')
+		err = r.transcode(w, depth+1)
+		if err != nil {
+			return
+		}
+	}
+	w.WriteByte('}')
+	return
+}
+
+// JSON object keys must be strings. Integer keys, such as post IDs, are
+// converted to their string representation.
+func (r *msgpackReader) transcodeKey(w *bytes.Buffer) (err error) {
+	var key bytes.Buffer
+	err = r.transcode(&key, maxTranscodingDepth)
+	if err != nil {
+		return
+	}
+	switch c := key.Bytes()[0]; {
+	case c == '"':
+		w.Write(key.Bytes())
+	case c == '-' || (c >= '0' && c <= '9'):
+		w.WriteByte('"')
+		w.Write(key.Bytes())
+		w.WriteByte('"')
+	default:
+		return errInvalidMsgpack
+	}
+	return
+}
@@ -0,0 +1,201 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/bakape/meguca/test"
+)
+
+func TestJSONToMsgpack(t *testing.T) {
+	t.Parallel()
+
+	cases := [...]struct {
+		name, in string
+		out      []byte
+	}{
+		{"null", `null`, []byte{0xc0}},
+		{"bool", `[true,false]`, []byte{0x92, 0xc3, 0xc2}},
+		{"fixint", `127`, []byte{0x7f}},
+		{"uint8", `200`, []byte{0xcc, 200}},
+		{"uint16", `1000`, []byte{0xcd, 0x03, 0xe8}},
+		{"uint64", `18446744073709551615`,
+			[]byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"negative fixint", `-1`, []byte{0xff}},
+		{"int8", `-100`, []byte{0xd0, 0x9c}},
+		{"float", `0.5`,
+			[]byte{0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+		{"string", `"foo"`, []byte{0xa3, 'f', 'o', 'o'}},
+		{"map", `{"a":1}`, []byte{0x81, 0xa1, 'a', 0x01}},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var w bytes.Buffer
+			err := jsonToMsgpack(&w, []byte(c.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertEquals(t, w.Bytes(), c.out)
+
+			res, err := msgpackToJSON(c.out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertEquals(t, string(res), c.in)
+		})
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	const std = `{"id":1,"body":"` +
+		"foo\\n\\\"bar\\\" あ" +
+		`","list":[-1000000,{"nested":[]},null,1.25],"empty":{}}`
+
+	var w bytes.Buffer
+	err := jsonToMsgpack(&w, []byte(std))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := msgpackToJSON(w.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, string(res), std)
+}
+
+func TestMsgpackWideArray(t *testing.T) {
+	t.Parallel()
+
+	in := `[[` + strings.Repeat(`1,`, 19) + `1],2]`
+	out := []byte{0x92, 0xdc, 0, 20}
+	out = append(out, bytes.Repeat([]byte{0x01}, 20)...)
+	out = append(out, 0x02)
+
+	var w bytes.Buffer
+	err := jsonToMsgpack(&w, []byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, w.Bytes(), out)
+
+	res, err := msgpackToJSON(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, string(res), in)
+}
+
+func TestInvalidMsgpack(t *testing.T) {
+	t.Parallel()
+
+	nested := bytes.Repeat([]byte{0x91}, maxTranscodingDepth+1)
+	cases := [...]struct {
+		name string
+		in   []byte
+	}{
+		{"truncated string", []byte{0xa3, 'f'}},
+		{"truncated uint", []byte{0xcd, 0x01}},
+		{"missing array element", []byte{0x92, 0x01}},
+		{"trailing data", []byte{0x01, 0x02}},
+		{"binary", []byte{0xc4, 0x01, 0x00}},
+		{"map key", []byte{0x81, 0x90, 0x01}},
+		{"too deeply nested", append(nested, 0xc0)},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := msgpackToJSON(c.in); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestTranscodeMessage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("version 1", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := TranscodeMessage(1, []byte(`02"a"`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEquals(t, string(res), `02"a"`)
+	})
+
+	t.Run("no payload", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := TranscodeMessage(2, []byte("34"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEquals(t, res, []byte{34})
+	})
+
+	t.Run("concatenated", func(t *testing.T) {
+		t.Parallel()
+
+		msg, err := EncodeMessage(MessageConcat, []string{`02"a"`, "34"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := TranscodeMessage(2, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEquals(t, res, []byte{
+			byte(MessageConcat), 0x92,
+			0xc4, 0x03, byte(MessageAppend), 0xa1, 'a',
+			0xc4, 0x01, byte(MessageNOOP),
+		})
+	})
+}
+
+func TestDecodeBinaryMessage(t *testing.T) {
+	t.Parallel()
+
+	typ, data, err := DecodeBinaryMessage([]byte{
+		byte(MessageSynchronise), 0x82,
+		0xa5, 'b', 'o', 'a', 'r', 'd', 0xa1, 'a',
+		0xa6, 't', 'h', 'r', 'e', 'a', 'd', 0x01,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, typ, MessageSynchronise)
+	AssertEquals(t, string(data), `{"board":"a","thread":1}`)
+}
+
+func TestMessageEncode(t *testing.T) {
+	t.Parallel()
+
+	m := NewMessage([]byte("3012"))
+	for i := 0; i < 2; i++ {
+		buf, err := m.Encode(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEquals(t, buf, []byte{byte(MessageSynchronise), 12})
+	}
+	buf, err := m.Encode(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, string(buf), "3012")
+
+	if _, err := m.Encode(ProtocolVersion + 1); err == nil {
+		t.Fatal("expected error")
+	}
+}
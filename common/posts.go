@@ -6,6 +6,9 @@ package common
 // TODO: Clean up this function signature
 var ParseBody func([]byte, string, uint64, uint64, string, bool) ([]Link, []Command, error)
 
+// CensorText forwards parser.CensorText to avoid cyclic imports in db/upkeep
+var CensorText func(board, scope, text string) (string, bool)
+
 // Board is defined to enable marshalling optimizations and sorting by sticky
 // threads
 type Board struct {
@@ -34,6 +37,7 @@ type Thread struct {
 	Sticky     bool   `json:"sticky"`
  	//NonLive    bool   `json:"nonLive,omitempty"`
 	Locked     bool   `json:"locked"`
+	Archived   bool   `json:"archived"`
 	PostCount  uint32 `json:"post_count"`
 	ImageCount uint32 `json:"image_count"`
 	UpdateTime int64  `json:"update_time"`
@@ -42,6 +46,9 @@ type Thread struct {
 	Board      string `json:"board"`
 	Post
 	Posts []Post `json:"posts"`
+
+	// Links to posts of the thread from posts on other boards
+	CrossBoardBacklinks []Backlink `json:"cross_board_backlinks,omitempty"`
 }
 
 // Post is a generic post exposed publically through the JSON API. Either OP or
@@ -53,20 +60,33 @@ type Post struct {
 	Auth       ModerationLevel   `json:"auth"`
 	ID         uint64            `json:"id"`
 	Time       int64             `json:"time"`
+	Edited     int64             `json:"edited,omitempty"`
 	Body       string            `json:"body"`
 	Flag       string            `json:"flag"`
 	Name       string            `json:"name"`
 	Trip       string            `json:"trip"`
 	Image      *Image            `json:"image"`
+	Files      []Image           `json:"files,omitempty"`
 	Links      []Link            `json:"links"`
 	Commands   []Command         `json:"commands"`
 	Moderation []ModerationEntry `json:"moderation"`
 }
 
-// Return if post has been deleted by staff
+// Return if post has been deleted by staff or its author
 func (p *Post) IsDeleted() bool {
 	for _, l := range p.Moderation {
-		if l.Type == DeletePost {
+		switch l.Type {
+		case DeletePost, SelfDeletePost:
+			return true
+		}
+	}
+	return false
+}
+
+// Return if post contents have been purged by staff
+func (p *Post) IsPurged() bool {
+	for _, l := range p.Moderation {
+		if l.Type == PurgePost {
 			return true
 		}
 	}
@@ -80,6 +100,19 @@ type Link struct {
 	Board string `json:"board"`
 }
 
+// Backlink describes a link to post Target from another post
+type Backlink struct {
+	Link
+	Target uint64 `json:"target"`
+}
+
+// PostRevision is a previous version of the body of an edited post
+type PostRevision struct {
+	// Time the body was replaced by the next revision
+	Time int64  `json:"time"`
+	Body string `json:"body"`
+}
+
 // StandalonePost is a post view that includes the "op" and "board" fields,
 // which are not exposed though Post, but are required for retrieving a post
 // with unknown parenthood.
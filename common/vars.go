@@ -37,9 +37,15 @@ const (
 	MaxLenRules        = 5000
 	MaxLenEightball    = 2000
 	MaxLenReason       = 100
+	MaxLenAppeal       = 2000
+	MaxLenAppealNote   = 200
 	MaxNumBanners      = 20
 	MaxAssetSize       = 100 << 10
 	MaxDiceSides       = 10000
+	MaxNumDice         = 10
+	MaxDiceTerms       = 10
+	MaxDiceRolls       = 100
+	MaxNumFiles        = 10
 	BumpLimit          = 1000
 )
 
@@ -88,8 +94,8 @@ var (
 
 // Common Regex expressions
 var (
-	CommandRegexp = regexp.MustCompile(`^#(flip|\d*d\d+|8ball|pyu|pcount|sw(?:\d+:)?\d+:\d+(?:[+-]\d+)?|roulette|rcount)$`)
-	DiceRegexp    = regexp.MustCompile(`(\d*)d(\d+)`)
+	CommandRegexp = regexp.MustCompile(`^#(flip|` + diceExpr + `|8ball|pyu|pcount|sw(?:\d+:)?\d+:\d+(?:[+-]\d+)?|roulette|rcount|poll)$`)
+	DiceRegexp    = regexp.MustCompile(`^` + diceExpr + `$`)
 )
 
 func init() {
@@ -3,11 +3,22 @@ package common
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
 )
 
-// ProtocolVersion is the websocket protocol version
-const ProtocolVersion = 1
+// Supported websocket protocol versions. Version 1 encodes messages as a two
+// digit ASCII type followed by JSON and is sent in text frames. Version 2
+// encodes messages as a single type byte followed by MessagePack and is sent
+// in binary frames.
+const (
+	// ProtocolVersion is the latest websocket protocol version
+	ProtocolVersion = 2
+
+	// MinProtocolVersion is the oldest supported websocket protocol version
+	MinProtocolVersion = 1
+)
 
 // MessageType is the identifier code for websocket message types
 type MessageType uint8
@@ -59,12 +70,59 @@ const (
 	// Passes MeguTV playlist data
 	MessageMeguTV
 
-	// Used by the client to send it's protocol version and by the server to
-	// send server and board configurations
+	// Used by the client to negotiate the protocol version as its first message
+	// and by the server to confirm the negotiated version and send server and
+	// board configurations
 	MessageConfigs
 
 	// Set a cookie on the client
 	MessageSetCookie
+
+	// Sequence number of the last thread feed message sent to the client.
+	// Sent by the client on reconnection to resume from this message.
+	MessageFeedSequence
+
+	// Instructs the client to resynchronise from scratch, because the feed
+	// messages it missed while disconnected can no longer be replayed
+	MessageResync
+
+	// Catalog entry of a thread created or modified on a board page feed
+	MessageBoardThread
+
+	// ID of a thread deleted from a board page feed
+	MessageBoardThreadDeleted
+
+	// Used by the client to register watched threads and own posts in them
+	// and by the server to send post count changes and deletions of these
+	// threads
+	MessageWatchThreads
+
+	// A watched post of the client was quoted
+	MessageQuoted
+
+	// Messages of the client are being slowed down for exceeding a rate limit
+	MessageRateLimited
+
+	// Server is shutting down. Reconnect after the passed number of
+	// milliseconds.
+	MessageReconnect
+
+	// A post in a thread was linked to from another board
+	MessageBacklink
+
+	// Body of a closed post was edited by its author
+	MessageEditPost
+
+	// Used by the client to vote in a #poll and by the server to send the
+	// updated vote tallies of the poll
+	MessageVotePoll
+
+	// Deletion of a post or its image by the post's author
+	MessageSelfDelete
+
+	// Change to the open post's body was rejected by a word filter. Carries
+	// the current body of the post, that the client should revert to.
+	MessageRejectBody
 )
 
 // Forwarded functions from "github.com/bakape/megucawebsockets/feeds" to avoid circular imports
@@ -85,7 +143,11 @@ var (
 // Client exposes some globally accessible websocket client functionality
 // without causing circular imports
 type Client interface {
+	// Send a message encoded in protocol version 1. The message is converted
+	// to the protocol version of the client, if it differs.
 	Send([]byte)
+	// Send a message encoded in the protocol version of the client
+	SendMessage(*Message)
 	Redirect(board string)
 	IP() string
 	LastTime() int64
@@ -126,3 +188,96 @@ func PrependMessageType(typ MessageType, data []byte) []byte {
 
 	return encoded
 }
+
+// IsSupportedProtocolVersion returns, if v is a supported websocket protocol
+// version
+func IsSupportedProtocolVersion(v uint) bool {
+	return v >= MinProtocolVersion && v <= ProtocolVersion
+}
+
+// TranscodeMessage converts a message encoded in protocol version 1 to
+// protocol version v
+func TranscodeMessage(v uint8, msg []byte) ([]byte, error) {
+	if v <= MinProtocolVersion {
+		return msg, nil
+	}
+	if len(msg) < 2 {
+		return nil, errors.New("message too short")
+	}
+	typ, err := strconv.ParseUint(string(msg[:2]), 10, 8)
+	if err != nil {
+		return nil, err
+	}
+	data := msg[2:]
+
+	var w bytes.Buffer
+	w.Grow(len(msg))
+	w.WriteByte(byte(typ))
+	switch {
+	case len(data) == 0:
+	case MessageType(typ) == MessageConcat:
+		// Array of individually encoded messages
+		var msgs []string
+		err = json.Unmarshal(data, &msgs)
+		if err != nil {
+			return nil, err
+		}
+		writeMsgpackHeader(&w, len(msgs), 0x90, 0xdc)
+		for _, m := range msgs {
+			buf, err := TranscodeMessage(v, []byte(m))
+			if err != nil {
+				return nil, err
+			}
+			writeMsgpackBinary(&w, buf)
+		}
+	default:
+		err = jsonToMsgpack(&w, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return w.Bytes(), nil
+}
+
+// DecodeBinaryMessage splits a message encoded in protocol version 2 into
+// its type and payload converted to JSON
+func DecodeBinaryMessage(msg []byte) (
+	typ MessageType, data []byte, err error,
+) {
+	if len(msg) == 0 {
+		err = errors.New("empty message")
+		return
+	}
+	typ = MessageType(msg[0])
+	if len(msg) > 1 {
+		data, err = msgpackToJSON(msg[1:])
+	}
+	return
+}
+
+// Message is a websocket message encoded in protocol version 1, that is
+// converted to other protocol versions at most once per version. Allows
+// sending the same message to many clients of differing protocol versions.
+// Not safe for concurrent use.
+type Message struct {
+	encoded [ProtocolVersion - MinProtocolVersion + 1][]byte
+}
+
+// NewMessage wraps a message encoded in protocol version 1
+func NewMessage(msg []byte) *Message {
+	var m Message
+	m.encoded[0] = msg
+	return &m
+}
+
+// Encode returns the message encoded in protocol version v
+func (m *Message) Encode(v uint8) (buf []byte, err error) {
+	if v < MinProtocolVersion || v > ProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version: %d", v)
+	}
+	i := v - MinProtocolVersion
+	if m.encoded[i] == nil {
+		m.encoded[i], err = TranscodeMessage(v, m.encoded[0])
+	}
+	return m.encoded[i], err
+}
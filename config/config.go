@@ -61,12 +61,23 @@ var (
 	// Defaults contains the default server configuration values
 	Defaults = Configs{
 		BoardExpiry:       7,
+		ArchiveExpiry:     30,
 		MaxHeight:         6000,
 		MaxWidth:          6000,
 		SessionExpiry:     30,
 		CharScore:         170,
 		PostCreationScore: 15000,
 		ImageScore:        15000,
+		AppendRate:        1200,
+		AppendBurst:       200,
+		SpliceRate:        600,
+		SpliceBurst:       100,
+		InsertPostRate:    20,
+		InsertPostBurst:   5,
+		InsertImageRate:   20,
+		InsertImageBurst:  5,
+		ReclaimRate:       10,
+		ReclaimBurst:      3,
 		EmailErrPort:      587,
 		Salt:              "LALALALALALALALALALALALALALALALALALALALA",
 		EmailErrMail:      "admin@email.com",
@@ -83,6 +94,7 @@ var (
 			DefaultLang:     "en_GB",
 			ThreadExpiryMin: 7,
 			ThreadExpiryMax: 14,
+			ThreadsPerPage:  15,
 			MaxSize:         5,
 			Links:           map[string]string{"4chan": "http://www.4chan.org/"},
 		},
@@ -11,11 +11,22 @@ type Configs struct {
 	MaxWidth            uint16 `json:"maxWidth"`
 	MaxHeight           uint16 `json:"maxHeight"`
 	BoardExpiry         uint   `json:"boardExpiry"`
+	ArchiveExpiry       uint   `json:"archiveExpiry"`
 	SessionExpiry       uint   `json:"sessionExpiry"`
 	EmailErrPort        uint   `json:"emailErrPort"`
 	CharScore           uint   `json:"charScore"`
 	PostCreationScore   uint   `json:"postCreationScore"`
 	ImageScore          uint   `json:"imageScore"`
+	AppendRate          uint   `json:"appendRate"`
+	AppendBurst         uint   `json:"appendBurst"`
+	SpliceRate          uint   `json:"spliceRate"`
+	SpliceBurst         uint   `json:"spliceBurst"`
+	InsertPostRate      uint   `json:"insertPostRate"`
+	InsertPostBurst     uint   `json:"insertPostBurst"`
+	InsertImageRate     uint   `json:"insertImageRate"`
+	InsertImageBurst    uint   `json:"insertImageBurst"`
+	ReclaimRate         uint   `json:"reclaimRate"`
+	ReclaimBurst        uint   `json:"reclaimBurst"`
 	RootURL             string `json:"rootURL"`
 	Salt                string `json:"salt"`
 	EmailErrMail        string `json:"emailErrMail"`
@@ -35,6 +46,7 @@ type Public struct {
 	PruneThreads      bool              `json:"pruneThreads"`
 	ThreadExpiryMin   uint              `json:"threadExpiryMin"`
 	ThreadExpiryMax   uint              `json:"threadExpiryMax"`
+	ThreadsPerPage    uint              `json:"threadsPerPage"`
 	MaxSize           uint              `json:"maxSize"`
 	DefaultLang       string            `json:"defaultLang"`
 	DefaultCSS        string            `json:"defaultCSS"`
@@ -45,26 +57,63 @@ type Public struct {
 // BoardConfigs stores board-specific configuration
 type BoardConfigs struct {
 	BoardPublic
-	DisableRobots bool     `json:"disableRobots"`
-	ID            string   `json:"id"`
-	Eightball     []string `json:"eightball"`
+	DisableRobots bool         `json:"disableRobots"`
+	Archive       bool         `json:"archive"`
+	ID            string       `json:"id"`
+	Eightball     []string     `json:"eightball"`
+	WordFilters   []WordFilter `json:"wordFilters"`
+}
+
+// Scopes of text word filters apply to
+const (
+	FilterBody    = "body"
+	FilterName    = "name"
+	FilterSubject = "subject"
+)
+
+// Actions taken on text matching a word filter
+const (
+	FilterReplace = "replace"
+	FilterReject  = "reject"
+	FilterFlag    = "flag"
+)
+
+var (
+	// FilterScopes contains all valid word filter scopes
+	FilterScopes = [...]string{FilterBody, FilterName, FilterSubject}
+
+	// FilterActions contains all valid word filter actions
+	FilterActions = [...]string{FilterReplace, FilterReject, FilterFlag}
+)
+
+// WordFilter is a board-specific rule for rewriting, rejecting or flagging
+// text matching a literal or regex pattern
+type WordFilter struct {
+	Regex       bool   `json:"regex"`
+	Pattern     string `json:"pattern"`
+	Scope       string `json:"scope"`
+	Action      string `json:"action"`
+	Replacement string `json:"replacement"`
 }
 
 // BoardPublic contains publically accessible board-specific configurations
 type BoardPublic struct {
-	ReadOnly   bool `json:"readOnly"`
-	TextOnly   bool `json:"textOnly"`
-	ForcedAnon bool `json:"forcedAnon"`
-	Flags      bool `json:"flags"`
- 	NonLive    bool `json:"nonLive"`
-	ForcedLive bool `json:"forcedLive"`
-	NSFW       bool
-	RbText     bool   `json:"rbText"`
-	Pyu        bool   `json:"pyu"`
-	DefaultCSS string `json:"defaultCSS"`
-	Title      string `json:"title"`
-	Notice     string `json:"notice"`
-	Rules      string `json:"rules"`
+	ReadOnly        bool `json:"readOnly"`
+	TextOnly        bool `json:"textOnly"`
+	ForcedAnon      bool `json:"forcedAnon"`
+	Flags           bool `json:"flags"`
+	NonLive         bool `json:"nonLive"`
+	ForcedLive      bool `json:"forcedLive"`
+	NSFW            bool
+	RbText          bool   `json:"rbText"`
+	Pyu             bool   `json:"pyu"`
+	AllowEdits      uint   `json:"allowEdits"`
+	AllowSelfDelete uint   `json:"allowSelfDelete"`
+	MaxFiles        uint   `json:"maxFiles"`
+	DefaultCSS      string `json:"defaultCSS"`
+	Title           string `json:"title"`
+	Notice          string `json:"notice"`
+	Rules           string `json:"rules"`
 
 	// Can't use []uint8, because it marshals to string
 	Banners []uint16 `json:"banners"`
@@ -15,14 +15,49 @@ import (
 // Write moderation action to board-level and post-level logs
 func logModeration(tx *sql.Tx, e auth.ModLogEntry) (err error) {
 	_, err = sq.Insert("mod_log").
-		Columns("type", "board", "post_id", "by", "length", "data").
-		Values(e.Type, e.Board, e.ID, e.By, e.Length, e.Data).
+		Columns("type", "board", "post_id", "by", "length", "data", "prefix").
+		Values(e.Type, e.Board, e.ID, e.By, e.Length, e.Data, e.Prefix).
 		RunWith(tx).
 		Exec()
 	return
 }
 
-// Clear post contents and remove any uploaded image from the server
+// WriteModLogEntry writes a moderation log entry with its original creation
+// time. Entries with a non-zero post ID are also applied to the post.
+// Only used for board imports.
+func WriteModLogEntry(tx *sql.Tx, e auth.ModLogEntry) (err error) {
+	_, err = sq.Insert("mod_log").
+		Columns("type", "board", "post_id", "by", "length", "data", "prefix",
+			"created").
+		Values(e.Type, e.Board, e.ID, e.By, e.Length, e.Data, e.Prefix,
+			e.Created).
+		RunWith(tx).
+		Exec()
+	return
+}
+
+// WritePostModeration applies moderation to a post without recording it in
+// the board moderation log. Only used for board imports.
+func WritePostModeration(tx *sql.Tx, id uint64, e common.ModerationEntry,
+) (err error) {
+	_, err = sq.Insert("post_moderation").
+		Columns("post_id", "type", "by", "length", "data").
+		Values(id, e.Type, e.By, e.Length, e.Data).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return
+	}
+	_, err = sq.Update("posts").
+		Set("moderated", true).
+		Where("id = ?", id).
+		RunWith(tx).
+		Exec()
+	return
+}
+
+// Clear post contents, including its edit history, and remove any uploaded
+// image from the server
 func PurgePost(id uint64, by, reason string) (err error) {
 	post, err := GetPost(id)
 	if err != nil {
@@ -54,6 +89,14 @@ func PurgePost(id uint64, by, reason string) (err error) {
 		if err != nil {
 			return
 		}
+		_, err = sq.
+			Delete("post_revisions").
+			Where("post_id = ?", post.ID).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return
+		}
 
 		return logModeration(tx, auth.ModLogEntry{
 			Board: post.Board,
@@ -281,6 +324,26 @@ func DeletePosts(ids []uint64, by string) (err error) {
 	return
 }
 
+// SelfDeletePost deletes a post or only its image on behalf of its author.
+// Recorded in the logs separately from deletions by staff.
+func SelfDeletePost(id uint64, image bool) error {
+	if !image {
+		return moderatePost(id,
+			common.ModerationEntry{
+				Type: common.SelfDeletePost,
+			},
+			nil)
+	}
+
+	q := sq.Update("posts").
+		Set("sha1", nil)
+	return moderatePost(id,
+		common.ModerationEntry{
+			Type: common.SelfDeleteImage,
+		},
+		&q)
+}
+
 // SetThreadSticky sets the sticky field on a thread
 func SetThreadSticky(id uint64, sticky bool) error {
 	_, err := sq.Update("threads").
@@ -290,8 +353,17 @@ func SetThreadSticky(id uint64, sticky bool) error {
 	return err
 }
 
-// SetThreadLock sets the ability of users to post in a specific thread
-func SetThreadLock(id uint64, locked bool, by string) error {
+// SetThreadLock sets the ability of users to post in a specific thread.
+// Archived threads are always locked.
+func SetThreadLock(id uint64, locked bool, by string) (err error) {
+	archived, err := CheckThreadArchived(id)
+	if err != nil {
+		return
+	}
+	if archived {
+		return errThreadArchived
+	}
+
 	q := sq.Update("threads").
 		Set("locked", locked).
 		Where("id = ?", id)
@@ -311,11 +383,11 @@ func GetModLog(board string) (log []auth.ModLogEntry, err error) {
 
 	var query squirrel.SelectBuilder
 	if board == "all" || board == "" {
-		query = sq.Select("type", "board", "post_id", "by", "created", "length", "data").
+		query = sq.Select("type", "board", "post_id", "by", "created", "length", "data", "prefix").
 			From("mod_log").
 			OrderBy("created desc")
 	} else {
-		query = sq.Select("type", "board", "post_id", "by", "created", "length", "data").
+		query = sq.Select("type", "board", "post_id", "by", "created", "length", "data", "prefix").
 			From("mod_log").
 			Where("board = ?", board).
 			OrderBy("created desc")
@@ -324,7 +396,7 @@ func GetModLog(board string) (log []auth.ModLogEntry, err error) {
 		query,
 		func(r *sql.Rows) (err error) {
 			err = r.Scan(&e.Type, &e.Board, &e.ID, &e.By, &e.Created, &e.Length,
-				&e.Data)
+				&e.Data, &e.Prefix)
 			if err != nil {
 				return
 			}
@@ -339,11 +411,11 @@ func GetModLog(board string) (log []auth.ModLogEntry, err error) {
 func GetModLogEntry(id uint64) (e auth.ModLogEntry, err error) {
 	err = sq.
 		Select("type", "board", "post_id", "by", "created", "length",
-			"data").
+			"data", "prefix").
 		From("mod_log").
 		Where("id = ?", id).
 		QueryRow().
 		Scan(&e.Type, &e.Board, &e.ID, &e.By, &e.Created, &e.Length,
-			&e.Data)
+			&e.Data, &e.Prefix)
 	return
 }
@@ -66,8 +66,48 @@ func TestDeleteImages(t *testing.T) {
 	}
 }
 
+func TestSelfDeletePost(t *testing.T) {
+	prepareForModeration(t)
+
+	err := SelfDeletePost(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := GetPost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Image != nil {
+		t.Fatal("image not deleted")
+	}
+	if p.IsDeleted() {
+		t.Fatal("post deleted")
+	}
+
+	err = SelfDeletePost(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = GetPost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsDeleted() {
+		t.Fatal("post not deleted")
+	}
+	types := make(map[common.ModerationAction]bool, 2)
+	for _, e := range p.Moderation {
+		types[e.Type] = true
+	}
+	test.AssertEquals(t, types, map[common.ModerationAction]bool{
+		common.SelfDeleteImage: true,
+		common.SelfDeletePost:  true,
+	})
+}
+
 func TestSpoilerImages(t *testing.T) {
 	prepareForModeration(t)
+	insertSampleImage(t) // Additional file
 
 	p, err := GetPost(1)
 	if err != nil {
@@ -89,6 +129,10 @@ func TestSpoilerImages(t *testing.T) {
 	if !p.Image.Spoiler {
 		t.Fatal("no spoiler")
 	}
+	test.AssertEquals(t, len(p.Files), 1)
+	if !p.Files[0].Spoiler {
+		t.Fatal("no file spoiler")
+	}
 }
 
 func TestDeletePostsByIP(t *testing.T) {
@@ -284,7 +328,12 @@ func TestDeletePostsByIP(t *testing.T) {
 func TestPurgePost(t *testing.T) {
 	prepareForModeration(t)
 
-	err := PurgePost(1, "admin", "test")
+	_, err := EditPost(1, "edited", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = PurgePost(1, "admin", "test")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -294,8 +343,15 @@ func TestPurgePost(t *testing.T) {
 		t.Fatal(err)
 	}
 	test.AssertEquals(t, len(post.Moderation), 1)
+	test.AssertEquals(t, post.IsPurged(), true)
 	test.AssertEquals(t, post.Image == nil, true)
 	test.AssertEquals(t, post.Body, "")
+
+	revs, err := GetPostRevisions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertEquals(t, len(revs), 0)
 }
 
 func TestStickyThread(t *testing.T) {
@@ -9,6 +9,7 @@ import (
 	"github.com/bakape/meguca/auth"
 	"github.com/bakape/meguca/common"
 	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/metrics"
 	"github.com/go-playground/log"
 )
 
@@ -26,8 +27,17 @@ var (
 
 	// Limit of allowed incorrect captchas per hour
 	incorrectCaptchaLimit = 30
+
+	spamScoreFlushes, spamBans metrics.Counter
 )
 
+func init() {
+	metrics.Register("meguca_spam_score_flushes_total",
+		"Flushes of buffered spam scores to the database", &spamScoreFlushes)
+	metrics.Register("meguca_spam_bans_total",
+		"Bans issued by spam and bot detection", &spamBans)
+}
+
 type sessionData struct {
 	score time.Duration
 	ip    string
@@ -43,12 +53,20 @@ func syncSpamScores() (err error) {
 		return
 	}
 	err = flushSpamScores()
+	if err == nil {
+		spamScoreFlushes.Inc()
+	}
 	for session := range spamScoreBuffer {
 		delete(spamScoreBuffer, session)
 	}
 	return
 }
 
+// FlushSpamScores writes all buffered spam scores to the database
+func FlushSpamScores() error {
+	return syncSpamScores()
+}
+
 // Periodically flush buffered spam scores to DB
 func handleSpamScores() (err error) {
 	if !common.IsTest {
@@ -112,8 +130,12 @@ func flushSpamScores() (err error) {
 	})
 }
 
-func banForSpam(tx *sql.Tx, ip string) error {
-	return systemBanTx(tx, ip, "spam detected", time.Hour*48)
+func banForSpam(tx *sql.Tx, ip string) (err error) {
+	err = systemBanTx(tx, ip, "spam detected", time.Hour*48)
+	if err == nil {
+		spamBans.Inc()
+	}
+	return
 }
 
 // This surely is not done by normal human interaction
@@ -305,6 +327,7 @@ func ValidateCaptcha(
 				if err != nil {
 					return
 				}
+				spamBans.Inc()
 				banned = true
 			}
 
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+)
+
+// Shortest interval between two appeals from the same IP
+const appealCooldown = time.Hour
+
+var (
+	errNotBanned       = common.ErrInvalidInput("not banned")
+	errAppealPending   = common.ErrInvalidInput("ban already appealed")
+	errAppealTooSoon   = common.ErrInvalidInput("appealing too often")
+	errAppealNotExists = common.ErrInvalidInput("no such pending appeal")
+)
+
+// AppealBan submits an appeal against the ban currently preventing ip from
+// posting on board
+func AppealBan(board, ip, body string) (err error) {
+	ban, err := GetBanInfo(ip, board)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return errNotBanned
+	default:
+		return
+	}
+
+	return InTransaction(false, func(tx *sql.Tx) (err error) {
+		var pending, recent bool
+		err = sq.Select().
+			Column(
+				`coalesce(bool_or(board = ? and for_post = ?
+					and decided is null), false)`,
+				board, ban.ForPost).
+			Column("coalesce(bool_or(ip = ? and created > ?), false)",
+				ip, time.Now().UTC().Add(-appealCooldown)).
+			From("ban_appeals").
+			Where("(board = ? and for_post = ?) or ip = ?",
+				board, ban.ForPost, ip).
+			RunWith(tx).
+			QueryRow().
+			Scan(&pending, &recent)
+		switch {
+		case err != nil:
+			return
+		case pending:
+			return errAppealPending
+		case recent:
+			return errAppealTooSoon
+		}
+
+		_, err = sq.Insert("ban_appeals").
+			Columns("board", "for_post", "ip", "body").
+			Values(board, ban.ForPost, ip, body).
+			RunWith(tx).
+			Exec()
+		return
+	})
+}
+
+// GetAppeals reads all pending appeals against active bans on board
+func GetAppeals(board string) (appeals []auth.Appeal, err error) {
+	appeals = make([]auth.Appeal, 0, 16)
+	var a auth.Appeal
+	err = queryAll(
+		sq.Select("a.id", "a.for_post", "a.board", "a.body", "a.created",
+			"b.ip", "b.reason", "b.by", "b.expires", "b.type").
+			Options("distinct on (a.id)").
+			From("ban_appeals a").
+			Join("bans b on b.board = a.board and b.forPost = a.for_post").
+			Where(`a.board = ?
+				and a.decided is null
+				and b.expires >= now() at time zone 'utc'`,
+				board).
+			OrderBy("a.id", "b.expires desc"),
+		func(r *sql.Rows) (err error) {
+			err = r.Scan(&a.ID, &a.ForPost, &a.Board, &a.Body, &a.Created,
+				&a.IP, &a.Reason, &a.By, &a.Expires, &a.Type)
+			if err != nil {
+				return
+			}
+			appeals = append(appeals, a)
+			return
+		},
+	)
+	return
+}
+
+// DecideAppeal accepts or denies a pending appeal on board and records the
+// decision in the moderation log. Accepting an appeal lifts the ban.
+func DecideAppeal(id uint64, board string, accept bool, note, by string,
+) error {
+	return InTransaction(false, func(tx *sql.Tx) (err error) {
+		var forPost uint64
+		err = sq.Update("ban_appeals").
+			SetMap(map[string]interface{}{
+				"decided":    squirrel.Expr("now() at time zone 'utc'"),
+				"decided_by": by,
+				"accepted":   accept,
+				"note":       note,
+			}).
+			Where("id = ? and board = ? and decided is null", id, board).
+			Suffix("returning for_post").
+			RunWith(tx).
+			QueryRow().
+			Scan(&forPost)
+		switch err {
+		case nil:
+		case sql.ErrNoRows:
+			return errAppealNotExists
+		default:
+			return
+		}
+
+		typ := common.DenyAppeal
+		if accept {
+			typ = common.AcceptAppeal
+			err = unban(tx, board, forPost, by)
+			if err != nil {
+				return
+			}
+		}
+		return logModeration(tx, auth.ModLogEntry{
+			ModerationEntry: common.ModerationEntry{
+				Type: typ,
+				By:   by,
+				Data: note,
+			},
+			Board: board,
+			ID:    forPost,
+		})
+	})
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestBanAppeal(t *testing.T) {
+	prepareForModeration(t)
+	assertTableClear(t, "ban_appeals")
+
+	AssertEquals(t, AppealBan("a", "::1", "foo"), errNotBanned)
+
+	err := Ban("a", "test", "admin", time.Minute, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = AppealBan("a", "::1", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, AppealBan("a", "::1", "bar"), errAppealPending)
+
+	appeals, err := GetAppeals("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, len(appeals), 1)
+	a := appeals[0]
+	AssertEquals(t, a.ForPost, uint64(1))
+	AssertEquals(t, a.Body, "foo")
+	AssertEquals(t, a.Reason, "test")
+
+	err = DecideAppeal(a.ID, "a", true, "bar", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, DecideAppeal(a.ID, "a", false, "", "admin"),
+		errAppealNotExists)
+
+	err = RefreshBanCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = IsBanned("a", "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appeals, err = GetAppeals("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, len(appeals), 0)
+
+	log, err := GetModLog("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged bool
+	for _, e := range log {
+		if e.Type == common.AcceptAppeal {
+			logged = true
+			AssertEquals(t, e.ID, uint64(1))
+			AssertEquals(t, e.Data, "bar")
+		}
+	}
+	if !logged {
+		t.Fatal("appeal decision not logged")
+	}
+}
@@ -43,36 +43,41 @@ func updateAssets(table string,
 	load func(board string, files []assets.File),
 ) func(string) error {
 	return func(board string) (err error) {
-		files := make([]assets.File, 0, 16)
-		err = queryAll(
-			sq.Select("data", "mime").
-				From(table).
-				Where("board  = ?", board),
-			func(r *sql.Rows) (err error) {
-				var (
-					data []byte
-					mime string
-				)
-				err = r.Scan(&data, &mime)
-				if err != nil {
-					return
-				}
-				files = append(files, assets.File{
-					Data: data,
-					Mime: mime,
-				})
-				return
-			},
-		)
+		files, err := getAssets(table, board)
 		if err != nil {
 			return
 		}
-
 		load(board, files)
 		return
 	}
 }
 
+// Read all assets of a specific board from table
+func getAssets(table, board string) (files []assets.File, err error) {
+	files = make([]assets.File, 0, 16)
+	err = queryAll(
+		sq.Select("data", "mime").
+			From(table).
+			Where("board  = ?", board),
+		func(r *sql.Rows) (err error) {
+			var (
+				data []byte
+				mime string
+			)
+			err = r.Scan(&data, &mime)
+			if err != nil {
+				return
+			}
+			files = append(files, assets.File{
+				Data: data,
+				Mime: mime,
+			})
+			return
+		},
+	)
+	return
+}
+
 func loadBanners() error {
 	return loadAssets("banners", assets.Banners.Set)
 }
@@ -132,6 +137,24 @@ func SetBanners(board string, banners []assets.File) error {
 	return setAssets("banners", board, banners)
 }
 
+// GetBanners retrieves the banners of a specific board
+func GetBanners(board string) ([]assets.File, error) {
+	return getAssets("banners", board)
+}
+
+// GetLoadingAnimation retrieves the loading animation of a specific board.
+// Nil file.Data means the default animation is used.
+func GetLoadingAnimation(board string) (f assets.File, err error) {
+	files, err := getAssets("loading_animations", board)
+	if err != nil {
+		return
+	}
+	if len(files) != 0 {
+		f = files[0]
+	}
+	return
+}
+
 // SetLoadingAnimation sets the loading animation for a specific board.
 // Nil file.Data means the default animation should be used.
 func SetLoadingAnimation(board string, file assets.File) error {
@@ -2,6 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
+	"net"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,12 +14,126 @@ import (
 	"github.com/go-playground/log"
 )
 
+const (
+	// Shortest network prefixes allowed for range bans
+	minIPv4Prefix = 16
+	minIPv6Prefix = 32
+)
+
 var (
-	// board: IP: IsBanned
-	banCache = map[string]map[string]bool{}
+	// board: banned IPs and ranges
+	banCache = map[string]*banSet{}
 	bansMu   sync.RWMutex
+
+	errBanRangeTooWide = common.ErrInvalidInput("ban range too wide")
 )
 
+// Banned IPs and CIDR ranges of a board
+type banSet struct {
+	// Exact IPs and CIDR ranges in canonical notation
+	ips map[string]bool
+
+	// Distinct network prefix lengths of banned ranges. Matching an IP
+	// against ranges only requires one lookup per prefix length.
+	v4Prefixes, v6Prefixes []int
+}
+
+func newBanSet() *banSet {
+	return &banSet{
+		ips: make(map[string]bool),
+	}
+}
+
+// Add an exact IP or CIDR range to the set
+func (s *banSet) add(ip string) {
+	if !strings.ContainsRune(ip, '/') {
+		s.ips[ip] = true
+		return
+	}
+
+	_, n, err := net.ParseCIDR(ip)
+	if err != nil {
+		log.Errorf("invalid banned range: %s", ip)
+		return
+	}
+	s.ips[n.String()] = true
+
+	ones, bits := n.Mask.Size()
+	prefixes := &s.v6Prefixes
+	if bits == 32 {
+		prefixes = &s.v4Prefixes
+	}
+	for _, p := range *prefixes {
+		if p == ones {
+			return
+		}
+	}
+	*prefixes = append(*prefixes, ones)
+}
+
+// Returns, if ip is banned directly or contained in any banned range
+func (s *banSet) contains(ip string) bool {
+	if s == nil {
+		return false
+	}
+	if s.ips[ip] {
+		return true
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	prefixes, bits := s.v6Prefixes, 128
+	if v4 := parsed.To4(); v4 != nil {
+		parsed = v4
+		prefixes, bits = s.v4Prefixes, 32
+	}
+	for _, p := range prefixes {
+		if s.ips[cidrRange(parsed, p, bits)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Format the CIDR range of prefix length containing ip in canonical notation
+func cidrRange(ip net.IP, prefix, bits int) string {
+	mask := net.CIDRMask(prefix, bits)
+	n := net.IPNet{
+		IP:   ip.Mask(mask),
+		Mask: mask,
+	}
+	return n.String()
+}
+
+// Returns the CIDR range to ban for ip and its network prefix length.
+// Prefixes of zero or the full address length ban only the exact IP, in which
+// case ip itself and a zero prefix are returned.
+func banRange(ip string, ipv4Prefix, ipv6Prefix int) (
+	target string, prefix int, err error,
+) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		err = fmt.Errorf("invalid IP: %s", ip)
+		return
+	}
+	p, min, bits := ipv6Prefix, minIPv6Prefix, 128
+	if v4 := parsed.To4(); v4 != nil {
+		parsed = v4
+		p, min, bits = ipv4Prefix, minIPv4Prefix, 32
+	}
+
+	switch {
+	case p == 0 || p >= bits:
+		return ip, 0, nil
+	case p < min:
+		err = errBanRangeTooWide
+		return
+	}
+	return cidrRange(parsed, p, bits), p, nil
+}
+
 func writeBan(tx *sql.Tx, ip string, entry auth.ModLogEntry) (err error) {
 	_, err = sq.Insert("bans").
 		Columns("ip", "board", "forPost", "reason", "by", "expires").
@@ -31,7 +148,8 @@ func writeBan(tx *sql.Tx, ip string, entry auth.ModLogEntry) (err error) {
 	return logModeration(tx, entry)
 }
 
-// Propagate ban updates through DB and disconnect all banned IPs
+// Propagate ban updates through DB and disconnect all banned IPs. ip can also
+// be a CIDR range.
 func propagateBans(board string, ip string) (err error) {
 	_, err = db.Exec(`notify bans_updated`)
 	if err != nil {
@@ -73,6 +191,16 @@ func systemBanTx(tx *sql.Tx, ip, reason string, length time.Duration,
 // Ban IPs from accessing a specific board. Need to target posts. Returns all
 // banned IPs.
 func Ban(board, reason, by string, length time.Duration, id uint64,
+) (err error) {
+	return BanRange(board, reason, by, length, id, 0, 0)
+}
+
+// BanRange bans the CIDR range containing the IP of the targeted post from
+// accessing a specific board. The network prefix length of the range is
+// chosen by the IP version of the post. Prefixes of zero or the full address
+// length ban only the exact IP.
+func BanRange(board, reason, by string, length time.Duration, id uint64,
+	ipv4Prefix, ipv6Prefix int,
 ) (err error) {
 	ip, err := GetIP(id)
 	switch err {
@@ -85,51 +213,60 @@ func Ban(board, reason, by string, length time.Duration, id uint64,
 	default:
 		return
 	}
+	target, prefix, err := banRange(ip, ipv4Prefix, ipv6Prefix)
+	if err != nil {
+		return
+	}
 
 	// Write ban messages to posts and ban table
 	err = InTransaction(false, func(tx *sql.Tx) (err error) {
-		return writeBan(tx, ip, auth.ModLogEntry{
+		return writeBan(tx, target, auth.ModLogEntry{
 			ModerationEntry: common.ModerationEntry{
 				Type:   common.BanPost,
 				Length: uint64(length / time.Second),
 				By:     by,
 				Data:   reason,
 			},
-			Board: board,
-			ID:    id,
+			Board:  board,
+			ID:     id,
+			Prefix: uint8(prefix),
 		})
 	})
 	if err != nil {
 		return
 	}
 
-	return propagateBans(board, ip)
+	return propagateBans(board, target)
 }
 
 // Unban lifts a ban from a specific post on a specific board
 func Unban(board string, id uint64, by string) error {
-	return InTransaction(false, func(tx *sql.Tx) (err error) {
-		_, err = sq.Delete("bans").
-			Where("board = ? and forPost = ?", board, id).
-			RunWith(tx).
-			Exec()
-		if err != nil {
-			return
-		}
-		err = logModeration(tx, auth.ModLogEntry{
-			ModerationEntry: common.ModerationEntry{
-				Type: common.UnbanPost,
-				By:   by,
-			},
-			Board: board,
-			ID:    id,
-		})
-		if err != nil {
-			return
-		}
-		_, err = tx.Exec("notify bans_updated")
+	return InTransaction(false, func(tx *sql.Tx) error {
+		return unban(tx, board, id, by)
+	})
+}
+
+func unban(tx *sql.Tx, board string, id uint64, by string) (err error) {
+	_, err = sq.Delete("bans").
+		Where("board = ? and forPost = ?", board, id).
+		RunWith(tx).
+		Exec()
+	if err != nil {
 		return
+	}
+	err = logModeration(tx, auth.ModLogEntry{
+		ModerationEntry: common.ModerationEntry{
+			Type: common.UnbanPost,
+			By:   by,
+		},
+		Board: board,
+		ID:    id,
 	})
+	if err != nil {
+		return
+	}
+	_, err = tx.Exec("notify bans_updated")
+	return
 }
 
 func loadBans() error {
@@ -166,14 +303,14 @@ func RefreshBanCache() (err error) {
 		return
 	}
 
-	new := map[string]map[string]bool{}
+	new := map[string]*banSet{}
 	for _, b := range bans {
 		board, ok := new[b.Board]
 		if !ok {
-			board = map[string]bool{}
+			board = newBanSet()
 			new[b.Board] = board
 		}
-		board[b.IP] = true
+		board.add(b.IP)
 	}
 
 	bansMu.Lock()
@@ -183,17 +320,16 @@ func RefreshBanCache() (err error) {
 	return
 }
 
-// IsBanned checks,  if the IP is banned on the target board or globally
+// IsBanned checks,  if the IP is banned on the target board or globally either
+// directly or as part of a banned range
 func IsBanned(board, ip string) error {
 	bansMu.RLock()
 	defer bansMu.RUnlock()
-	global := banCache["all"]
-	ips := banCache[board]
 
-	if (global != nil && global[ip]) || (ips != nil && ips[ip]) {
+	if banCache["all"].contains(ip) || banCache[board].contains(ip) {
 		// Need to assert ban has not expired and cache is invalid
 
-		r, err := selectBans("board").Where("ip = ?", ip).Query()
+		r, err := selectBans("board").Where("ip >>= ?", ip).Query()
 		if err != nil {
 			return err
 		}
@@ -238,16 +374,19 @@ func IsBanned(board, ip string) error {
 	return nil
 }
 
-// GetBanInfo retrieves information about a specific ban
+// GetBanInfo retrieves information about the longest lasting ban of an IP or
+// any range containing it
 func GetBanInfo(ip, board string) (b auth.BanRecord, err error) {
 	err = sq.Select("ip", "board", "forPost", "reason", "by", "expires").
 		From("bans").
 		Where(
 			`expires >= now() at time zone 'utc'
-					and ip = ?
+					and ip >>= ?
 					and board = ?
 					and type = 'classic'`,
 			ip, board).
+		OrderBy("expires desc").
+		Limit(1).
 		QueryRow().
 		Scan(&b.IP, &b.Board, &b.ForPost, &b.Reason, &b.By, &b.Expires)
 	return
@@ -31,3 +31,66 @@ func TestBanUnban(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestBanRange(t *testing.T) {
+	t.Parallel()
+
+	cases := [...]struct {
+		name, ip   string
+		ipv4, ipv6 int
+		target     string
+		prefix     int
+		err        error
+	}{
+		{"exact IPv4", "192.168.1.7", 0, 64, "192.168.1.7", 0, nil},
+		{"full IPv4 prefix", "192.168.1.7", 32, 0, "192.168.1.7", 0, nil},
+		{"IPv4 range", "192.168.1.7", 24, 0, "192.168.1.0/24", 24, nil},
+		{"exact IPv6", "2001:db8::1", 24, 0, "2001:db8::1", 0, nil},
+		{
+			"IPv6 range",
+			"2001:db8:1:2:3::1", 0, 64,
+			"2001:db8:1:2::/64", 64, nil,
+		},
+		{"too wide", "192.168.1.7", 8, 0, "", 0, errBanRangeTooWide},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			target, prefix, err := banRange(c.ip, c.ipv4, c.ipv6)
+			if err != c.err {
+				UnexpectedError(t, err)
+			}
+			AssertEquals(t, target, c.target)
+			AssertEquals(t, prefix, c.prefix)
+		})
+	}
+}
+
+func TestBanSet(t *testing.T) {
+	t.Parallel()
+
+	s := newBanSet()
+	for _, ip := range [...]string{
+		"10.0.0.1", "192.168.1.0/24", "172.16.0.0/16", "2001:db8:1:2::/64",
+	} {
+		s.add(ip)
+	}
+
+	for ip, banned := range map[string]bool{
+		"10.0.0.1":          true,
+		"10.0.0.2":          false,
+		"192.168.1.200":     true,
+		"192.168.2.1":       false,
+		"172.16.254.1":      true,
+		"2001:db8:1:2:ff::": true,
+		"2001:db8:1:3::1":   false,
+	} {
+		AssertEquals(t, s.contains(ip), banned)
+	}
+
+	var empty *banSet
+	AssertEquals(t, empty.contains("10.0.0.1"), false)
+}
@@ -6,10 +6,21 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/bakape/meguca/auth"
 	"github.com/bakape/meguca/common"
 	"github.com/lib/pq"
 )
 
+var (
+	// ErrInvalidPollOption is returned on voting for an option of a #poll,
+	// that does not exist
+	ErrInvalidPollOption = common.ErrInvalidInput("invalid poll option")
+
+	// ErrPollVoted is returned on changing the options of a #poll, that has
+	// already been voted in
+	ErrPollVoted = common.ErrInvalidInput("poll already has votes")
+)
+
 // For encoding and decoding hash command results
 type commandRow []common.Command
 
@@ -241,3 +252,112 @@ func IncrementRcount(tx *sql.Tx, t uint64) (err error) {
 		Exec()
 	return
 }
+
+// VotePoll casts a vote for an option of the #poll in a post and returns the
+// updated vote tallies of the poll. Each captcha session and IP can only vote
+// once per poll. Returns nil tallies, if they already have.
+func VotePoll(id uint64, option int, session auth.Base64Token, ip string,
+) (votes []uint32, err error) {
+	err = InTransaction(false, func(tx *sql.Tx) (err error) {
+		var com commandRow
+		err = sq.Select("commands").
+			From("posts").
+			Where("id = ?", id).
+			Suffix("for update").
+			RunWith(tx).
+			QueryRow().
+			Scan(&com)
+		if err != nil {
+			return
+		}
+
+		poll := pollIndex(com)
+		if poll == -1 ||
+			option < 0 ||
+			option >= len(com[poll].Poll.Votes) {
+			return ErrInvalidPollOption
+		}
+
+		res, err := sq.Insert("poll_votes").
+			Columns("post_id", "option", "session", "ip").
+			Values(id, option, session[:], ip).
+			Suffix("on conflict do nothing").
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return
+		}
+		n, err := res.RowsAffected()
+		if err != nil || n == 0 {
+			return
+		}
+
+		com[poll].Poll.Votes[option]++
+		_, err = sq.Update("posts").
+			Set("commands", com).
+			Where("id = ?", id).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return
+		}
+		votes = com[poll].Poll.Votes
+		return
+	})
+	return
+}
+
+// Returns the index of the #poll in a post's commands or -1, if none
+func pollIndex(com []common.Command) int {
+	for i := range com {
+		if com[i].Type == common.Poll {
+			return i
+		}
+	}
+	return -1
+}
+
+// Carry over the vote tallies of a post's #poll to its edited commands. The
+// options of a poll can not be changed, once it has been voted in, as the
+// votes would no longer match them.
+func keepPollVotes(tx *sql.Tx, id uint64, com []common.Command) (err error) {
+	var old commandRow
+	err = sq.Select("commands").
+		From("posts").
+		Where("id = ?", id).
+		Suffix("for update").
+		RunWith(tx).
+		QueryRow().
+		Scan(&old)
+	if err != nil {
+		return
+	}
+	var voted bool
+	err = tx.QueryRow(
+		`select exists (select 1 from poll_votes where post_id = $1)`,
+		id,
+	).Scan(&voted)
+	if err != nil || !voted {
+		return
+	}
+
+	i, j := pollIndex(old), pollIndex(com)
+	if i == -1 || j == -1 ||
+		!equalStrings(old[i].Poll.Options, com[j].Poll.Options) {
+		return ErrPollVoted
+	}
+	com[j].Poll.Votes = old[i].Poll.Votes
+	return
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
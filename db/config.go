@@ -51,8 +51,8 @@ func GetConfigs() (c config.Configs, err error) {
 func getBoardConfigs() squirrel.SelectBuilder {
 	return sq.Select(
 		"readOnly", "textOnly", "forcedAnon", "disableRobots", "flags", "NSFW",
-		/*"nonLive",*/ "forcedLive", "rbText", "pyu", "id", "defaultCSS", "title", "notice",
-		"rules", "eightball",
+		/*"nonLive",*/ "forcedLive", "rbText", "pyu", "archive", "allowEdits", "allowSelfDelete", "maxFiles", "id", "defaultCSS", "title", "notice",
+		"rules", "eightball", "wordFilters",
 	).
 		From("boards")
 }
@@ -74,13 +74,24 @@ func loadBoardConfigs() (err error) {
 }
 
 func scanBoardConfigs(r rowScanner) (c config.BoardConfigs, err error) {
-	var eightball pq.StringArray
+	var (
+		eightball pq.StringArray
+		filters   []byte
+	)
 	err = r.Scan(
 		&c.ReadOnly, &c.TextOnly, &c.ForcedAnon, &c.DisableRobots, &c.Flags,
-		&c.NSFW, /*&c.NonLive,*/ &c.ForcedLive, &c.RbText, &c.Pyu,
-		&c.ID, &c.DefaultCSS, &c.Title, &c.Notice, &c.Rules, &eightball,
+		&c.NSFW, /*&c.NonLive,*/ &c.ForcedLive, &c.RbText, &c.Pyu, &c.Archive,
+		&c.AllowEdits, &c.AllowSelfDelete, &c.MaxFiles, &c.ID, &c.DefaultCSS,
+		&c.Title, &c.Notice, &c.Rules, &eightball, &filters,
 	)
+	if err != nil {
+		return
+	}
 	c.Eightball = []string(eightball)
+	err = json.Unmarshal(filters, &c.WordFilters)
+	if len(c.WordFilters) == 0 {
+		c.WordFilters = nil
+	}
 	return
 }
 
@@ -90,20 +101,32 @@ func WriteBoard(tx *sql.Tx, c BoardConfigs) error {
 		Columns(
 			"id", "readOnly", "textOnly", "forcedAnon", "disableRobots",
 			"flags", "NSFW", /*"nonLive",*/ "forcedLive",
-			"rbText", "pyu", "created", "defaultCSS", "title",
-			"notice", "rules", "eightball",
+			"rbText", "pyu", "archive", "allowEdits", "allowSelfDelete",
+			"maxFiles", "created", "defaultCSS", "title", "notice", "rules",
+			"eightball", "wordFilters",
 		).
 		Values(
 			c.ID, c.ReadOnly, c.TextOnly, c.ForcedAnon, c.DisableRobots,
 			c.Flags, c.NSFW, /*c.NonLive,*/ c.ForcedLive, c.RbText, c.Pyu,
-			c.Created, c.DefaultCSS, c.Title, c.Notice, c.Rules,
+			c.Archive, c.AllowEdits, c.AllowSelfDelete, c.MaxFiles, c.Created,
+			c.DefaultCSS, c.Title, c.Notice, c.Rules,
 			pq.StringArray(c.Eightball),
+			wordFiltersValue(c.WordFilters),
 		).
 		RunWith(tx).
 		Exec()
 	return err
 }
 
+// Encode word filters for storage in a JSONB column
+func wordFiltersValue(f []config.WordFilter) string {
+	if len(f) == 0 {
+		return "[]"
+	}
+	buf, _ := json.Marshal(f) // Can not fail
+	return string(buf)
+}
+
 // UpdateBoard updates board configurations
 func UpdateBoard(c config.BoardConfigs) (err error) {
 	_, err = sq.Update("boards").
@@ -115,14 +138,19 @@ func UpdateBoard(c config.BoardConfigs) (err error) {
 			"flags":         c.Flags,
 			"NSFW":          c.NSFW,
 			//"nonLive":       c.NonLive,
-			"forcedLive":    c.ForcedLive,
-			"rbText":        c.RbText,
-			"pyu":           c.Pyu,
-			"defaultCSS":    c.DefaultCSS,
-			"title":         c.Title,
-			"notice":        c.Notice,
-			"rules":         c.Rules,
-			"eightball":     pq.StringArray(c.Eightball),
+			"forcedLive":      c.ForcedLive,
+			"rbText":          c.RbText,
+			"pyu":             c.Pyu,
+			"archive":         c.Archive,
+			"allowEdits":      c.AllowEdits,
+			"allowSelfDelete": c.AllowSelfDelete,
+			"maxFiles":        c.MaxFiles,
+			"defaultCSS":      c.DefaultCSS,
+			"title":           c.Title,
+			"notice":          c.Notice,
+			"rules":           c.Rules,
+			"eightball":       pq.StringArray(c.Eightball),
+			"wordFilters":     wordFiltersValue(c.WordFilters),
 		}).
 		Where("id = ?", c.ID).
 		Exec()
@@ -171,6 +199,16 @@ func GetBoardConfigs(board string) (config.BoardConfigs, error) {
 	return scanBoardConfigs(q.QueryRow())
 }
 
+// GetBoardCreationTime retrieves the time a board was created at
+func GetBoardCreationTime(board string) (t time.Time, err error) {
+	err = sq.Select("created").
+		From("boards").
+		Where("id = ?", board).
+		QueryRow().
+		Scan(&t)
+	return
+}
+
 // WriteConfigs writes new global configurations to the database
 func WriteConfigs(c config.Configs) (err error) {
 	data, err := json.Marshal(c)
@@ -137,7 +137,8 @@ func HasImage(id uint64) (has bool, err error) {
 }
 
 // InsertImage insert and image into and existing open post and return image
-// JSON
+// JSON. If the post already has an image, the image is appended to the post's
+// additional files.
 func InsertImage(tx *sql.Tx, postID uint64, token, name string, spoiler bool,
 ) (
 	json []byte, err error,
@@ -216,12 +217,13 @@ func VideoPlaylist(board string) (videos []Video, err error) {
 	return
 }
 
-// Delete images not used in any posts
-func deleteUnusedImages() (err error) {
+// DeleteUnusedImages deletes images not used in any posts
+func DeleteUnusedImages() (err error) {
 	r, err := db.Query(`
 		delete from images
 		where (
 			(select count(*) from posts where SHA1 = images.SHA1)
+			+ (select count(*) from post_files where SHA1 = images.SHA1)
 			+ (select count(*) from image_tokens where SHA1 = images.SHA1)
 		) = 0
 		returning SHA1, file_type, thumb_type`)
@@ -247,3 +249,24 @@ func deleteUnusedImages() (err error) {
 
 	return r.Err()
 }
+
+// Write the additional files of a post to the database in order
+func writeFiles(tx *sql.Tx, id uint64, files []common.Image) (err error) {
+	if len(files) == 0 {
+		return
+	}
+
+	q, err := tx.Prepare(
+		`insert into post_files (post_id, position, sha1, name, spoiler)
+		values($1, $2, $3, $4, $5)`)
+	if err != nil {
+		return
+	}
+	for i, f := range files {
+		_, err = q.Exec(id, i+1, f.SHA1, f.Name, f.Spoiler)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -129,7 +129,7 @@ func TestAllocateImage(t *testing.T) {
 		wg.Wait()
 		defer cleanUp()
 
-		err := deleteUnusedImages()
+		err := DeleteUnusedImages()
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -247,6 +247,37 @@ func TestSpoilerImage(t *testing.T) {
 	test.AssertEquals(t, post.Image.Spoiler, true)
 }
 
+func TestInsertAdditionalFiles(t *testing.T) {
+	assertTableClear(t, "images", "boards")
+	writeSampleImage(t)
+	writeSampleBoard(t)
+	writeSampleThread(t)
+	insertSampleImage(t)
+	insertSampleImage(t)
+
+	post, err := GetPost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Image == nil {
+		t.Fatal("no image")
+	}
+	test.AssertEquals(t, len(post.Files), 1)
+	test.AssertEquals(t, post.Files[0].SHA1, assets.StdJPEG.SHA1)
+	test.AssertEquals(t, post.Files[0].Name, assets.StdJPEG.Name)
+
+	// Deleting the main image also deletes the additional files
+	err = SelfDeletePost(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err = GetPost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertEquals(t, len(post.Files), 0)
+}
+
 func TestVideoPlaylist(t *testing.T) {
 	std := assets.StdJPEG
 	std.FileType = common.WEBM
@@ -12,6 +12,7 @@ import (
 	"github.com/bakape/meguca/auth"
 	"github.com/bakape/meguca/common"
 	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/metrics"
 	"github.com/bakape/meguca/util"
 	"github.com/boltdb/bolt"
 	"github.com/go-playground/log"
@@ -32,6 +33,43 @@ var (
 	boltDB *bolt.DB
 )
 
+func init() {
+	// Connection pool statistics
+	stat := func(fn func(sql.DBStats) float64) func() float64 {
+		return func() float64 {
+			if db == nil {
+				return 0
+			}
+			return fn(db.Stats())
+		}
+	}
+	metrics.Register("meguca_db_open_connections",
+		"Established database connections, both in use and idle",
+		metrics.GaugeFunc(stat(func(s sql.DBStats) float64 {
+			return float64(s.OpenConnections)
+		})))
+	metrics.Register("meguca_db_in_use_connections",
+		"Database connections currently in use",
+		metrics.GaugeFunc(stat(func(s sql.DBStats) float64 {
+			return float64(s.InUse)
+		})))
+	metrics.Register("meguca_db_idle_connections",
+		"Idle database connections",
+		metrics.GaugeFunc(stat(func(s sql.DBStats) float64 {
+			return float64(s.Idle)
+		})))
+	metrics.Register("meguca_db_wait_count_total",
+		"Times a database connection had to be waited for",
+		metrics.CounterFunc(stat(func(s sql.DBStats) float64 {
+			return float64(s.WaitCount)
+		})))
+	metrics.Register("meguca_db_wait_duration_seconds_total",
+		"Total time spent waiting for database connections",
+		metrics.CounterFunc(stat(func(s sql.DBStats) float64 {
+			return s.WaitDuration.Seconds()
+		})))
+}
+
 // Connects to PostgreSQL database and performs schema upgrades
 func LoadDB() error {
 	return loadDB(config.Server.Database, "")
@@ -99,7 +137,43 @@ func LoadTestDB(suffix string) (close func() error, err error) {
 	return
 }
 
-func loadDB(connURL, dbSuffix string) (err error) {
+// Connect opens a connection to the PostgreSQL database without performing
+// schema upgrades or loading any state. Used for offline maintenance.
+func Connect() error {
+	return connect(config.Server.Database)
+}
+
+// Migrate initializes the database or upgrades its schema to the latest
+// version. Requires a connection opened with Connect.
+func Migrate() (err error) {
+	exists, err := schemaExists()
+	if err != nil {
+		return
+	}
+	if !exists {
+		return initDB()
+	}
+	return runMigrations()
+}
+
+// NeedsMigration returns, if the database schema is missing or outdated.
+// Requires a connection opened with Connect.
+func NeedsMigration() (need bool, err error) {
+	exists, err := schemaExists()
+	if err != nil || !exists {
+		return true, err
+	}
+	var v int
+	err = sq.Select("val").
+		From("main").
+		Where("id = 'version'").
+		QueryRow().
+		Scan(&v)
+	need = v < version
+	return
+}
+
+func connect(connURL string) (err error) {
 	// Enable binary parameters for more efficient encoding of []byte
 	u, err := url.Parse(connURL)
 	if err != nil {
@@ -119,13 +193,25 @@ func loadDB(connURL, dbSuffix string) (err error) {
 	sq = squirrel.StatementBuilder.
 		RunWith(squirrel.NewStmtCacheProxy(db)).
 		PlaceholderFormat(squirrel.Dollar)
+	return
+}
 
-	var exists bool
+// Returns, if the database has already been initialized
+func schemaExists() (exists bool, err error) {
 	const q = `select exists (
 			select 1 from information_schema.tables
 				where table_schema = 'public' and table_name = 'main'
 		)`
 	err = db.QueryRow(q).Scan(&exists)
+	return
+}
+
+func loadDB(connURL, dbSuffix string) (err error) {
+	err = connect(connURL)
+	if err != nil {
+		return
+	}
+	exists, err := schemaExists()
 	if err != nil {
 		return
 	}
@@ -96,3 +96,34 @@ func writeLinks(tx *sql.Tx, source uint64, links []common.Link) (err error) {
 	}
 	return
 }
+
+// Read links to posts of a thread from posts on other boards
+func getCrossBoardBacklinks(tx *sql.Tx, thread uint64, board string) (
+	bls []common.Backlink, err error,
+) {
+	r, err := tx.Query(
+		`select l.source, source_post.op, source_thread.board, l.target
+		from links as l
+		join posts as target_post on l.target = target_post.id
+		join posts as source_post on l.source = source_post.id
+		join threads as source_thread on source_post.op = source_thread.id
+		where target_post.op = $1 and source_thread.board != $2
+		order by l.source`,
+		thread, board,
+	)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	var bl common.Backlink
+	for r.Next() {
+		err = r.Scan(&bl.ID, &bl.OP, &bl.Board, &bl.Target)
+		if err != nil {
+			return
+		}
+		bls = append(bls, bl)
+	}
+	err = r.Err()
+	return
+}
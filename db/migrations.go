@@ -1530,6 +1530,195 @@ var migrations = []func(*sql.Tx) error{
 			"bans":{{after, tableInsert}},
 		})
 	},
+	func(tx *sql.Tx) (err error) {
+		err = execAll(tx,
+			`alter table posts
+				add column body_tsv tsvector`,
+			`update posts
+				set body_tsv = to_tsvector('simple', body)
+				where editing = false`,
+			`create index posts_body_tsv_idx on posts using gin (body_tsv)`,
+		)
+		if err != nil {
+			return
+		}
+		return registerTriggers(tx, map[string][]triggerDescriptor{
+			"posts": {{before, tableUpdate}},
+		})
+	},
+	func(tx *sql.Tx) (err error) {
+		return execAll(tx,
+			`alter table boards
+				add column archive bool not null default false`,
+			`alter table threads
+				add column archived bool not null default false`,
+			`alter table threads
+				add column archive_time bigint`,
+			createIndex("threads", "archived"),
+		)
+	},
+	func(tx *sql.Tx) (err error) {
+		// Bans can now also target CIDR ranges
+		err = execAll(tx,
+			`create index bans_ip_gist_idx on bans using gist (ip inet_ops)`,
+			`alter table mod_log
+				add column prefix smallint not null default 0`,
+		)
+		if err != nil {
+			return
+		}
+		return loadSQL(tx, "triggers/posts", "triggers/bans")
+	},
+	func(tx *sql.Tx) (err error) {
+		err = execAll(tx,
+			`create table ban_appeals (
+				id bigserial primary key,
+				board text not null,
+				for_post bigint not null,
+				ip inet not null,
+				body text not null,
+				created timestamp not null
+					default (now() at time zone 'utc'),
+				decided timestamp,
+				decided_by text,
+				accepted bool,
+				note text
+			)`,
+			createIndex("ban_appeals", "board", "for_post"),
+			createIndex("ban_appeals", "ip"),
+			createIndex("ban_appeals", "created"),
+		)
+		if err != nil {
+			return
+		}
+		return loadSQL(tx, "triggers/mod_log")
+	},
+	func(tx *sql.Tx) (err error) {
+		return loadSQL(tx, "triggers/threads", "triggers/posts")
+	},
+	func(tx *sql.Tx) (err error) {
+		return execAll(tx,
+			`create table pubsub_messages (
+				id bigserial primary key,
+				data text not null,
+				expires timestamp not null
+					default (now() at time zone 'utc' + interval '1 minute')
+			)`,
+			createIndex("pubsub_messages", "expires"),
+		)
+	},
+	func(tx *sql.Tx) (err error) {
+		return patchConfigsLegacy(tx, func(conf *config.Configs) {
+			d := config.Defaults
+			conf.AppendRate, conf.AppendBurst = d.AppendRate, d.AppendBurst
+			conf.SpliceRate, conf.SpliceBurst = d.SpliceRate, d.SpliceBurst
+			conf.InsertPostRate = d.InsertPostRate
+			conf.InsertPostBurst = d.InsertPostBurst
+			conf.InsertImageRate = d.InsertImageRate
+			conf.InsertImageBurst = d.InsertImageBurst
+			conf.ReclaimRate, conf.ReclaimBurst = d.ReclaimRate, d.ReclaimBurst
+		})
+	},
+	func(tx *sql.Tx) (err error) {
+		return registerTriggers(tx, map[string][]triggerDescriptor{
+			"links": {{after, tableInsert}},
+		})
+	},
+	func(tx *sql.Tx) (err error) {
+		err = execAll(tx,
+			`alter table boards
+				add column allowEdits bigint not null default 0`,
+			`alter table posts
+				add column edited bigint`,
+			`create table post_revisions (
+				id bigserial primary key,
+				post_id bigint not null references posts on delete cascade,
+				time bigint not null,
+				body text not null
+			)`,
+			createIndex("post_revisions", "post_id"),
+		)
+		if err != nil {
+			return
+		}
+		return registerTriggers(tx, map[string][]triggerDescriptor{
+			"post_revisions": {{after, tableInsert}},
+		})
+	},
+	func(tx *sql.Tx) (err error) {
+		err = execAll(tx,
+			`create table poll_votes (
+				post_id bigint not null references posts on delete cascade,
+				option smallint not null,
+				session bytea not null,
+				ip inet not null,
+				primary key (post_id, session),
+				unique (post_id, ip)
+			)`,
+		)
+		if err != nil {
+			return
+		}
+		return registerTriggers(tx, map[string][]triggerDescriptor{
+			"poll_votes": {{after, tableInsert}},
+		})
+	},
+	func(tx *sql.Tx) (err error) {
+		_, err = tx.Exec(
+			`alter table boards
+				add column wordFilters jsonb not null default '[]'`,
+		)
+		return
+	},
+	func(tx *sql.Tx) (err error) {
+		_, err = tx.Exec(
+			`alter table boards
+				add column allowSelfDelete bigint not null default 0`,
+		)
+		if err != nil {
+			return
+		}
+		err = registerFunctions(tx, "is_deleted")
+		if err != nil {
+			return
+		}
+		return loadSQL(tx, "triggers/mod_log")
+	},
+	func(tx *sql.Tx) (err error) {
+		err = execAll(tx,
+			`alter table boards
+				add column maxFiles bigint not null default 1`,
+			`create table post_files (
+				post_id bigint not null references posts on delete cascade,
+				position smallint not null,
+				sha1 char(40) not null references images on delete cascade,
+				name varchar(200) not null,
+				spoiler bool not null default false,
+				primary key (post_id, position)
+			)`,
+			createIndex("post_files", "sha1"),
+		)
+		if err != nil {
+			return
+		}
+		err = registerFunctions(tx, "insert_image")
+		if err != nil {
+			return
+		}
+		err = loadSQL(tx, "triggers/posts")
+		if err != nil {
+			return
+		}
+		return registerTriggers(tx, map[string][]triggerDescriptor{
+			"post_files": {{after, tableInsert}},
+		})
+	},
+	func(tx *sql.Tx) (err error) {
+		return registerFunctions(tx, "spoiler_images")
+	},
+	func(tx *sql.Tx) (err error) {
+		return loadSQL(tx, "triggers/threads")
+	},
 }
 /* function stop */
 
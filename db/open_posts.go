@@ -28,8 +28,12 @@ var (
 
 // Close DB and release resources
 func Close() (err error) {
-	atomic.StoreUint32(&boltDBState, boltDBClosed)
-	return boltDB.Close()
+	// Prevent opening after closing, if never opened
+	boltDBOnce.Do(func() {})
+	if atomic.SwapUint32(&boltDBState, boltDBClosed) == boltDBOpen {
+		err = boltDB.Close()
+	}
+	return
 }
 
 // Need to drop any incoming requests, when Db is closed during graceful restart
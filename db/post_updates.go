@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/bakape/meguca/common"
 )
@@ -16,7 +17,6 @@ func ClosePost(id, op uint64, body string, links []common.Link,
 				"editing":  false,
 				"body":     body,
 				"commands": commandRow(com),
-				"password": nil,
 			}).
 			Where("id = ?", id).
 			RunWith(tx).
@@ -41,3 +41,72 @@ func ClosePost(id, op uint64, body string, links []common.Link,
 
 	return deleteOpenPostBody(id)
 }
+
+// EditPost replaces the body of a closed post and commits the links and hash
+// commands parsed from it. The vote tallies of a #poll in com are replaced
+// with the current ones. The previous body is stored as a revision. Returns
+// the time of the edit.
+func EditPost(id uint64, body string, links []common.Link,
+	com []common.Command,
+) (edited int64, err error) {
+	edited = time.Now().Unix()
+	err = InTransaction(false, func(tx *sql.Tx) (err error) {
+		err = keepPollVotes(tx, id, com)
+		if err != nil {
+			return
+		}
+		_, err = tx.Exec(
+			`insert into post_revisions (post_id, time, body)
+			select id, $2, body
+			from posts
+			where id = $1`,
+			id, edited,
+		)
+		if err != nil {
+			return
+		}
+		_, err = sq.Update("posts").
+			SetMap(map[string]interface{}{
+				"body":     body,
+				"commands": commandRow(com),
+				"edited":   edited,
+			}).
+			Where("id = ?", id).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return
+		}
+		_, err = sq.Delete("links").
+			Where("source = ?", id).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return
+		}
+		return writeLinks(tx, id, links)
+	})
+	return
+}
+
+// GetPostRevisions retrieves the previous bodies of an edited post from
+// oldest to newest
+func GetPostRevisions(id uint64) (revs []common.PostRevision, err error) {
+	revs = make([]common.PostRevision, 0, 4)
+	err = queryAll(
+		sq.Select("time", "body").
+			From("post_revisions").
+			Where("post_id = ?", id).
+			OrderBy("id"),
+		func(r *sql.Rows) (err error) {
+			var rev common.PostRevision
+			err = r.Scan(&rev.Time, &rev.Body)
+			if err != nil {
+				return
+			}
+			revs = append(revs, rev)
+			return
+		},
+	)
+	return
+}
@@ -4,7 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bakape/meguca/auth"
 	"github.com/bakape/meguca/common"
+	. "github.com/bakape/meguca/test"
 )
 
 // Only select post updates should bump threads
@@ -99,3 +101,121 @@ func TestNoBumpOnPostUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestEditPost(t *testing.T) {
+	p := Post{
+		StandalonePost: common.StandalonePost{
+			OP:    1,
+			Board: "a",
+			Post: common.Post{
+				Body: "foo",
+			},
+		},
+		IP: "::1",
+	}
+	insertPost(t, &p)
+
+	links := []common.Link{{ID: 1, OP: 1, Board: "a"}}
+	com := []common.Command{{Type: common.Flip, Flip: true}}
+	edited, err := EditPost(p.ID, ">>1 #flip", links, com)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post, err := GetPost(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, post.Body, ">>1 #flip")
+	AssertEquals(t, post.Edited, edited)
+	AssertEquals(t, post.Links, links)
+	AssertEquals(t, post.Commands, com)
+
+	revs, err := GetPostRevisions(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, revs, []common.PostRevision{
+		{
+			Time: edited,
+			Body: "foo",
+		},
+	})
+}
+
+func TestVotePoll(t *testing.T) {
+	p := Post{
+		StandalonePost: common.StandalonePost{
+			OP:    1,
+			Board: "a",
+			Post: common.Post{
+				Editing: true,
+			},
+		},
+		IP: "::1",
+	}
+	insertPost(t, &p)
+	com := []common.Command{
+		{
+			Type: common.Poll,
+			Poll: common.PollState{
+				Options: []string{"foo", "bar"},
+				Votes:   []uint32{0, 0},
+			},
+		},
+	}
+	err := ClosePost(p.ID, p.OP, "#poll\nfoo\nbar", nil, com)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sessions [3]auth.Base64Token
+	for i := range sessions {
+		sessions[i][0] = byte(i + 1)
+	}
+
+	cases := [...]struct {
+		name, ip string
+		option   int
+		session  auth.Base64Token
+		votes    []uint32
+		err      error
+	}{
+		{"first vote", "::1", 1, sessions[0], []uint32{0, 1}, nil},
+		{"same session", "::2", 0, sessions[0], nil, nil},
+		{"same IP", "::1", 0, sessions[1], nil, nil},
+		{"invalid option", "::3", 2, sessions[2], nil, ErrInvalidPollOption},
+		{"second vote", "::3", 0, sessions[2], []uint32{1, 1}, nil},
+	}
+
+	for _, c := range cases {
+		votes, err := VotePoll(p.ID, c.option, c.session, c.ip)
+		if err != c.err {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		AssertEquals(t, votes, c.votes)
+	}
+
+	post, err := GetPost(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, post.Commands[0].Poll.Votes, []uint32{1, 1})
+
+	// Tallies are kept on edits, that do not change the options
+	com[0].Poll.Votes = []uint32{0, 0}
+	_, err = EditPost(p.ID, "edited\n#poll\nfoo\nbar", nil, com)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, com[0].Poll.Votes, []uint32{1, 1})
+
+	com[0].Poll = common.PollState{
+		Options: []string{"foo", "baz"},
+		Votes:   []uint32{0, 0},
+	}
+	_, err = EditPost(p.ID, "#poll\nfoo\nbaz", nil, com)
+	if err != ErrPollVoted {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -64,8 +64,8 @@ func BestBoardCounter() (uint64, error) {
 	return getCounter(q)
 }
 
-// WritePost writes a post struct to the database. Only used in tests and
-// migrations.
+// WritePost writes a post struct to the database. Only used in tests,
+// migrations and board imports.
 func WritePost(tx *sql.Tx, p Post) (err error) {
 	// Don't store empty strings of these in the database. Zero value != NULL.
 	var (
@@ -85,13 +85,13 @@ func WritePost(tx *sql.Tx, p Post) (err error) {
 	_, err = sq.Insert("posts").
 		Columns(
 			"editing", "spoiler", "id", "board", "op", "time", "body", "flag",
-			"name", "trip", "auth", "password", "ip",
+			"name", "trip", "auth", "sage", "password", "ip",
 			"SHA1", "imageName",
 			"commands",
 		).
 		Values(
 			p.Editing, spoiler, p.ID, p.Board, p.OP, p.Time, p.Body, p.Flag,
-			p.Name, p.Trip, p.Auth, p.Password, ip,
+			p.Name, p.Trip, p.Auth, p.Sage, p.Password, ip,
 			img, imgName,
 			commandRow(p.Commands),
 		).
@@ -104,6 +104,10 @@ func WritePost(tx *sql.Tx, p Post) (err error) {
 	if err != nil {
 		return
 	}
+	err = writeFiles(tx, p.ID, p.Files)
+	if err != nil {
+		return
+	}
 
 	if p.Editing {
 		err = SetOpenBody(p.ID, []byte(p.Body))
@@ -172,6 +176,12 @@ func GetPostPassword(id uint64) (p []byte, err error) {
 	return
 }
 
+// NewPostID reserves a new post ID from the post ID sequence
+func NewPostID() (id uint64, err error) {
+	err = db.QueryRow(`select nextval('post_id')`).Scan(&id)
+	return
+}
+
 // SetPostCounter sets the post counter.
 // Should only be used in tests.
 func SetPostCounter(c uint64) error {
@@ -0,0 +1,63 @@
+// Publish/subscribe messaging between processes sharing the database
+
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Maximum size of a PostgreSQL notification payload. Larger messages are
+// passed through the pubsub_messages table.
+const maxNotifyPayload = 7999
+
+// Publish sends a message to the subscribers of channel in all processes
+// connected to the database, including this one
+func Publish(channel string, msg []byte) (err error) {
+	payload := string(msg)
+	if len(payload) > maxNotifyPayload {
+		var id uint64
+		err = sq.Insert("pubsub_messages").
+			Columns("data").
+			Values(payload).
+			Suffix("returning id").
+			QueryRow().
+			Scan(&id)
+		if err != nil {
+			return
+		}
+		payload = "#" + strconv.FormatUint(id, 10)
+	}
+	_, err = db.Exec("select pg_notify($1, $2)", channel, payload)
+	return
+}
+
+// Subscribe assigns a function to receive messages published on channel.
+// Can't be used in tests.
+func Subscribe(channel string, fn func(msg []byte) error) error {
+	return Listen(channel, func(payload string) (err error) {
+		msg, err := resolvePayload(payload)
+		if err != nil {
+			return
+		}
+		return fn(msg)
+	})
+}
+
+// Read messages, that did not fit into the notification payload, from the
+// database
+func resolvePayload(payload string) (msg []byte, err error) {
+	if !strings.HasPrefix(payload, "#") {
+		return []byte(payload), nil
+	}
+	id, err := strconv.ParseUint(payload[1:], 10, 64)
+	if err != nil {
+		return nil, ErrMsgParse(payload)
+	}
+	err = sq.Select("data").
+		From("pubsub_messages").
+		Where("id = ?", id).
+		QueryRow().
+		Scan(&msg)
+	return
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -12,15 +13,25 @@ import (
 )
 
 const (
+	// Additional files of a post as a JSON array in order of insertion
+	postFilesSQL = `(select jsonb_agg(
+			to_jsonb(fi)
+				|| jsonb_build_object('spoiler', f.spoiler, 'name', f.name)
+			order by f.position)
+		from post_files as f
+		join images as fi on f.sha1 = fi.sha1
+		where f.post_id = p.id
+	)`
+
 	postSelectsSQL = `p.editing, p.moderated, p.spoiler, p.sage, p.id,
-	p.time, p.body, p.flag, p.name, p.trip, p.auth,
+	p.time, p.body, p.flag, p.name, p.trip, p.auth, p.edited,
 	(select array_agg((l.target, linked_post.op, linked_thread.board))
 		from links as l
 		join posts as linked_post on l.target = linked_post.id
 		join threads as linked_thread on linked_post.op = linked_thread.id
 		where l.source = p.id
 	),
-	p.commands, p.imageName,
+	p.commands, ` + postFilesSQL + `, p.imageName,
 	i.*`
 
 	threadSelectsSQL = `t.sticky, t.board,
@@ -34,8 +45,14 @@ const (
 		from posts
 		where t.id = posts.op
 			and posts.SHA1 is not null
+	) + (
+		select count(*)
+		from post_files as f
+		join posts on f.post_id = posts.id
+		where t.id = posts.op
 	),
-	t.update_time, t.bump_time, t.subject, t.locked, ` + postSelectsSQL
+	t.update_time, t.bump_time, t.subject, t.locked, t.archived, ` +
+		postSelectsSQL
 
 	getOPSQL = `
 	select ` + threadSelectsSQL + `
@@ -60,6 +77,16 @@ const (
 const bestBoards = `board = 'media' OR board = 'life' OR board = 'world' OR board = 'sci' OR board = 'self' OR board = 'meta'`
 const bestTBoards = `t.board = 'media' OR t.board = 'life' OR t.board = 'world' OR t.board = 'sci' OR t.board = 'self' OR t.board = 'meta'`
 
+// IsBestBoard returns, if the threads of a board are shown on the /b/
+// metaboard. Must match bestBoards.
+func IsBestBoard(board string) bool {
+	switch board {
+	case "media", "life", "world", "sci", "self", "meta":
+		return true
+	}
+	return false
+}
+
 type imageScanner struct {
 	Audio, Video, Spoiler             sql.NullBool
 	FileType, ThumbType, Length, Size sql.NullInt64
@@ -112,19 +139,28 @@ type postScanner struct {
 	imageName string
 	links     linkScanner
 	commands  commandRow
+	files     []byte
+	edited    sql.NullInt64
 }
 
 func (p *postScanner) ScanArgs() []interface{} {
 	return []interface{}{
 		&p.Editing, &p.Moderated, &p.spoiler, &p.Sage, &p.ID, &p.Time, &p.Body,
-		&p.Flag, &p.Name, &p.Trip, &p.Auth, &p.links, &p.commands,
-		&p.imageName,
+		&p.Flag, &p.Name, &p.Trip, &p.Auth, &p.edited, &p.links, &p.commands,
+		&p.files, &p.imageName,
 	}
 }
 
 func (p postScanner) Val() (common.Post, error) {
 	p.Links = []common.Link(p.links)
 	p.Commands = []common.Command(p.commands)
+	p.Edited = p.edited.Int64
+	if p.files != nil {
+		err := json.Unmarshal(p.files, &p.Files)
+		if err != nil {
+			return p.Post, err
+		}
+	}
 
 	return p.Post, nil
 }
@@ -193,6 +229,11 @@ func GetThread(id uint64, lastN int) (t common.Thread, err error) {
 			return
 		}
 
+		t.CrossBoardBacklinks, err = getCrossBoardBacklinks(tx, id, t.Board)
+		if err != nil {
+			return
+		}
+
 		// Inject  moderation into affected posts
 		moderated := make([]*common.Post, 0, 64)
 		filterModerated(&moderated, &t.Post)
@@ -221,11 +262,11 @@ func scanOP(r rowScanner) (t common.Thread, err error) {
 		img   imageScanner
 		pArgs = post.ScanArgs()
 		iArgs = img.ScanArgs()
-		args  = make([]interface{}, 0, 8+len(pArgs)+len(iArgs))
+		args  = make([]interface{}, 0, 9+len(pArgs)+len(iArgs))
 	)
 	args = append(args,
 		&t.Sticky, &t.Board, &t.PostCount, &t.ImageCount, &t.UpdateTime,
-		&t.BumpTime, &t.Subject, &t.Locked,
+		&t.BumpTime, &t.Subject, &t.Locked, &t.Archived,
 	)
 	args = append(args, pArgs...)
 	args = append(args, iArgs...)
@@ -328,13 +369,13 @@ func getOPs() squirrel.SelectBuilder {
 func getPosts() squirrel.SelectBuilder {
 	return sq.Select(`
 			p.editing, p.moderated, p.spoiler, p.sage, p.id,
-			p.time, p.body, p.flag, p.name, p.trip, p.auth,
+			p.time, p.body, p.flag, p.name, p.trip, p.auth, p.edited,
 			(select array_agg((l.target, linked_post.op, linked_thread.board))
 				from links as l
 				join posts as linked_post on l.target = linked_post.id
 				join threads as linked_thread on linked_post.op = linked_thread.id
 				where l.source = p.id
-			), p.commands, p.imagename, i.*
+			), p.commands, ` + postFilesSQL + `, p.imagename, i.*
 		`).From("posts as p").
 		LeftJoin("images as i on p.SHA1 = i.SHA1")
 }
@@ -343,22 +384,78 @@ func getPosts() squirrel.SelectBuilder {
 // GetBoardCatalog retrieves all OPs of a single board
 func GetBoardCatalog(board string) (b common.Board, err error) {
 	b, err = scanCatalog(getOPs().
-		Where("t.board = ?", board).
+		Where("t.board = ? and t.archived = false", board).
 		OrderBy("sticky desc, bump_time desc"))
 	return
 }
 
+// ArchivePageSize is the maximum number of threads returned per archive page
+const ArchivePageSize = 100
+
+// GetArchive retrieves a page of archived OPs of a board or meta-board, most
+// recently archived first. Pages of the returned board is set to the total
+// number of archive pages.
+func GetArchive(board string, page int) (b common.Board, err error) {
+	b.Threads = []common.Thread{}
+	if page < 0 {
+		return
+	}
+
+	var total int
+	err = filterArchive(sq.Select("count(*)").From("threads as t"), board).
+		QueryRow().
+		Scan(&total)
+	if err != nil {
+		return
+	}
+	pages := (total + ArchivePageSize - 1) / ArchivePageSize
+	if page >= pages {
+		b.Pages = pages
+		return
+	}
+
+	b, err = scanCatalog(filterArchive(getOPs(), board).
+		OrderBy("t.archive_time desc").
+		Limit(ArchivePageSize).
+		Offset(uint64(page * ArchivePageSize)))
+	b.Pages = pages
+	return
+}
+
+// Restrict query to archived threads of a board or meta-board
+func filterArchive(q squirrel.SelectBuilder, board string,
+) squirrel.SelectBuilder {
+	q = q.Where("t.archived = true")
+	switch board {
+	case "all":
+	case "b":
+		q = q.Where("(" + bestTBoards + ")")
+	default:
+		q = q.Where("t.board = ?", board)
+	}
+	return q
+}
+
+// GetCatalogThreads retrieves the catalog entries of the specified threads.
+// Threads, that do not exist, are omitted.
+func GetCatalogThreads(ids []uint64) (b common.Board, err error) {
+	return scanCatalog(getOPs().
+		Where("t.id = any(?::bigint[])", encodeUint64Array(ids)))
+}
+
 // GetThreadIDs retrieves all threads IDs on the board in bump order with stickies first
 func GetThreadIDs(board string) ([]uint64, error) {
 	return scanThreadIDs(sq.Select("id").
 		From("threads").
-		Where("board = ?", board).
+		Where("board = ? and archived = false", board).
 		OrderBy("sticky desc, bump_time desc"))
 }
 
 // GetAllBoardCatalog retrieves all threads for the "/all/" meta-board
 func GetAllBoardCatalog() (board common.Board, err error) {
-	board, err = scanCatalog(getOPs().OrderBy("bump_time desc"))
+	board, err = scanCatalog(getOPs().
+		Where("t.archived = false").
+		OrderBy("bump_time desc"))
 	if err != nil {
 		return
 	}
@@ -390,7 +487,7 @@ func GetAllBoardCatalogMod() (board common.Board, err error) {
 // GetBestBoardCatalog retrieves all threads for the "/b/" meta-board
 func GetBestBoardCatalog() (board common.Board, err error) {
 	board, err = scanCatalog(getOPs().
-		Where(bestTBoards).
+		Where("(" + bestTBoards + ") and t.archived = false").
 		OrderBy("bump_time desc"))
 	if err != nil {
 		return
@@ -413,17 +510,36 @@ func GetBestBoardCatalog() (board common.Board, err error) {
 
 // GetAllThreadsIDs retrieves all threads IDs in bump order
 func GetAllThreadsIDs() ([]uint64, error) {
-	return scanThreadIDs(sq.Select("id").
+	return scanThreadIDs(filterNSFW(sq.Select("id").
 		From("threads").
-		OrderBy("bump_time desc"))
+		Where("archived = false").
+		OrderBy("bump_time desc"), "board"))
 }
 
 // GetBestThreadsIDs retrieves all threads IDs in bump order
 func GetBestThreadsIDs() ([]uint64, error) {
-	return scanThreadIDs(sq.Select("id").
+	return scanThreadIDs(filterNSFW(sq.Select("id").
 		From("threads").
-		Where(bestBoards).
-		OrderBy("bump_time desc"))
+		Where("(" + bestBoards + ") and archived = false").
+		OrderBy("bump_time desc"), "board"))
+}
+
+// Exclude rows from NSFW boards, if enabled. col is the name of the column
+// containing the board.
+func filterNSFW(q squirrel.SelectBuilder, col string) squirrel.SelectBuilder {
+	if !config.Get().HideNSFW {
+		return q
+	}
+	nsfw := make([]string, 0, 8)
+	for _, c := range config.GetAllBoardConfigs() {
+		if c.NSFW {
+			nsfw = append(nsfw, c.ID)
+		}
+	}
+	if len(nsfw) == 0 {
+		return q
+	}
+	return q.Where(squirrel.NotEq{col: nsfw})
 }
 
 func scanCatalog(q squirrel.SelectBuilder) (board common.Board, err error) {
@@ -9,6 +9,9 @@ import (
 	"github.com/go-playground/log"
 )
 
+// WordFilterReason is the reason of reports created by flagging word filters
+const WordFilterReason = "flagged by word filter"
+
 // Report a post for rule violations
 func Report(id uint64, board, reason, ip string, illegal bool) error {
 	// If the reported content is illegal, log an error so it will email
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/bakape/meguca/common"
+)
+
+// SearchPageSize is the maximum number of posts returned per search page
+const SearchPageSize = 30
+
+// SearchParams contains the query and filters of a full-text post search
+type SearchParams struct {
+	HasImage bool
+	// Only match posts with images of this file type, if set
+	FileType *uint8
+	// Limit search to a single thread, if not 0
+	Thread uint64
+	// Unix timestamp range of post creation. Ignored, if 0.
+	From, To int64
+	// 0-indexed result page
+	Page int
+	// Board to search. Empty string or "all" searches all boards.
+	// "b" searches all boards of the /b/ meta-board.
+	Board             string
+	Query, Name, Trip string
+}
+
+// SearchResult is a single page of search results
+type SearchResult struct {
+	Page  int                     `json:"page"`
+	Pages int                     `json:"pages"`
+	Total int                     `json:"total"`
+	Posts []common.StandalonePost `json:"posts"`
+}
+
+// Apply filters common to both the counting and retrieving search queries
+func filterSearch(q squirrel.SelectBuilder, p SearchParams,
+) squirrel.SelectBuilder {
+	q = q.
+		Where("p.body_tsv @@ plainto_tsquery('simple', ?)", p.Query).
+		Where("p.editing = false").
+		Where(`not exists (
+			select 1
+			from post_moderation as pm
+			where pm.post_id = p.id
+				and pm.type in (?, ?)
+		)`, common.DeletePost, common.SelfDeletePost)
+
+	switch p.Board {
+	case "", "all", "b":
+		if p.Board == "b" {
+			q = q.Where("(" + bestBoards + ")")
+		}
+		q = filterNSFW(q, "p.board")
+	default:
+		q = q.Where("p.board = ?", p.Board)
+	}
+	if p.Thread != 0 {
+		q = q.Where("p.op = ?", p.Thread)
+	}
+	if p.Name != "" {
+		q = q.Where("p.name = ?", p.Name)
+	}
+	if p.Trip != "" {
+		q = q.Where("p.trip = ?", p.Trip)
+	}
+	if p.HasImage || p.FileType != nil {
+		q = q.Where("p.SHA1 is not null")
+	}
+	if p.FileType != nil {
+		q = q.Where("i.file_type = ?", *p.FileType)
+	}
+	if p.From != 0 {
+		q = q.Where("p.time >= ?", p.From)
+	}
+	if p.To != 0 {
+		q = q.Where("p.time <= ?", p.To)
+	}
+	return q
+}
+
+// SearchPosts performs a full-text search of closed post bodies and returns
+// the requested page of matching posts in reverse chronological order
+func SearchPosts(p SearchParams) (res SearchResult, err error) {
+	res.Page = p.Page
+	res.Posts = make([]common.StandalonePost, 0, SearchPageSize)
+	if p.Query == "" || p.Page < 0 {
+		return
+	}
+
+	err = filterSearch(
+		sq.Select("count(*)").
+			From("posts as p").
+			LeftJoin("images as i on p.SHA1 = i.SHA1"),
+		p,
+	).
+		QueryRow().
+		Scan(&res.Total)
+	if err != nil {
+		return
+	}
+	res.Pages = (res.Total + SearchPageSize - 1) / SearchPageSize
+	if res.Page >= res.Pages {
+		return
+	}
+
+	q := filterSearch(
+		sq.Select("p.op, p.board, "+postSelectsSQL).
+			From("posts as p").
+			LeftJoin("images as i on p.SHA1 = i.SHA1"),
+		p,
+	).
+		OrderBy("p.id desc").
+		Limit(SearchPageSize).
+		Offset(uint64(p.Page * SearchPageSize))
+	err = queryAll(q, func(r *sql.Rows) (err error) {
+		var (
+			post  postScanner
+			img   imageScanner
+			sp    common.StandalonePost
+			pArgs = post.ScanArgs()
+			iArgs = img.ScanArgs()
+			args  = make([]interface{}, 2, 2+len(pArgs)+len(iArgs))
+		)
+		args[0] = &sp.OP
+		args[1] = &sp.Board
+		args = append(args, pArgs...)
+		args = append(args, iArgs...)
+
+		err = r.Scan(args...)
+		if err != nil {
+			return
+		}
+		sp.Post, err = extractPost(post, img)
+		if err != nil {
+			return
+		}
+		res.Posts = append(res.Posts, sp)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	moderated := make([]*common.Post, 0, 16)
+	for i := range res.Posts {
+		filterModerated(&moderated, &res.Posts[i].Post)
+	}
+	err = injectModeration(moderated, nil)
+	return
+}
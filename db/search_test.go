@@ -0,0 +1,104 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/bakape/meguca/common"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestSearchPosts(t *testing.T) {
+	prepareThreads(t)
+
+	jpeg := common.JPEG
+	png := common.PNG
+
+	cases := [...]struct {
+		name   string
+		params SearchParams
+		ids    []uint64
+	}{
+		{
+			name: "no query",
+		},
+		{
+			name:   "match",
+			params: SearchParams{Query: "foo"},
+			ids:    []uint64{2},
+		},
+		{
+			name:   "no match",
+			params: SearchParams{Query: "bar"},
+		},
+		{
+			name: "board filter",
+			params: SearchParams{
+				Query: "foo",
+				Board: "a",
+			},
+			ids: []uint64{2},
+		},
+		{
+			name: "other board",
+			params: SearchParams{
+				Query: "foo",
+				Board: "c",
+			},
+		},
+		{
+			name: "thread filter",
+			params: SearchParams{
+				Query:  "foo",
+				Thread: 3,
+			},
+		},
+		{
+			name: "has image",
+			params: SearchParams{
+				Query:    "foo",
+				HasImage: true,
+			},
+		},
+		{
+			name: "file type mismatch",
+			params: SearchParams{
+				Query:    "foo",
+				FileType: &png,
+			},
+		},
+		{
+			name: "file type no image",
+			params: SearchParams{
+				Query:    "foo",
+				FileType: &jpeg,
+			},
+		},
+		{
+			name: "page overflow",
+			params: SearchParams{
+				Query: "foo",
+				Page:  1,
+			},
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := SearchPosts(c.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]uint64, 0, len(res.Posts))
+			for _, p := range res.Posts {
+				ids = append(ids, p.ID)
+			}
+			if c.ids == nil {
+				c.ids = []uint64{}
+			}
+			AssertEquals(t, ids, c.ids)
+		})
+	}
+}
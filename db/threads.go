@@ -17,6 +17,7 @@ var (
 	postCountCacheMu         sync.RWMutex
 	errTooManyWatchedThreads = common.StatusError{
 		errors.New("too many watched threads"), 400}
+	errThreadArchived = common.ErrInvalidInput("thread archived")
 )
 
 // Diff of passed and actual thread posts counts
@@ -137,10 +138,11 @@ func listenForThreadUpdates(canceller <-chan struct{}) (err error) {
 
 // Thread is a template for writing new threads to the database
 type Thread struct {
-	ID                   uint64
-	PostCtr, ImageCtr    uint32
-	UpdateTime, BumpTime int64
-	Subject, Board       string
+	ID                       uint64
+	PostCtr, ImageCtr        uint32
+	UpdateTime, BumpTime     int64
+	Subject, Board           string
+	Sticky, Locked, Archived bool
 	//nonLive              bool
 }
 
@@ -184,12 +186,20 @@ func InsertThread(tx *sql.Tx, subject string, p *Post) (err error) {
 	return InsertPost(tx, p)
 }
 
-// WriteThread writes a thread and it's OP to the database. Only used for tests.
+// WriteThread writes a thread and it's OP to the database. Only used for tests
+// and board imports.
 func WriteThread(t Thread, p Post) (err error) {
+	// Archived threads are considered archived at their last update
+	var archiveTime *int64
+	if t.Archived {
+		archiveTime = &t.UpdateTime
+	}
+
 	return InTransaction(false, func(tx *sql.Tx) (err error) {
 		_, err = sq.
 			Insert("threads").
-			Columns("board", "id", "update_time", "bump_time", "subject"/*, "nonLive"*/).
+			Columns("board", "id", "update_time", "bump_time", "subject", /*, "nonLive"*/
+				"sticky", "locked", "archived", "archive_time").
 			Values(
 				t.Board,
 				t.ID,
@@ -197,6 +207,10 @@ func WriteThread(t Thread, p Post) (err error) {
 				t.BumpTime,
 				t.Subject,
 				//t.nonLive,
+				t.Sticky,
+				t.Locked,
+				t.Archived,
+				archiveTime,
 			).
 			RunWith(tx).
 			Exec()
@@ -207,6 +221,16 @@ func WriteThread(t Thread, p Post) (err error) {
 	})
 }
 
+// SetThreadBumpTime overrides the bump time of a thread. Used for restoring
+// the bump order of imported threads.
+func SetThreadBumpTime(id uint64, bumpTime int64) (err error) {
+	_, err = sq.Update("threads").
+		Set("bump_time", bumpTime).
+		Where("id = ?", id).
+		Exec()
+	return
+}
+
 func queryThreadBool(id uint64, key string) (val bool, err error) {
 	err = sq.Select(key).
 		From("threads").
@@ -221,6 +245,11 @@ func CheckThreadNonLive(id uint64) (bool, error) {
 	return queryThreadBool(id, "nonLive")
 }*/
 
+// CheckThreadArchived checks, if a thread has been moved to the board archive
+func CheckThreadArchived(id uint64) (bool, error) {
+	return queryThreadBool(id, "archived")
+}
+
 // CheckThreadLocked checks, if a thread has been locked by a moderator
 func CheckThreadLocked(id uint64) (locked bool, err error) {
 	err = sq.Select("locked").
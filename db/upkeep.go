@@ -41,7 +41,8 @@ func runCleanupTasks() {
 func runMinuteTasks() {
 	if config.Server.ImagerMode != config.ImagerOnly {
 		logError("open post cleanup", closeDanglingPosts())
-		expireRows("image_tokens", "bans", "failed_captchas")
+		expireRows("image_tokens", "bans", "failed_captchas",
+			"pubsub_messages")
 	}
 }
 
@@ -60,13 +61,14 @@ func runHourTasks() {
 			"mod_log", "reports")
 		logError("remove identity info", removeIdentityInfo())
 		logError("thread cleanup", deleteOldThreads())
+		logError("archive cleanup", deleteOldArchivedThreads())
 		logError("board cleanup", deleteUnusedBoards())
 		logError("delete dangling open post bodies", cleanUpOpenPostBodies())
 		_, err := db.Exec(`vacuum`)
 		logError("vaccum database", err)
 	}
 	if config.Server.ImagerMode != config.NoImager {
-		logError("image cleanup", deleteUnusedImages())
+		logError("image cleanup", DeleteUnusedImages())
 	}
 }
 
@@ -140,6 +142,8 @@ func closeDanglingPosts() error {
 			return err
 		}
 
+		body, flag := common.CensorText(p.board, config.FilterBody, body)
+
 		links, com, err := common.ParseBody([]byte(body), p.board, p.op, p.id, p.ip.String, true)
 		// Still close posts on invalid input
 		switch err.(type) {
@@ -156,6 +160,12 @@ func closeDanglingPosts() error {
 		if err != nil {
 			return err
 		}
+		if flag && p.ip.Valid {
+			err = Report(p.id, p.board, WordFilterReason, p.ip.String, false)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -227,9 +237,10 @@ func deleteBoard(tx *sql.Tx, id, by, reason string) (err error) {
 	return
 }
 
-// Delete stale threads. Thread retention measured in a bump time threshold,
-// that is calculated as a function of post count till bump limit with an N days
-// floor and ceiling.
+// Delete stale threads or move them to the archive, if the board has archiving
+// enabled. Thread retention measured in a bump time threshold, that is
+// calculated as a function of post count till bump limit with an N days floor
+// and ceiling.
 func deleteOldThreads() (err error) {
 	conf := config.Get()
 	if !conf.PruneThreads {
@@ -243,14 +254,17 @@ func deleteOldThreads() (err error) {
 			min           = float64(conf.ThreadExpiryMin * 24 * 3600)
 			max           = float64(conf.ThreadExpiryMax * 24 * 3600)
 			toDel         = make([]uint64, 0, 16)
+			toArchive     = make([]uint64, 0, 16)
 			id, postCount uint64
 			bumpTime      int64
+			board         string
 			deleted       sql.NullBool
 		)
 		err = queryAll(
 			sq.
 				Select(
 					"threads.id",
+					"threads.board",
 					"bump_time",
 					`(select count(*)
 						from posts
@@ -259,14 +273,15 @@ func deleteOldThreads() (err error) {
 					fmt.Sprintf(
 						`(select exists (
 							select 1 from post_moderation
-							where post_id = threads.id and type = %d))`,
-						common.DeletePost),
+							where post_id = threads.id and type in (%d, %d)))`,
+						common.DeletePost, common.SelfDeletePost),
 				).
 				From("threads").
 				Join("posts on threads.id = posts.id").
+				Where("threads.archived = false").
 				RunWith(tx),
 			func(r *sql.Rows) (err error) {
-				err = r.Scan(&id, &bumpTime, &postCount, &deleted)
+				err = r.Scan(&id, &board, &bumpTime, &postCount, &deleted)
 				if err != nil {
 					return
 				}
@@ -280,7 +295,12 @@ func deleteOldThreads() (err error) {
 					threshold = min
 				}
 				if float64(now-bumpTime) > threshold {
-					toDel = append(toDel, id)
+					// Deleted threads are never archived
+					if !deleted.Bool && config.GetBoardConfigs(board).Archive {
+						toArchive = append(toArchive, id)
+					} else {
+						toDel = append(toDel, id)
+					}
 				}
 				return
 			},
@@ -303,7 +323,39 @@ func deleteOldThreads() (err error) {
 				}
 			}
 		}
+		if len(toArchive) != 0 {
+			// Lock threads and move them out of the board index
+			q, err = tx.Prepare(`update threads
+				set archived = true,
+					locked = true,
+					archive_time = $2,
+					update_time = $2
+				where id = $1`)
+			if err != nil {
+				return
+			}
+			for _, id := range toArchive {
+				_, err = q.Exec(id, now)
+				if err != nil {
+					return
+				}
+			}
+		}
 
 		return
 	})
 }
+
+// Delete archived threads, that have exceeded the archive retention time.
+// A retention time of 0 keeps archived threads indefinitely.
+func deleteOldArchivedThreads() (err error) {
+	days := config.Get().ArchiveExpiry
+	if days == 0 {
+		return
+	}
+	_, err = sq.Delete("threads").
+		Where("archived = true and archive_time < ?",
+			time.Now().Add(-time.Duration(days)*time.Hour*24).Unix()).
+		Exec()
+	return
+}
@@ -28,6 +28,9 @@ func TestOpenPostClosing(t *testing.T) {
 	) {
 		return nil, nil, nil
 	}
+	common.CensorText = func(_, _, text string) (string, bool) {
+		return text, false
+	}
 
 	tooOld := time.Now().Add(-time.Minute * 31).Unix()
 	posts := [...]Post{
@@ -349,6 +352,91 @@ func TestDeleteOldThreads(t *testing.T) {
 	})
 }
 
+func TestArchiveOldThreads(t *testing.T) {
+	assertTableClear(t, "boards")
+	writeSampleBoard(t)
+	config.Set(config.Configs{
+		ArchiveExpiry: 7,
+		Public: config.Public{
+			PruneThreads:    true,
+			ThreadExpiryMin: 7,
+			ThreadExpiryMax: 7,
+		},
+	})
+	_, err := config.SetBoardConfigs(config.BoardConfigs{
+		ID:      "a",
+		Archive: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer config.ClearBoards()
+
+	writeExpiringThreads(t, threadExpiryCases{
+		{1, "a", time.Now().Add(-eightDays)},
+		{2, "a", time.Now()},
+	})
+
+	err = deleteOldThreads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertThreadDeleted(t, 1, false)
+	assertThreadDeleted(t, 2, false)
+
+	for id, std := range map[uint64]bool{1: true, 2: false} {
+		archived, err := CheckThreadArchived(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEquals(t, archived, std)
+		locked, err := CheckThreadLocked(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEquals(t, locked, std)
+	}
+
+	archive, err := GetArchive("a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, archive.Pages, 1)
+	AssertEquals(t, len(archive.Threads), 1)
+	AssertEquals(t, archive.Threads[0].ID, uint64(1))
+
+	archive, err = GetArchive("a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, len(archive.Threads), 0)
+	ids, err := GetThreadIDs("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, ids, []uint64{2})
+
+	err = SetThreadLock(1, false, "admin")
+	if err != errThreadArchived {
+		UnexpectedError(t, err)
+	}
+
+	// Expire archived thread
+	_, err = sq.Update("threads").
+		Set("archive_time", time.Now().Add(-eightDays).Unix()).
+		Where("id = 1").
+		Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = deleteOldArchivedThreads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertThreadDeleted(t, 1, true)
+	assertThreadDeleted(t, 2, false)
+}
+
 func TestDeleteBoard(t *testing.T) {
 	assertTableClear(t, "boards", "accounts")
 	writeSampleBoard(t)
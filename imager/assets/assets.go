@@ -2,7 +2,6 @@
 package assets
 
 import (
-	"errors"
 	"io"
 	"os"
 	"path/filepath"
@@ -39,27 +38,26 @@ var (
 	}
 )
 
-// GetFilePaths generates file paths of the source file and its thumbnail
+// Default root path of images, if no ImageRootOverride is set
+const defaultImageRoot = "/assets/images"
+
+// GetFilePaths generates file paths of the source file and its thumbnail on
+// the local file system
 func GetFilePaths(SHA1 string, fileType, thumbType uint8) (paths [2]string) {
-	paths[0] = util.ConcatStrings(
-		"/images/src/",
-		SHA1,
-		".",
-		common.Extensions[fileType],
-	)
-	paths[1] = util.ConcatStrings(
-		"/images/thumb/",
-		SHA1,
-		".",
-		common.Extensions[thumbType],
-	)
-	for i := range paths {
-		paths[i] = filepath.FromSlash(paths[i][1:])
+	for i, k := range FileKeys(SHA1, fileType, thumbType) {
+		paths[i] = filepath.Join("images", filepath.FromSlash(k))
 	}
-
 	return
 }
 
+// FileKeys generates storage keys of the source file and its thumbnail
+func FileKeys(SHA1 string, fileType, thumbType uint8) [2]string {
+	return [2]string{
+		util.ConcatStrings("src/", SHA1, ".", common.Extensions[fileType]),
+		util.ConcatStrings("thumb/", SHA1, ".", common.Extensions[thumbType]),
+	}
+}
+
 // RelativeSourcePath returns a file's source path relative to the root path
 func RelativeSourcePath(fileType uint8, SHA1 string) string {
 	return util.ConcatStrings(
@@ -98,7 +96,7 @@ func imageRoot() string {
 	if r != "" {
 		return r
 	}
-	return "/assets/images"
+	return defaultImageRoot
 }
 
 // ThumbPath returns the path to the thumbnail of an image
@@ -123,11 +121,11 @@ func SourcePath(fileType uint8, SHA1 string) string {
 	)
 }
 
-// Return free space on image storage device.
+// Return free space on the storage device of the local image directory.
 // Image source file and thumbnail directories must be on the same drive.
-func freeSpace() (n uint64, err error) {
+func freeSpace(root string) (n uint64, err error) {
 	var stats syscall.Statfs_t
-	path, err := filepath.Abs("images/src")
+	path, err := filepath.Abs(filepath.Join(root, "src"))
 	if err != nil {
 		return
 	}
@@ -135,58 +133,29 @@ func freeSpace() (n uint64, err error) {
 	return stats.Bavail * uint64(stats.Bsize), err
 }
 
-// Write writes file assets to disk
+// Write writes file assets to the configured storage backend
 func Write(SHA1 string, fileType, thumbType uint8, src, thumb io.ReadSeeker,
 ) (
 	err error,
 ) {
-	// Assert at least 100 MB of free disk space is available
-	if !common.IsCI {
-		var free uint64
-		free, err = freeSpace()
-		if err != nil {
-			return
-		}
-		if free < (100 << 20) {
-			return errors.New("not enough disk space")
-		}
-	}
-
-	paths := GetFilePaths(SHA1, fileType, thumbType)
+	keys := FileKeys(SHA1, fileType, thumbType)
 
 	// Don't write files in parallel to reduce the amount of threads the Go
 	// runtime needs to spawn.
-	err = writeFile(paths[0], src)
+	err = storage.Write(keys[0], src)
 	if err != nil {
 		return
 	}
 	if thumb != nil { // Archives, audio, etc. can be missing thumbnails
-		err = writeFile(paths[1], thumb)
-	}
-	return
-}
-
-// Write a single file to disk with the appropriate permissions and flags
-func writeFile(path string, src io.ReadSeeker) (err error) {
-	file, err := os.Create(path)
-	if err != nil {
-		return
-	}
-	defer file.Close()
-
-	_, err = src.Seek(0, 0)
-	if err != nil {
-		return
+		err = storage.Write(keys[1], thumb)
 	}
-	_, err = io.Copy(file, src)
 	return
 }
 
 // Delete deletes file assets belonging to a single upload
 func Delete(SHA1 string, fileType, thumbType uint8) error {
-	for _, path := range GetFilePaths(SHA1, fileType, thumbType) {
-		// Ignore somehow absent images
-		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+	for _, k := range FileKeys(SHA1, fileType, thumbType) {
+		if err := storage.Delete(k); err != nil {
 			return err
 		}
 	}
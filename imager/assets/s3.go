@@ -0,0 +1,276 @@
+package assets
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakape/meguca/config"
+)
+
+const (
+	// Format of timestamps used in AWS Signature Version 4
+	amzDateFormat = "20060102T150405Z"
+
+	// Validity period of presigned redirect URLs
+	presignExpiry = time.Hour
+)
+
+// S3Storage stores files in a bucket of an S3-compatible object storage,
+// such as AWS S3 or MinIO. Objects are addressed path-style as
+// <endpoint>/<bucket>/<key> or, if no bucket is configured, as
+// <endpoint>/<key> with the endpoint addressing the bucket directly.
+type S3Storage struct {
+	conf   config.S3Configs
+	client *http.Client
+	// For overriding during tests
+	now func() time.Time
+}
+
+// NewS3Storage creates a storage backend for an S3-compatible bucket
+func NewS3Storage(c config.S3Configs) *S3Storage {
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	return &S3Storage{
+		conf:   c,
+		client: &http.Client{Timeout: time.Minute},
+		now:    time.Now,
+	}
+}
+
+// Error returned by an object storage on unexpected responses
+type s3Error struct {
+	method, key string
+	code        int
+	body        string
+}
+
+func (e s3Error) Error() string {
+	return fmt.Sprintf("s3: %s %s: %d %s", e.method, e.key, e.code, e.body)
+}
+
+// Returns an error describing a failed response. Consumes the response body.
+func newS3Error(res *http.Response, key string) error {
+	buf, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1<<10))
+	return s3Error{res.Request.Method, key, res.StatusCode, string(buf)}
+}
+
+// Write implements Storage
+func (s *S3Storage) Write(key string, src io.ReadSeeker) (err error) {
+	// The payload hash and length must be known before sending
+	size, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	_, err = src.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, src)
+	if err != nil {
+		return
+	}
+	_, err = src.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest("PUT", s.objectURL(key), ioutil.NopCloser(src))
+	if err != nil {
+		return
+	}
+	req.ContentLength = size
+	if typ := mime.TypeByExtension(path.Ext(key)); typ != "" {
+		req.Header.Set("Content-Type", typ)
+	}
+	s.sign(req, hex.EncodeToString(h.Sum(nil)))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return newS3Error(res, key)
+	}
+	return
+}
+
+// Delete implements Storage
+func (s *S3Storage) Delete(key string) (err error) {
+	res, err := s.do("DELETE", key)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200, 204, 404:
+		return nil
+	default:
+		return newS3Error(res, key)
+	}
+}
+
+// Open implements Storage
+func (s *S3Storage) Open(key string) (io.ReadCloser, error) {
+	res, err := s.do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case 200:
+		return res.Body, nil
+	case 404:
+		res.Body.Close()
+		return nil, &os.PathError{Op: "open", Path: key, Err: os.ErrNotExist}
+	default:
+		defer res.Body.Close()
+		return nil, newS3Error(res, key)
+	}
+}
+
+// URL implements Storage. Returns a presigned URL, if redirection is enabled.
+func (s *S3Storage) URL(key string) (string, bool) {
+	if !s.conf.Redirect {
+		return "", false
+	}
+	return s.presign("GET", key, presignExpiry), true
+}
+
+// Send a signed request without a payload
+func (s *S3Storage) do(method, key string) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, emptyPayloadHash)
+	return s.client.Do(req)
+}
+
+func (s *S3Storage) objectURL(key string) string {
+	if s.conf.Bucket == "" {
+		return s.conf.Endpoint + "/" + key
+	}
+	return s.conf.Endpoint + "/" + s.conf.Bucket + "/" + key
+}
+
+// SHA-256 of an empty payload
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Sign request using AWS Signature Version 4 in the Authorization header
+func (s *S3Storage) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	date := now.Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           date,
+	}
+	if typ := req.Header.Get("Content-Type"); typ != "" {
+		headers = append(headers, "content-type")
+		values["content-type"] = typ
+	}
+	sort.Strings(headers)
+
+	var canonHeaders strings.Builder
+	for _, h := range headers {
+		canonHeaders.WriteString(h)
+		canonHeaders.WriteByte(':')
+		canonHeaders.WriteString(values[h])
+		canonHeaders.WriteByte('\n')
+	}
+	signed := strings.Join(headers, ";")
+
+	scope := s.scope(now)
+	sig := s.signature(now, canonicalRequest(
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signed,
+		payloadHash,
+	), scope)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.conf.AccessKey, scope, signed, sig,
+	))
+}
+
+// Generate a presigned URL with the signature in the query string
+func (s *S3Storage) presign(method, key string, expiry time.Duration) string {
+	now := s.now().UTC()
+	u, _ := url.Parse(s.objectURL(key))
+	scope := s.scope(now)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.conf.AccessKey+"/"+scope)
+	q.Set("X-Amz-Date", now.Format(amzDateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expiry/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+	// url.Values.Encode sorts by key, as required for the canonical query
+	query := strings.Replace(q.Encode(), "+", "%20", -1)
+
+	sig := s.signature(now, canonicalRequest(
+		method,
+		u.EscapedPath(),
+		query,
+		"host:"+u.Host+"\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	), scope)
+	u.RawQuery = query + "&X-Amz-Signature=" + sig
+	return u.String()
+}
+
+// Credential scope of a request made at t
+func (s *S3Storage) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.conf.Region + "/s3/aws4_request"
+}
+
+// Compute the hex-encoded signature of a canonical request
+func (s *S3Storage) signature(t time.Time, canonReq, scope string) string {
+	sum := sha256.Sum256([]byte(canonReq))
+	toSign := "AWS4-HMAC-SHA256\n" + t.Format(amzDateFormat) + "\n" + scope +
+		"\n" + hex.EncodeToString(sum[:])
+
+	key := []byte("AWS4" + s.conf.SecretKey)
+	for _, part := range [...]string{
+		t.Format("20060102"), s.conf.Region, "s3", "aws4_request",
+	} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func canonicalRequest(method, path, query, headers, signed, payloadHash string,
+) string {
+	return strings.Join([]string{
+		method, path, query, headers, signed, payloadHash,
+	}, "\n")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
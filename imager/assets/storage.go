@@ -0,0 +1,140 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+)
+
+// Active storage backend. Defaults to local disk storage for tests.
+var storage Storage = LocalStorage{Root: "images"}
+
+// Storage is a backend for persisting uploaded file assets. Files are
+// addressed by slash-separated keys relative to the image root, such as
+// "src/<SHA1>.jpg" or "thumb/<SHA1>.webp".
+type Storage interface {
+	// Write a file under key, overwriting any existing file
+	Write(key string, src io.ReadSeeker) error
+
+	// Delete the file under key. Absent files must not produce an error.
+	Delete(key string) error
+
+	// Open the file under key for reading. Returns an error satisfying
+	// os.IsNotExist, if the file does not exist.
+	Open(key string) (io.ReadCloser, error)
+
+	// URL returns a URL clients can be redirected to for retrieving the file
+	// under key. Returns false, if files must be served by this server.
+	URL(key string) (string, bool)
+}
+
+// Init selects and prepares the storage backend configured in
+// config.Server.Storage
+func Init() error {
+	c := config.Server.Storage
+	switch c.Type {
+	case "", "local":
+		storage = LocalStorage{Root: "images"}
+		return CreateDirs()
+	case "s3":
+		storage = NewS3Storage(c.S3)
+		return nil
+	default:
+		return fmt.Errorf("unknown storage type: %s", c.Type)
+	}
+}
+
+// SetStorage overrides the active storage backend. Only used in tests.
+func SetStorage(s Storage) {
+	storage = s
+}
+
+// Open opens an asset by a client-supplied path relative to the image root
+func Open(p string) (io.ReadCloser, error) {
+	return storage.Open(cleanKey(p))
+}
+
+// RedirectURL returns a URL to redirect clients to for a client-supplied
+// path relative to the image root. Returns false, if the asset must be served
+// by this server.
+func RedirectURL(p string) (string, bool) {
+	if _, ok := storage.(LocalStorage); ok {
+		return "", false
+	}
+
+	// Files are stored under the same keys, so the override can point
+	// straight to the bucket or a CDN in front of it
+	if r := config.Get().ImageRootOverride; r != "" && r != defaultImageRoot {
+		return strings.TrimSuffix(r, "/") + "/" + cleanKey(p), true
+	}
+	return storage.URL(cleanKey(p))
+}
+
+// Normalize path into a storage key, that can not escape the image root
+func cleanKey(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// LocalStorage stores files on the local file system under Root
+type LocalStorage struct {
+	Root string
+}
+
+func (s LocalStorage) path(key string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(key))
+}
+
+// Write implements Storage
+func (s LocalStorage) Write(key string, src io.ReadSeeker) (err error) {
+	// Assert at least 100 MB of free disk space is available
+	if !common.IsCI {
+		var free uint64
+		free, err = freeSpace(s.Root)
+		if err != nil {
+			return
+		}
+		if free < (100 << 20) {
+			return errors.New("not enough disk space")
+		}
+	}
+
+	file, err := os.Create(s.path(key))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = src.Seek(0, 0)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(file, src)
+	return
+}
+
+// Delete implements Storage
+func (s LocalStorage) Delete(key string) error {
+	// Ignore somehow absent images
+	err := os.Remove(s.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Open implements Storage
+func (s LocalStorage) Open(key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+// URL implements Storage
+func (s LocalStorage) URL(_ string) (string, bool) {
+	return "", false
+}
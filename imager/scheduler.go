@@ -9,14 +9,25 @@ import (
 	"mime/multipart"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/metrics"
 )
 
 var (
 	scheduleJob      = make(chan jobRequest, 128)
 	scheduleSmallJob = make(chan jobRequest, 128)
 
+	// Number of thumbnailing jobs queued or being processed
+	pendingJobs int64
+
+	// Time from queueing a thumbnailing job to its completion
+	thumbnailingLatency = metrics.NewHistogram([]float64{
+		.1, .25, .5, 1, 2.5, 5, 10, 30, 60,
+	})
+
 	// Pool of temp buffers used for hashing
 	buf512Pool = sync.Pool{
 		New: func() interface{} {
@@ -26,9 +37,10 @@ var (
 )
 
 type jobRequest struct {
-	file multipart.File
-	size int
-	res  chan<- thumbnailingResponse
+	file   multipart.File
+	size   int
+	queued time.Time
+	res    chan<- thumbnailingResponse
 }
 
 type thumbnailingResponse struct {
@@ -43,7 +55,8 @@ func requestThumbnailing(file multipart.File, size int,
 	// Allows for some degree of concurrent thumbnailing without exhausting
 	// server resources.
 	ch := make(chan thumbnailingResponse)
-	req := jobRequest{file, size, ch}
+	req := jobRequest{file, size, time.Now(), ch}
+	atomic.AddInt64(&pendingJobs, 1)
 	if size <= 4<<20 {
 		scheduleSmallJob <- req
 	} else {
@@ -54,18 +67,42 @@ func requestThumbnailing(file multipart.File, size int,
 
 // Queue thumbnailing jobs to reduce resource contention and prevent OOM
 func init() {
+	metrics.Register("meguca_thumbnail_queue_depth",
+		"Uploads waiting to be thumbnailed",
+		metrics.GaugeFunc(func() float64 {
+			return float64(len(scheduleJob) + len(scheduleSmallJob))
+		}))
+	metrics.Register("meguca_thumbnail_duration_seconds",
+		"Time from queueing an upload to thumbnailing completion",
+		thumbnailingLatency)
+
 	for _, ch := range [...]<-chan jobRequest{scheduleJob, scheduleSmallJob} {
 		go func(queue <-chan jobRequest) {
 			runtime.LockOSThread()
 			for {
 				req := <-queue
 				id, err := processRequest(req.file, req.size)
+				thumbnailingLatency.ObserveSince(req.queued)
 				req.res <- thumbnailingResponse{id, err}
+				atomic.AddInt64(&pendingJobs, -1)
 			}
 		}(ch)
 	}
 }
 
+// WaitForJobs blocks until all queued thumbnailing jobs have been processed
+// or the timeout expires. Returns false on timeout.
+func WaitForJobs(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&pendingJobs) != 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return true
+}
+
 // Hash file to string
 func hashFile(rs io.ReadSeeker, h hash.Hash, encode func([]byte) string,
 ) (
@@ -2,9 +2,23 @@
 
 package main
 
-import "github.com/bakape/meguca/server"
+import (
+	"fmt"
+	"os"
+
+	"github.com/bakape/meguca/cli"
+	"github.com/bakape/meguca/server"
+)
 
 func main() {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		if err := cli.Run(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	err := server.Start()
 	if err != nil {
 		panic(err)
@@ -0,0 +1,292 @@
+// Package metrics collects server internals and exposes them in the Prometheus
+// text exposition format
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are histogram bucket upper bounds in seconds suitable for
+// measuring request latency
+var DefaultBuckets = []float64{
+	.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10,
+}
+
+var (
+	registry   = make(map[string]entry)
+	registryMu sync.RWMutex
+)
+
+// Registered metric with metadata
+type entry struct {
+	help string
+	m    metric
+}
+
+// Any metric, that can be written in the text exposition format
+type metric interface {
+	// Prometheus metric type
+	kind() string
+
+	// Write all samples of the metric
+	write(w *bufio.Writer, name string)
+}
+
+// Register a metric under a unique name. Panics on duplicate names, as that is
+// always a programming error.
+func Register(name, help string, m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	registry[name] = entry{help, m}
+}
+
+// WriteTo writes all registered metrics to w, sorted by name
+func WriteTo(w io.Writer) error {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	for _, n := range names {
+		e := registry[n]
+		buf.WriteString("# HELP ")
+		buf.WriteString(n)
+		buf.WriteByte(' ')
+		buf.WriteString(e.help)
+		buf.WriteString("\n# TYPE ")
+		buf.WriteString(n)
+		buf.WriteByte(' ')
+		buf.WriteString(e.m.kind())
+		buf.WriteByte('\n')
+		e.m.write(buf, n)
+	}
+	return buf.Flush()
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	n uint64
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.n, 1)
+}
+
+// Add increments the counter by n
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.n, n)
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.n)
+}
+
+func (c *Counter) kind() string {
+	return "counter"
+}
+
+func (c *Counter) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", float64(c.Value()))
+}
+
+// CounterFunc is a counter, whose value is read from an external source on
+// each collection
+type CounterFunc func() float64
+
+func (CounterFunc) kind() string {
+	return "counter"
+}
+
+func (fn CounterFunc) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", fn())
+}
+
+// GaugeFunc is a value, that can go up and down, read on each collection
+type GaugeFunc func() float64
+
+func (GaugeFunc) kind() string {
+	return "gauge"
+}
+
+func (fn GaugeFunc) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", fn())
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// NewHistogram creates a histogram with the passed ascending bucket upper
+// bounds
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+// Observe adds a single observation to the histogram
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// ObserveSince adds the seconds passed since t as an observation
+func (h *Histogram) ObserveSince(t time.Time) {
+	h.Observe(time.Since(t).Seconds())
+}
+
+func (h *Histogram) kind() string {
+	return "histogram"
+}
+
+func (h *Histogram) write(w *bufio.Writer, name string) {
+	h.writeLabeled(w, name, "")
+}
+
+// Write histogram samples with an optional preformatted label set
+func (h *Histogram) writeLabeled(w *bufio.Writer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	for i, b := range h.buckets {
+		writeSample(w, name+"_bucket",
+			labels+sep+`le="`+formatFloat(b)+`"`, float64(h.counts[i]))
+	}
+	writeSample(w, name+"_bucket", labels+sep+`le="+Inf"`, float64(h.count))
+	writeSample(w, name+"_sum", labels, h.sum)
+	writeSample(w, name+"_count", labels, float64(h.count))
+}
+
+// HistogramVec is a set of histograms partitioned by label values
+type HistogramVec struct {
+	mu         sync.RWMutex
+	labels     []string
+	buckets    []float64
+	histograms map[string]*Histogram
+}
+
+// NewHistogramVec creates a histogram set partitioned by the passed label names
+func NewHistogramVec(buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		labels:     labels,
+		buckets:    buckets,
+		histograms: make(map[string]*Histogram),
+	}
+}
+
+// With returns the histogram for the passed label values, creating it, if
+// needed. Values must be in the same order as the label names.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	var b strings.Builder
+	for i, l := range v.labels {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l)
+		b.WriteString(`="`)
+		if i < len(values) {
+			b.WriteString(escapeLabel(values[i]))
+		}
+		b.WriteByte('"')
+	}
+	key := b.String()
+
+	v.mu.RLock()
+	h := v.histograms[key]
+	v.mu.RUnlock()
+	if h != nil {
+		return h
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	h = v.histograms[key]
+	if h == nil {
+		h = NewHistogram(v.buckets)
+		v.histograms[key] = h
+	}
+	return h
+}
+
+func (v *HistogramVec) kind() string {
+	return "histogram"
+}
+
+func (v *HistogramVec) write(w *bufio.Writer, name string) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	keys := make([]string, 0, len(v.histograms))
+	for k := range v.histograms {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.histograms[k].writeLabeled(w, name, k)
+	}
+}
+
+// Write a single sample line
+func writeSample(w *bufio.Writer, name, labels string, val float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(val))
+	w.WriteByte('\n')
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Escape a label value as required by the text exposition format
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/bakape/meguca/test"
+)
+
+func TestWriteTo(t *testing.T) {
+	var c Counter
+	c.Add(2)
+	c.Inc()
+	Register("test_counter", "A counter", &c)
+	Register("test_gauge", "A gauge", GaugeFunc(func() float64 {
+		return 1.5
+	}))
+
+	h := NewHistogramVec([]float64{1, 2}, "route")
+	h.With(`/"a"`).Observe(1.5)
+	h.With(`/"a"`).Observe(3)
+	Register("test_histogram", "A histogram", h)
+
+	var buf bytes.Buffer
+	if err := WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const std = `# HELP test_counter A counter
+# TYPE test_counter counter
+test_counter 3
+# HELP test_gauge A gauge
+# TYPE test_gauge gauge
+test_gauge 1.5
+# HELP test_histogram A histogram
+# TYPE test_histogram histogram
+test_histogram_bucket{route="/\"a\"",le="1"} 0
+test_histogram_bucket{route="/\"a\"",le="2"} 1
+test_histogram_bucket{route="/\"a\"",le="+Inf"} 2
+test_histogram_sum{route="/\"a\""} 4.5
+test_histogram_count{route="/\"a\""} 2
+`
+	AssertEquals(t, buf.String(), std)
+}
@@ -10,7 +10,8 @@ import (
 )
 
 var (
-	linkRegexp = regexp.MustCompile(`^>{2,}(\d+)$`)
+	linkRegexp           = regexp.MustCompile(`^>{2,}(\d+)$`)
+	crossBoardLinkRegexp = regexp.MustCompile(`^>{3,}\/(\w+)\/(\d+)$`)
 )
 
 // Needed to avoid cyclic imports for the 'db' package
@@ -22,6 +23,23 @@ func init() {
 // internal: function was called by automated upkeep task
 func ParseBody(body []byte, board string, thread uint64, id uint64, ip string, internal bool) (
 	links []common.Link, com []common.Command, err error,
+) {
+	return parseBody(body, board, thread, id, ip, internal, false)
+}
+
+// ParseEditedBody parses the body of an edited closed post for commands and
+// links. Hash commands are only recognised and typed, but not executed, as
+// their results are carried over from the original body. Executing them
+// again would roll the #roulette, increment the #pyu count etc.
+func ParseEditedBody(body []byte, board string, thread uint64, id uint64, ip string) (
+	links []common.Link, com []common.Command, err error,
+) {
+	return parseBody(body, board, thread, id, ip, false, true)
+}
+
+// typeOnly: only recognise hash commands without executing them
+func parseBody(body []byte, board string, thread uint64, id uint64, ip string, internal, typeOnly bool) (
+	links []common.Link, com []common.Command, err error,
 ) {
 	err = IsPrintableString(string(body), true)
 	if err != nil {
@@ -48,8 +66,16 @@ func ParseBody(body []byte, board string, thread uint64, id uint64, ip string, i
 	haveLink := make(map[uint64]bool)
 	// Prevent #pyu duplication
 	isSlut := false
+	// Only one #poll per post is allowed. Its options are skipped from
+	// parsing, once the end of the line with the command is reached.
+	havePoll := false
+	pollEnd := 0
+	skip := 0
 
 	for i, b := range body {
+		if i < skip {
+			continue
+		}
 		switch b {
 		case '\n', ' ', '\t':
 		default:
@@ -60,7 +86,7 @@ func ParseBody(body []byte, board string, thread uint64, id uint64, ip string, i
 			}
 		}
 
-		_, word, _ := util.SplitPunctuation(body[start:i])
+		_, word, trail := util.SplitPunctuation(body[start:i])
 		start = i + 1
 		if len(word) == 0 {
 			goto next
@@ -68,12 +94,14 @@ func ParseBody(body []byte, board string, thread uint64, id uint64, ip string, i
 
 		switch word[0] {
 		case '>':
-			m := linkRegexp.FindSubmatch(word)
-			if m == nil {
+			var l common.Link
+			if m := linkRegexp.FindSubmatch(word); m != nil {
+				l, err = parseLink(m)
+			} else if m := crossBoardLinkRegexp.FindSubmatch(word); m != nil {
+				l, err = parseCrossBoardLink(m)
+			} else {
 				goto next
 			}
-			var l common.Link
-			l, err = parseLink(m)
 			switch {
 			case err != nil:
 				return
@@ -84,20 +112,50 @@ func ParseBody(body []byte, board string, thread uint64, id uint64, ip string, i
 				}
 			}
 		case '#':
-			// Ignore hash commands in quotes, or #pyu/#pcount if board option disabled
-			if body[lineStart] == '>' || (len(word) > 1 && word[1] == 'p' && !pyu) {
+			// Ignore hash commands in quotes
+			if body[lineStart] == '>' {
 				goto next
 			}
 			m := common.CommandRegexp.FindSubmatch(word)
+			if trail == '!' {
+				// Exploding dice end with '!', which is otherwise split off
+				// as punctuation
+				ext := common.CommandRegexp.FindSubmatch(word[:len(word)+1])
+				if ext != nil {
+					m = ext
+				}
+			}
 			if m == nil {
 				goto next
 			}
+			switch string(m[1]) {
+			case "pyu", "pcount":
+				// Ignore #pyu/#pcount if board option disabled
+				if !pyu {
+					goto next
+				}
+			case "poll":
+				if havePoll {
+					goto next
+				}
+				c, end, ok := parsePoll(body, i)
+				if ok {
+					havePoll = true
+					pollEnd = end
+					com = append(com, c)
+				}
+				goto next
+			}
 			var c common.Command
-			c, err = parseCommand(m[1], board, thread, id, ip, &isSlut)
+			if typeOnly {
+				c, err = typeCommand(m[1])
+			} else {
+				c, err = parseCommand(m[1], board, thread, id, ip, &isSlut)
+			}
 			switch err {
 			case nil:
 				com = append(com, c)
-			case errTooManyRolls, errDieTooBig:
+			case errTooManyRolls, errDieTooBig, errInvalidDice:
 				// Consider command invalid
 				err = nil
 			default:
@@ -107,6 +165,10 @@ func ParseBody(body []byte, board string, thread uint64, id uint64, ip string, i
 	next:
 		if b == '\n' {
 			lineStart = i + 1
+			if pollEnd != 0 {
+				skip, start = pollEnd, pollEnd
+				pollEnd = 0
+			}
 		}
 	}
 
@@ -32,6 +32,104 @@ func TestParseLine(t *testing.T) {
 	}
 }
 
+func TestParseEditedBody(t *testing.T) {
+	config.SetBoardConfigs(config.BoardConfigs{
+		ID: "a",
+	})
+
+	// Would fail on the missing roulette and pyu rows, if executed
+	_, com, err := ParseEditedBody(
+		[]byte("#flip #roulette #rcount #2d6kh1 #d1000000"),
+		"a",
+		99,
+		1,
+		"::1",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, com, []common.Command{
+		{Type: common.Flip},
+		{Type: common.Roulette},
+		{Type: common.Rcount},
+		{Type: common.Dice, DiceExpr: "2d6kh1"},
+	})
+}
+
+func TestParsePoll(t *testing.T) {
+	config.SetBoardConfigs(config.BoardConfigs{
+		ID: "a",
+	})
+
+	cases := [...]struct {
+		name, in string
+		com      []common.Command
+	}{
+		{
+			name: "valid",
+			in:   "vote #poll\n foo \nbar\n\nbaz",
+			com: []common.Command{
+				{
+					Type: common.Poll,
+					Poll: common.PollState{
+						Options: []string{"foo", "bar"},
+						Votes:   []uint32{0, 0},
+					},
+				},
+			},
+		},
+		{
+			name: "too few options",
+			in:   "#poll\nfoo",
+		},
+		{
+			name: "no options",
+			in:   "#poll",
+		},
+		{
+			name: "quoted",
+			in:   ">#poll\nfoo\nbar",
+		},
+		{
+			name: "commands in options ignored",
+			in:   "#poll\n#flip\nbar\n#poll\nbaz",
+			com: []common.Command{
+				{
+					Type: common.Poll,
+					Poll: common.PollState{
+						Options: []string{"#flip", "bar", "#poll", "baz"},
+						Votes:   []uint32{0, 0, 0, 0},
+					},
+				},
+			},
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			_, com, err := ParseBody([]byte(c.in), "a", 1, 1, "::1", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertEquals(t, com, c.com)
+		})
+	}
+
+	t.Run("only one poll", func(t *testing.T) {
+		_, com, err := ParseBody(
+			[]byte("#poll\nfoo\nbar\n\n#poll\nbaz\nqux\n\n#flip"),
+			"a", 1, 1, "::1", false,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEquals(t, len(com), 2)
+		AssertEquals(t, com[0].Poll.Options, []string{"foo", "bar"})
+		AssertEquals(t, com[1].Type, common.Flip)
+	})
+}
+
 func TestParseBody(t *testing.T) {
 	test_db.ClearTables(t, "boards")
 	writeSampleBoard(t)
@@ -8,6 +8,7 @@ import (
 	"database/sql"
 	"math/big"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,8 +21,9 @@ import (
 var (
 	syncWatchRegexp = regexp.MustCompile(`^sw(\d+:)?(\d+):(\d+)([+-]\d+)?$`)
 
-	errTooManyRolls = common.ErrInvalidInput("too many rolls")
-	errDieTooBig    = common.ErrInvalidInput("die too big")
+	errTooManyRolls = common.ErrTooManyRolls
+	errDieTooBig    = common.ErrDieTooBig
+	errInvalidDice  = common.ErrInvalidDice
 )
 
 // Returns a cryptographically secure pseudorandom int in the interval [0;max)
@@ -182,55 +184,148 @@ func parseCommand(match []byte, board string, thread uint64, id uint64, ip strin
 
 		// Dice throw
 		com.Type = common.Dice
-		com.Dice, err = parseDice(matchStr)
+		com.DiceExpr = matchStr
+		com.Dice, com.DiceRoll, err = parseDice(matchStr)
 	}
 
 	return
 }
 
-func isNumError(err error) bool {
-	_, ok := err.(*strconv.NumError)
-	return ok
+// Determine the type of a matched hash command without executing it. Dice
+// expressions are still validated, so the same commands are recognised as by
+// parseCommand.
+func typeCommand(match []byte) (com common.Command, err error) {
+	switch matchStr := string(match); matchStr {
+	case "flip":
+		com.Type = common.Flip
+	case "8ball":
+		com.Type = common.EightBall
+	case "pyu":
+		com.Type = common.Pyu
+	case "pcount":
+		com.Type = common.Pcount
+	case "roulette":
+		com.Type = common.Roulette
+	case "rcount":
+		com.Type = common.Rcount
+	default:
+		if strings.HasPrefix(matchStr, "sw") {
+			com.Type = common.SyncWatch
+			com.SyncWatch = parseSyncWatch(matchStr)
+			return
+		}
+		com.Type = common.Dice
+		com.DiceExpr = matchStr
+		_, err = common.ParseDice(matchStr)
+	}
+	return
 }
 
-// Parse dice throw commands
-func parseDice(match string) (val []uint16, err error) {
-	dice := common.DiceRegexp.FindStringSubmatch(match)
-
-	var rolls int
-	if len(dice[1]) == 0 {
-		rolls = 1
-	} else {
-		rolls, err = strconv.Atoi(string(dice[1]))
-		switch {
-		case err != nil:
-			if isNumError(err) {
-				err = common.StatusError{err, 400}
+// Parse and roll a dice expression. Plain "NdM" rolls only return the flat
+// array of rolls. Expressions with modifiers or multiple terms also return the
+// breakdown of each term and the total.
+func parseDice(match string) (val []uint16, roll *common.DiceRoll, err error) {
+	expr, err := common.ParseDice(match)
+	if err != nil {
+		return
+	}
+	if expr.IsSimple() {
+		val = rollDie(expr[0].Sides, expr[0].Count)
+		return
+	}
+
+	roll = &common.DiceRoll{
+		Terms: make([]common.DiceTermRoll, len(expr)),
+	}
+	rolled := 0
+	for i, t := range expr {
+		res := common.DiceTermRoll{
+			Negative: t.Negative,
+		}
+		sum := t.Constant
+		if t.Dice {
+			res.Rolls = rollDie(t.Sides, t.Count)
+			rolled += t.Count
+
+			// Exploding dice roll an additional die for each die, that rolled
+			// the maximum value
+			for j := 0; t.Explode && j < len(res.Rolls); j++ {
+				if rolled >= common.MaxDiceRolls {
+					break
+				}
+				if int(res.Rolls[j]) == t.Sides {
+					res.Rolls = append(res.Rolls, rollDie(t.Sides, 1)...)
+					rolled++
+				}
+			}
+
+			res.Dropped = selectDice(res.Rolls, t.Select, t.SelectN)
+			sum = 0
+			for j, r := range res.Rolls {
+				if res.Dropped == nil || !res.Dropped[j] {
+					sum += int(r)
+				}
 			}
-			return
-		case rolls > 10:
-			return nil, errTooManyRolls
+		} else {
+			res.Constant = t.Constant
+		}
+
+		if t.Negative {
+			roll.Total -= sum
+		} else {
+			roll.Total += sum
 		}
+		roll.Terms[i] = res
 	}
+	for _, t := range roll.Terms {
+		val = append(val, t.Rolls...)
+	}
+	return
+}
 
-	max, err := strconv.Atoi(string(dice[2]))
-	switch {
-	case err != nil:
-		if isNumError(err) {
-			err = common.StatusError{err, 400}
+// Roll n dice with the specified number of sides
+func rollDie(sides, n int) []uint16 {
+	val := make([]uint16, n)
+	for i := range val {
+		if sides != 0 {
+			val[i] = uint16(randInt(sides)) + 1
 		}
+	}
+	return val
+}
+
+// Mark the rolls not counted towards the total according to a keep or drop
+// modifier. Returns nil, if all rolls are counted.
+func selectDice(rolls []uint16, sel common.DiceSelection, n int,
+) (dropped []bool) {
+	if sel == common.KeepAll {
 		return
-	case max > common.MaxDiceSides:
-		return nil, errDieTooBig
 	}
 
-	val = make([]uint16, rolls)
-	for i := 0; i < rolls; i++ {
-		if max != 0 {
-			val[i] = uint16(randInt(max)) + 1
-		} else {
-			val[i] = 0
-		}
+	// Sort indices of rolls from highest to lowest
+	order := make([]int, len(rolls))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return rolls[order[i]] > rolls[order[j]]
+	})
+
+	// Convert to the set of rolls to drop
+	switch sel {
+	case common.KeepHighest:
+		order = order[n:]
+	case common.KeepLowest:
+		order = order[:len(order)-n]
+	case common.DropHighest:
+		order = order[:n]
+	case common.DropLowest:
+		order = order[len(order)-n:]
+	}
+
+	dropped = make([]bool, len(rolls))
+	for _, i := range order {
+		dropped[i] = true
 	}
 	return
 }
@@ -269,3 +364,39 @@ func parseSyncWatch(match string) [5]uint64 {
 		end,
 	}
 }
+
+// Parse the options of a #poll from the lines following the command ending at
+// i. The options end at the first empty line. Returns the index of the end of
+// the last option and ok = false, if the poll does not have at least 2
+// options.
+func parsePoll(body []byte, i int) (com common.Command, end int, ok bool) {
+	j := bytes.IndexByte(body[i:], '\n')
+	if j == -1 {
+		return
+	}
+
+	com.Type = common.Poll
+	com.Poll.Options = make([]string, 0, 4)
+	pos := i + j + 1
+	for pos < len(body) && len(com.Poll.Options) < common.MaxPollOptions {
+		lineEnd := bytes.IndexByte(body[pos:], '\n')
+		if lineEnd == -1 {
+			lineEnd = len(body)
+		} else {
+			lineEnd += pos
+		}
+		opt := bytes.TrimSpace(body[pos:lineEnd])
+		if len(opt) == 0 {
+			break
+		}
+		com.Poll.Options = append(com.Poll.Options, string(opt))
+		end = lineEnd
+		pos = lineEnd + 1
+	}
+	if len(com.Poll.Options) < 2 {
+		return
+	}
+	com.Poll.Votes = make([]uint32, len(com.Poll.Options))
+	ok = true
+	return
+}
@@ -37,6 +37,9 @@ func TestDice(t *testing.T) {
 		{"too many dice", `11d100`, errTooManyRolls, 0, 0},
 		{"valid single die", `d10`, nil, 1, 10},
 		{"valid multiple dice", `10d100`, nil, 10, 100},
+		{"keep more than rolled", `2d20kh3`, errInvalidDice, 0, 0},
+		{"keep highest with modifier", `2d20kh1+5`, nil, 2, 20},
+		{"multiple terms", `4d6dl1-d4+2`, nil, 5, 6},
 	}
 	for i := range cases {
 		c := cases[i]
@@ -56,6 +59,37 @@ func TestDice(t *testing.T) {
 	}
 }
 
+func TestExtendedDice(t *testing.T) {
+	t.Parallel()
+
+	val, roll, err := parseDice("4d6dl1-d4+2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roll == nil {
+		t.Fatal("no dice roll breakdown")
+	}
+	AssertEquals(t, len(roll.Terms), 3)
+	AssertEquals(t, len(val), 5)
+
+	kept, lowest := 0, 6
+	for i, r := range roll.Terms[0].Rolls {
+		if !roll.Terms[0].Dropped[i] {
+			kept++
+		}
+		if int(r) < lowest {
+			lowest = int(r)
+		}
+	}
+	AssertEquals(t, kept, 3)
+
+	total := -lowest - int(roll.Terms[1].Rolls[0]) + 2
+	for _, r := range roll.Terms[0].Rolls {
+		total += int(r)
+	}
+	AssertEquals(t, roll.Total, total)
+}
+
 func Test8ball(t *testing.T) {
 	var isSlut bool
 	answers := []string{"Yes", "No"}
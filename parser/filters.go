@@ -0,0 +1,124 @@
+// Board-specific word filters
+
+package parser
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+)
+
+var (
+	// ErrWordFiltered is returned, when text matches a rejecting word filter
+	ErrWordFiltered = common.ErrInvalidInput("rejected by word filter")
+
+	// Compiled patterns of word filters
+	filterRegexps sync.Map
+)
+
+func init() {
+	common.CensorText = CensorText
+}
+
+// Key of a compiled word filter pattern
+type filterKey struct {
+	regex   bool
+	pattern string
+}
+
+// CompileWordFilter compiles the pattern of a word filter. Literal patterns
+// match case-insensitively.
+func CompileWordFilter(f config.WordFilter) (*regexp.Regexp, error) {
+	key := filterKey{f.Regex, f.Pattern}
+	if r, ok := filterRegexps.Load(key); ok {
+		return r.(*regexp.Regexp), nil
+	}
+
+	var (
+		r   *regexp.Regexp
+		err error
+	)
+	if f.Regex {
+		r, err = regexp.Compile(f.Pattern)
+	} else {
+		r, err = regexp.Compile("(?i)" + regexp.QuoteMeta(f.Pattern))
+	}
+	if err != nil {
+		return nil, err
+	}
+	filterRegexps.Store(key, r)
+	return r, nil
+}
+
+// FilterText applies the word filters of board with scope to text in order.
+// Returns the text with all replacements made and, if it should be flagged
+// for review. If a rejecting filter matched, ErrWordFiltered is returned
+// together with the filtered text.
+func FilterText(board, scope, text string) (
+	res string, flag bool, err error,
+) {
+	res, flag, rejected := filterText(board, scope, text, false)
+	if rejected {
+		err = ErrWordFiltered
+	}
+	return
+}
+
+// CensorText is like FilterText, but removes any text matching rejecting
+// filters instead of failing. Used for posts, that can no longer be rejected,
+// like open posts being closed.
+func CensorText(board, scope, text string) (res string, flag bool) {
+	res, flag, _ = filterText(board, scope, text, true)
+	return
+}
+
+func filterText(board, scope, text string, censor bool) (
+	res string, flag, rejected bool,
+) {
+	res = text
+	for _, f := range config.GetBoardConfigs(board).WordFilters {
+		if f.Scope != scope {
+			continue
+		}
+		r, compErr := CompileWordFilter(f)
+		if compErr != nil {
+			// Invalid patterns are rejected on configuration. Should not
+			// happen.
+			continue
+		}
+
+		switch f.Action {
+		case config.FilterReplace:
+			res = r.ReplaceAllLiteralString(res, f.Replacement)
+		case config.FilterReject:
+			if r.MatchString(res) {
+				rejected = true
+				if censor {
+					res = r.ReplaceAllLiteralString(res, "")
+				}
+			}
+		case config.FilterFlag:
+			if r.MatchString(res) {
+				flag = true
+			}
+		}
+	}
+	return
+}
+
+// CheckWordFilters returns ErrWordFiltered, if text matches any of the board's
+// rejecting word filters with scope
+func CheckWordFilters(board, scope, text string) error {
+	for _, f := range config.GetBoardConfigs(board).WordFilters {
+		if f.Scope != scope || f.Action != config.FilterReject {
+			continue
+		}
+		r, err := CompileWordFilter(f)
+		if err == nil && r.MatchString(text) {
+			return ErrWordFiltered
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/bakape/meguca/config"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestFilterText(t *testing.T) {
+	config.SetBoardConfigs(config.BoardConfigs{
+		ID: "f",
+		WordFilters: []config.WordFilter{
+			{
+				Pattern:     "tbh",
+				Scope:       config.FilterBody,
+				Action:      config.FilterReplace,
+				Replacement: "desu",
+			},
+			{
+				Regex:       true,
+				Pattern:     `\bcat+\b`,
+				Scope:       config.FilterBody,
+				Action:      config.FilterReplace,
+				Replacement: "dog",
+			},
+			{
+				Pattern: "spam",
+				Scope:   config.FilterBody,
+				Action:  config.FilterReject,
+			},
+			{
+				Regex:   true,
+				Pattern: `^admin$`,
+				Scope:   config.FilterName,
+				Action:  config.FilterFlag,
+			},
+		},
+	})
+
+	cases := [...]struct {
+		name, scope, in, out string
+		flag                 bool
+		err                  error
+	}{
+		{
+			name:  "no match",
+			scope: config.FilterBody,
+			in:    "foo bar",
+			out:   "foo bar",
+		},
+		{
+			name:  "literal replacement",
+			scope: config.FilterBody,
+			in:    "TBH foo tbh",
+			out:   "desu foo desu",
+		},
+		{
+			name:  "regex replacement",
+			scope: config.FilterBody,
+			in:    "catttt category",
+			out:   "dog category",
+		},
+		{
+			name:  "reject",
+			scope: config.FilterBody,
+			in:    "buy Spam tbh",
+			out:   "buy Spam desu",
+			err:   ErrWordFiltered,
+		},
+		{
+			name:  "other scope",
+			scope: config.FilterSubject,
+			in:    "tbh spam",
+			out:   "tbh spam",
+		},
+		{
+			name:  "flag",
+			scope: config.FilterName,
+			in:    "admin",
+			out:   "admin",
+			flag:  true,
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			out, flag, err := FilterText("f", c.scope, c.in)
+			if err != c.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			AssertEquals(t, out, c.out)
+			AssertEquals(t, flag, c.flag)
+		})
+	}
+
+	t.Run("censor", func(t *testing.T) {
+		t.Parallel()
+
+		out, flag := CensorText("f", config.FilterBody, "buy Spam tbh")
+		AssertEquals(t, out, "buy  desu")
+		AssertEquals(t, flag, false)
+	})
+
+	t.Run("check rejects", func(t *testing.T) {
+		t.Parallel()
+
+		err := CheckWordFilters("f", config.FilterBody, "tbh cat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = CheckWordFilters("f", config.FilterBody, "more SPAM")
+		if err != ErrWordFiltered {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
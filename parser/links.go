@@ -29,3 +29,13 @@ func parseLink(match [][]byte) (link common.Link, err error) {
 	}
 	return
 }
+
+// Extract a link to a post on a specific board from a text fragment and
+// verify the post is on this board
+func parseCrossBoardLink(match [][]byte) (link common.Link, err error) {
+	link, err = parseLink(match[1:])
+	if err == nil && link.Board != string(match[1]) {
+		link = common.Link{}
+	}
+	return
+}
@@ -66,6 +66,15 @@ func TestParseLinks(t *testing.T) {
 			},
 		},
 		{"all links invalid", " >>88 >>2 >>33", nil},
+		{
+			"cross-board links",
+			">>>/a/6 >>>>/a/8 >>>/a/88",
+			[]common.Link{
+				{6, 1, "a"},
+				{8, 1, "a"},
+			},
+		},
+		{"cross-board link to wrong board", ">>>/b/6 >>>/a/", nil},
 	}
 
 	for i := range cases {
@@ -15,13 +15,16 @@ import (
 	"github.com/bakape/meguca/common"
 	"github.com/bakape/meguca/config"
 	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/parser"
 	"github.com/bakape/meguca/templates"
 	"github.com/bakape/meguca/websockets/feeds"
 )
 
 const (
-	maxAnswers      = 100  // Maximum number of eightball answers
-	maxEightballLen = 2000 // Total chars in eightball
+	maxAnswers       = 100  // Maximum number of eightball answers
+	maxEightballLen  = 2000 // Total chars in eightball
+	maxWordFilters   = 100  // Maximum number of word filters
+	maxLenWordFilter = 200  // Maximum length of word filter patterns
 )
 
 var (
@@ -31,6 +34,9 @@ var (
 	errRulesTooLong     = common.ErrTooLong("rules")
 	errReasonTooLong    = common.ErrTooLong("reason")
 	errTooManyAnswers   = common.ErrInvalidInput("too many eightball answers")
+	errTooManyFilters   = common.ErrInvalidInput("too many word filters")
+	errTooManyFiles     = common.ErrInvalidInput("too many files per post")
+	errInvalidFilter    = common.ErrInvalidInput("invalid word filter")
 	errInvalidBoardName = common.ErrInvalidInput("invalid board name")
 	errBoardNameTaken   = common.ErrInvalidInput("board name taken")
 	errNoReason         = common.ErrInvalidInput("no reason provided")
@@ -53,6 +59,10 @@ type banRequest struct {
 	Duration uint64
 	Reason   string
 	IDs      []uint64 `json:"ids"`
+	// Network prefix lengths of the CIDR ranges to ban. Zero bans only the
+	// exact IP.
+	IPv4Prefix int `json:"ipv4Prefix"`
+	IPv6Prefix int `json:"ipv6Prefix"`
 }
 
 // Set board-specific configurations to the user's owned board
@@ -151,10 +161,20 @@ func validateBoardConfigs(w http.ResponseWriter, conf config.BoardConfigs,
 		err = errRulesTooLong
 	case len(conf.Title) > common.MaxLenBoardTitle:
 		err = errTitleTooLong
+	case len(conf.WordFilters) > maxWordFilters:
+		err = errTooManyFilters
+	case conf.MaxFiles > common.MaxNumFiles:
+		err = errTooManyFiles
 	}
 	if err != nil {
 		return
 	}
+	for _, f := range conf.WordFilters {
+		err = validateWordFilter(f)
+		if err != nil {
+			return
+		}
+	}
 
 	matched := false
 	for _, t := range common.Themes {
@@ -169,6 +189,37 @@ func validateBoardConfigs(w http.ResponseWriter, conf config.BoardConfigs,
 	return
 }
 
+// Validate a word filter of a board and ensure its pattern compiles
+func validateWordFilter(f config.WordFilter) error {
+	validScope := false
+	for _, s := range config.FilterScopes {
+		if f.Scope == s {
+			validScope = true
+			break
+		}
+	}
+	validAction := false
+	for _, a := range config.FilterActions {
+		if f.Action == a {
+			validAction = true
+			break
+		}
+	}
+	switch {
+	case !validScope, !validAction, f.Pattern == "",
+		len(f.Pattern) > maxLenWordFilter,
+		len(f.Replacement) > maxLenWordFilter:
+		return errInvalidFilter
+	}
+
+	_, err := parser.CompileWordFilter(f)
+	if err != nil {
+		return common.ErrInvalidInput(
+			fmt.Sprintf("invalid word filter pattern: %s", err))
+	}
+	return nil
+}
+
 // Serve the current board configurations to the client, including publically
 // unexposed ones. Intended to be used before setting the the configs with
 // configureBoard().
@@ -523,8 +574,8 @@ func ban(w http.ResponseWriter, r *http.Request) {
 			if bantime > day7 {
 				bantime = day7
 			}
-			err = db.Ban(board, msg.Reason, creds.UserID,
-				bantime, id)
+			err = db.BanRange(board, msg.Reason, creds.UserID,
+				bantime, id, msg.IPv4Prefix, msg.IPv6Prefix)
 			//err = db.DeletePostsByIP(id, creds.UserID,
 			//	time.Duration(msg.Duration)*time.Minute, msg.Reason)
 
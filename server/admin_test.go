@@ -193,6 +193,47 @@ func TestValidateBoardConfigs(t *testing.T) {
 			},
 			errTitleTooLong,
 		},
+		{
+			"too many word filters",
+			config.BoardConfigs{
+				WordFilters: make([]config.WordFilter, maxWordFilters+1),
+			},
+			errTooManyFilters,
+		},
+		{
+			"too many files per post",
+			config.BoardConfigs{
+				BoardPublic: config.BoardPublic{
+					MaxFiles: common.MaxNumFiles + 1,
+				},
+			},
+			errTooManyFiles,
+		},
+		{
+			"word filter with invalid action",
+			config.BoardConfigs{
+				WordFilters: []config.WordFilter{
+					{
+						Pattern: "foo",
+						Scope:   config.FilterBody,
+						Action:  "delete",
+					},
+				},
+			},
+			errInvalidFilter,
+		},
+		{
+			"word filter without pattern",
+			config.BoardConfigs{
+				WordFilters: []config.WordFilter{
+					{
+						Scope:  config.FilterName,
+						Action: config.FilterReject,
+					},
+				},
+			},
+			errInvalidFilter,
+		},
 	}
 
 	for i := range cases {
@@ -209,6 +250,28 @@ func TestValidateBoardConfigs(t *testing.T) {
 	}
 }
 
+func TestValidateWordFilterRegex(t *testing.T) {
+	t.Parallel()
+
+	f := config.WordFilter{
+		Regex:   true,
+		Pattern: "(foo",
+		Scope:   config.FilterBody,
+		Action:  config.FilterReplace,
+	}
+	err := validateWordFilter(f)
+	if err == nil {
+		t.Fatal("invalid regex accepted")
+	}
+	test.AssertEquals(t, err.(common.StatusError).Code, 400)
+
+	f.Regex = false
+	err = validateWordFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func disableCaptcha() {
 	conf := *config.Get()
 	conf.Captcha = false
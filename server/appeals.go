@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/lang"
+	"github.com/bakape/meguca/templates"
+)
+
+var (
+	errAppealTooLong     = common.ErrTooLong("appeal")
+	errNoAppeal          = common.ErrInvalidInput("no appeal provided")
+	errAppealNoteTooLong = common.ErrTooLong("note")
+	errNoDecision        = common.ErrInvalidInput("no decision")
+)
+
+// Appeal a ban from a board submitted from the ban page
+func appeal(w http.ResponseWriter, r *http.Request) {
+	err := func() (err error) {
+		r.Body = http.MaxBytesReader(w, r.Body, jsonLimit)
+		err = r.ParseForm()
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		f := r.Form
+
+		board := f.Get("board")
+		if !auth.IsBoard(board) {
+			return errInvalidBoardName
+		}
+		body := f.Get("body")
+		switch {
+		case strings.TrimSpace(body) == "":
+			return errNoAppeal
+		case len(body) > common.MaxLenAppeal:
+			return errAppealTooLong
+		}
+
+		ip, err := auth.GetIP(r)
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		var session auth.Base64Token
+		err = session.EnsureCookie(w, r)
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		has, err := db.SolvedCaptchaRecently(session, time.Minute)
+		if err != nil {
+			return
+		}
+		if !has {
+			return errInvalidCaptcha
+		}
+
+		err = db.AppealBan(board, ip, body)
+		if err != nil {
+			return
+		}
+		setHTMLHeaders(w)
+		w.Write([]byte(lang.Get().UI["appealSubmitted"]))
+		return
+	}()
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
+
+// Render a list of pending ban appeals for the board
+func appealList(w http.ResponseWriter, r *http.Request) {
+	board := extractParam(r, "board")
+	_, err := canPerform(w, r, board, common.Moderator, false)
+	if err != nil {
+		httpError(w, r, err)
+		return
+	}
+
+	appeals, err := db.GetAppeals(board)
+	if err != nil {
+		httpError(w, r, err)
+		return
+	}
+	setHTMLHeaders(w)
+	templates.WriteAppealList(w, appeals, board)
+}
+
+// Accept or deny a ban appeal
+func decideAppeal(w http.ResponseWriter, r *http.Request) {
+	err := func() (err error) {
+		board := extractParam(r, "board")
+		creds, err := canPerform(w, r, board, common.Moderator, false)
+		if err != nil {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, jsonLimit)
+		err = r.ParseForm()
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		f := r.Form
+
+		id, err := strconv.ParseUint(f.Get("id"), 10, 64)
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		note := f.Get("note")
+		if len(note) > common.MaxLenAppealNote {
+			return errAppealNoteTooLong
+		}
+		var accept bool
+		switch {
+		case f.Get("accept") != "":
+			accept = true
+		case f.Get("deny") != "":
+		default:
+			return errNoDecision
+		}
+
+		err = db.DecideAppeal(id, board, accept, note, creds.UserID)
+		if err != nil {
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/html/appeals/%s", board), 303)
+		return
+	}()
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
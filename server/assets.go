@@ -3,6 +3,8 @@ package server
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -13,6 +15,7 @@ import (
 	"github.com/bakape/meguca/assets"
 	"github.com/bakape/meguca/common"
 	"github.com/bakape/meguca/db"
+	imgassets "github.com/bakape/meguca/imager/assets"
 	"github.com/bakape/thumbnailer/v2"
 )
 
@@ -23,9 +26,6 @@ var (
 		// it is a year or greater, so keep it a little below.
 		"Cache-Control": "max-age=30240000, public, immutable",
 	}
-
-	// For overriding during tests
-	imageWebRoot = "images"
 )
 
 type fileError struct {
@@ -47,9 +47,18 @@ func newFileError(h *multipart.FileHeader, msg string) error {
 // (except deletion), so we can also set separate caching policies for them.
 func serveImages(w http.ResponseWriter, r *http.Request) {
 	path := extractParam(r, "path")
-	file, err := os.Open(cleanJoin(imageWebRoot, path))
+	if url, ok := imgassets.RedirectURL(path); ok {
+		http.Redirect(w, r, url, 302)
+		return
+	}
+
+	file, err := imgassets.Open(path)
 	if err != nil {
-		text404(w)
+		if os.IsNotExist(err) {
+			text404(w)
+		} else {
+			httpError(w, r, err)
+		}
 		return
 	}
 	defer file.Close()
@@ -59,7 +68,18 @@ func serveImages(w http.ResponseWriter, r *http.Request) {
 		head.Set(key, val)
 	}
 
-	http.ServeContent(w, r, path, time.Time{}, file)
+	// Local files support range requests. Proxy everything else as is.
+	if rs, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, path, time.Time{}, rs)
+		return
+	}
+	if typ := mime.TypeByExtension(filepath.Ext(path)); typ != "" {
+		head.Set("Content-Type", typ)
+	}
+	_, err = io.Copy(w, file)
+	if err != nil {
+		logError(r, err)
+	}
 }
 
 func cleanJoin(a, b string) string {
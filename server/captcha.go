@@ -22,11 +22,21 @@ func (e errCaptchasNotReady) Error() string {
 
 // Authenticate a captcha solution
 func authenticateCaptcha(w http.ResponseWriter, r *http.Request) {
+	b := extractParam(r, "board")
+	if !assertNotBanned(w, r, b) || !assertNotBanned(w, r, "all") {
+		return
+	}
+	validateCaptcha(w, r, b)
+}
+
+// Authenticate a captcha solution for appealing a ban. Unlike other captchas,
+// these can be solved by banned clients.
+func authenticateAppealCaptcha(w http.ResponseWriter, r *http.Request) {
+	validateCaptcha(w, r, extractParam(r, "board"))
+}
+
+func validateCaptcha(w http.ResponseWriter, r *http.Request, b string) {
 	err := func() (err error) {
-		b := extractParam(r, "board")
-		if !assertNotBanned(w, r, b) || !assertNotBanned(w, r, "all") {
-			return
-		}
 		err = r.ParseForm()
 		if err != nil {
 			return common.StatusError{err, 400}
@@ -69,12 +79,20 @@ func authenticateCaptcha(w http.ResponseWriter, r *http.Request) {
 // Create new captcha and write its HTML to w. Colour and background can be left
 // blank to use defaults.
 func serveNewCaptcha(w http.ResponseWriter, r *http.Request) {
-	httpError(w, r, func() (err error) {
-		b := extractParam(r, "board")
-		if !assertNotBanned(w, r, b) || !assertNotBanned(w, r, "all") {
-			return
-		}
+	b := extractParam(r, "board")
+	if !assertNotBanned(w, r, b) || !assertNotBanned(w, r, "all") {
+		return
+	}
+	writeNewCaptcha(w, r, b)
+}
+
+// Create new captcha for appealing a ban on a board
+func serveNewAppealCaptcha(w http.ResponseWriter, r *http.Request) {
+	writeNewCaptcha(w, r, extractParam(r, "board"))
+}
 
+func writeNewCaptcha(w http.ResponseWriter, r *http.Request, b string) {
+	httpError(w, r, func() (err error) {
 		ip, err := auth.GetIP(r)
 		if err != nil {
 			return
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/cache"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+)
+
+// Serve an Atom feed of a board's newest threads
+func boardFeed(w http.ResponseWriter, r *http.Request, b string) {
+	if !auth.IsBoard(b) {
+		text404(w)
+		return
+	}
+	serveFeed(w, r, b, cache.FeedKey(b, 0))
+}
+
+// Serve an Atom feed of a thread's newest replies
+func threadFeed(w http.ResponseWriter, r *http.Request) {
+	id, ok := validateThread(w, r)
+	if !ok {
+		return
+	}
+	b := extractParam(r, "board")
+	serveFeed(w, r, b, cache.FeedKey(b, id))
+}
+
+// Retrieve a feed from the cache and write it to the client
+func serveFeed(w http.ResponseWriter, r *http.Request, b string, k cache.Key) {
+	buf, _, ctr, err := cache.GetHTML(k, cache.FeedFE)
+	if err != nil {
+		httpError(w, r, err)
+		return
+	}
+
+	_, hash := config.GetClient()
+	etag := formatEtag(ctr, hash, common.NotLoggedIn)
+	if checkClientEtag(w, r, etag) {
+		return
+	}
+
+	head := w.Header()
+	for key, val := range vanillaHeaders {
+		head.Set(key, val)
+	}
+	head.Set("ETag", etag)
+	head.Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if !feedIndexable(b) {
+		head.Set("X-Robots-Tag", "noindex, nofollow")
+	}
+
+	writeData(w, r, buf)
+}
+
+// Returns, if search engines may index a board's feeds. Mirrors robots.txt and
+// additionally excludes NSFW boards.
+func feedIndexable(b string) bool {
+	switch {
+	case config.Get().GlobalDisableRobots:
+		return false
+	case b == "all":
+		return false
+	case b == "b":
+		return true
+	}
+	conf := config.GetBoardConfigs(b)
+	return !conf.DisableRobots && !conf.NSFW
+}
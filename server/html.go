@@ -1,7 +1,9 @@
 package server
 
 import (
+	"database/sql"
 	"net/http"
+	"strconv"
 
 	"github.com/bakape/meguca/auth"
 	"github.com/bakape/meguca/cache"
@@ -120,12 +122,48 @@ func threadHTML(w http.ResponseWriter, r *http.Request) {
 		w,
 		id,
 		b, thread.Subject, theme,
-		lastN != 0, thread.Locked,
+		lastN != 0, thread.Locked, thread.Archived,
 		pos,
 		html,
 	)
 }
 
+// Render the archived thread listing of a board
+func archiveHTML(w http.ResponseWriter, r *http.Request, b string) {
+	if !auth.IsBoard(b) {
+		text404(w)
+		return
+	}
+	pos, ok := extractPosition(w, r)
+	if !ok {
+		return
+	}
+
+	page := archivePage(r)
+	archive, err := db.GetArchive(b, page)
+	switch {
+	case err != nil:
+		httpError(w, r, err)
+		return
+	case page != 0 && page >= archive.Pages:
+		text404(w)
+		return
+	}
+
+	setHTMLHeaders(w)
+	templates.Archive(w, b, resolveTheme(r, b), pos, archive.Threads, page,
+		archive.Pages)
+}
+
+// Returns the requested page of a board's archive. Defaults to the first page.
+func archivePage(r *http.Request) int {
+	p, err := strconv.ParseUint(r.URL.Query().Get("page"), 10, 31)
+	if err != nil {
+		return 0
+	}
+	return int(p)
+}
+
 // Extract logged in position for HTML request.
 // If ok == false, caller should return.
 func extractPosition(w http.ResponseWriter, r *http.Request) (
@@ -260,3 +298,64 @@ func loadingAnimationForm(w http.ResponseWriter, r *http.Request) {
 	setHTMLHeaders(w)
 	templates.WriteLoadingAnimationForm(w)
 }
+
+// Retrieve a post and its edit history. The history of deleted or purged posts
+// is only visible to the board's staff and otherwise treated as missing.
+func getPostHistory(r *http.Request, id uint64) (
+	post common.StandalonePost, revs []common.PostRevision, err error,
+) {
+	post, err = db.GetPost(id)
+	if err != nil {
+		return
+	}
+	if post.IsDeleted() || post.IsPurged() {
+		var can bool
+		can, err = isBoardStaff(r, post.Board)
+		switch {
+		case err != nil:
+			return
+		case !can:
+			err = sql.ErrNoRows
+			return
+		}
+	}
+	revs, err = db.GetPostRevisions(id)
+	return
+}
+
+// Returns, if the client is logged in as staff of the board
+func isBoardStaff(r *http.Request, board string) (bool, error) {
+	creds := extractLoginCreds(r)
+	if creds.UserID == "" {
+		return false, nil
+	}
+	loggedIn, err := db.IsLoggedIn(creds.UserID, creds.Session)
+	switch {
+	case err == common.ErrInvalidCreds:
+		return false, nil
+	case err != nil || !loggedIn:
+		return false, err
+	}
+	return db.CanPerform(creds.UserID, board, common.Janitor)
+}
+
+// Render the edit history of a post
+func postHistory(w http.ResponseWriter, r *http.Request) {
+	err := func() (err error) {
+		id, err := strconv.ParseUint(extractParam(r, "post"), 10, 64)
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		post, revs, err := getPostHistory(r, id)
+		if err != nil {
+			return
+		}
+
+		setHTMLHeaders(w)
+		templates.WritePostHistory(w, post, revs)
+		return
+	}()
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
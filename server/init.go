@@ -53,7 +53,7 @@ func Start() (err error) {
 	mlog.Init(mlog.Console)
 	mlog.ConsoleHandler.SetDisplayColor(config.Server.Debug)
 
-	err = util.Parallel(db.LoadDB, assets.CreateDirs)
+	err = util.Parallel(db.LoadDB, assets.Init)
 	if err != nil {
 		return
 	}
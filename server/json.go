@@ -99,6 +99,22 @@ func servePost(w http.ResponseWriter, r *http.Request) {
 	serveJSON(w, r, "", post)
 }
 
+// Serve the previous bodies of an edited post as JSON
+func servePostHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(extractParam(r, "post"), 10, 64)
+	if err != nil {
+		httpError(w, r, common.StatusError{err, 400})
+		return
+	}
+
+	_, revs, err := getPostHistory(r, id)
+	if err != nil {
+		httpError(w, r, err)
+		return
+	}
+	serveJSON(w, r, "", revs)
+}
+
 // Serve board-specific configuration JSON
 func serveBoardConfigs(
 	w http.ResponseWriter,
@@ -185,6 +201,26 @@ func boardJSON(w http.ResponseWriter, r *http.Request, catalog bool, catalogMode
 	}
 }
 
+// Serves the archived thread listing of a board as JSON
+func archiveJSON(w http.ResponseWriter, r *http.Request) {
+	b := extractParam(r, "board")
+	if !auth.IsBoard(b) {
+		text404(w)
+		return
+	}
+
+	page := archivePage(r)
+	archive, err := db.GetArchive(b, page)
+	switch {
+	case err != nil:
+		httpError(w, r, err)
+	case page != 0 && page >= archive.Pages:
+		text404(w)
+	default:
+		serveJSON(w, r, "", archive)
+	}
+}
+
 // Serve a JSON array of all available boards and their titles
 func serveBoardList(res http.ResponseWriter, req *http.Request) {
 	serveJSON(res, req, "", config.GetBoardTitles())
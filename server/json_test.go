@@ -128,6 +128,59 @@ func TestPostJSON(t *testing.T) {
 	}
 }
 
+func TestPostHistory(t *testing.T) {
+	test_db.ClearTables(t, "accounts", "boards")
+	writeSampleBoard(t)
+	writeSampleThread(t)
+	writeSampleUser(t)
+	writeSampleBoardOwner(t)
+	writeExtraSampleBoard(t)
+
+	for _, id := range [...]uint64{1, 2, 4} {
+		if _, err := db.EditPost(id, "edited", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.DeletePosts([]uint64{2}, "user1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PurgePost(4, "user1", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := [...]struct {
+		name  string
+		id    string
+		staff bool
+		code  int
+	}{
+		{"visible", "1", false, 200},
+		{"deleted", "2", false, 404},
+		{"deleted as staff", "2", true, 200},
+		{"purged", "4", false, 404},
+		{"purged as staff", "4", true, 200},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, url := range [...]string{
+				"/json/post/" + c.id + "/history",
+				"/html/post-history/" + c.id,
+			} {
+				rec, req := newPair(url)
+				if c.staff {
+					setLoginCookies(req, sampleLoginCreds)
+				}
+				router.ServeHTTP(rec, req)
+				assertCode(t, rec, c.code)
+			}
+		})
+	}
+}
+
 // Setup the database for testing post-related paths
 func setupPosts(t *testing.T) {
 	t.Helper()
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/metrics"
+	"github.com/dimfeld/httptreemux"
+)
+
+var requestLatency = metrics.NewHistogramVec(metrics.DefaultBuckets,
+	"method", "route")
+
+func init() {
+	metrics.Register("meguca_http_request_duration_seconds",
+		"Time taken to serve HTTP requests by route", requestLatency)
+}
+
+// Router group, that records the latency of all requests to its routes
+type routeGroup struct {
+	*httptreemux.ContextGroup
+	path string
+}
+
+// NewGroup creates a child group under path
+func (g routeGroup) NewGroup(path string) routeGroup {
+	return routeGroup{
+		ContextGroup: g.ContextGroup.NewGroup(path),
+		path:         g.path + path,
+	}
+}
+
+// GET registers a handler for GET requests on path
+func (g routeGroup) GET(path string, h http.HandlerFunc) {
+	g.Handle("GET", path, h)
+}
+
+// POST registers a handler for POST requests on path
+func (g routeGroup) POST(path string, h http.HandlerFunc) {
+	g.Handle("POST", path, h)
+}
+
+// Handle registers a handler for method requests on path
+func (g routeGroup) Handle(method, path string, h http.HandlerFunc) {
+	hist := requestLatency.With(method, g.path+path)
+	g.ContextGroup.Handle(method, path,
+		func(w http.ResponseWriter, r *http.Request) {
+			defer hist.ObserveSince(time.Now())
+			h(w, r)
+		})
+}
+
+// Serve server internals in the Prometheus text exposition format
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	ip, err := auth.GetIP(r)
+	if err != nil || !metricsAllowed(ip) {
+		http.Error(w, "403 forbidden", 403)
+		return
+	}
+
+	head := w.Header()
+	for key, val := range vanillaHeaders {
+		head.Set(key, val)
+	}
+	head.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	err = metrics.WriteTo(w)
+	if err != nil {
+		logError(r, err)
+	}
+}
+
+// Returns, if ip matches any address or CIDR range in the configured metrics
+// allow-list
+func metricsAllowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, a := range config.Server.Server.MetricsAllow {
+		if strings.ContainsRune(a, '/') {
+			_, n, err := net.ParseCIDR(a)
+			if err == nil && n.Contains(parsed) {
+				return true
+			}
+		} else if allowed := net.ParseIP(a); allowed != nil &&
+			allowed.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
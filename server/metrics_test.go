@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/bakape/meguca/config"
+)
+
+func TestMetricsAllowed(t *testing.T) {
+	config.Server.Server.MetricsAllow = []string{"127.0.0.1", "10.0.0.0/8"}
+
+	cases := [...]struct {
+		name, ip string
+		allowed  bool
+	}{
+		{"exact match", "127.0.0.1", true},
+		{"CIDR match", "10.1.2.3", true},
+		{"no match", "192.168.1.1", false},
+		{"invalid IP", "foo", false},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			if metricsAllowed(c.ip) != c.allowed {
+				t.Fatalf("unexpected result for %s", c.ip)
+			}
+		})
+	}
+}
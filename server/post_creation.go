@@ -184,6 +184,44 @@ func createReply(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Replace the body of a closed post with an edited version
+func editPost(w http.ResponseWriter, r *http.Request) {
+	err := func() (err error) {
+		var req websockets.PostEditRequest
+		err = decodeJSON(r, &req)
+		if err != nil {
+			return
+		}
+		ip, err := auth.GetIP(r)
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		return websockets.EditPost(req, ip)
+	}()
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
+
+// Delete one of the poster's own closed posts or its image
+func selfDeletePost(w http.ResponseWriter, r *http.Request) {
+	err := func() (err error) {
+		var req websockets.SelfDeleteRequest
+		err = decodeJSON(r, &req)
+		if err != nil {
+			return
+		}
+		ip, err := auth.GetIP(r)
+		if err != nil {
+			return common.StatusError{err, 400}
+		}
+		return websockets.SelfDeletePost(req, ip)
+	}()
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
+
 func incrementSpamscore(ip, body string, session auth.Base64Token, isOP bool) {
 	conf := config.Get()
 	s := conf.CharScore * uint(utf8.RuneCountInString(body))
@@ -46,15 +46,19 @@ func startWebServer() (err error) {
 	fmt.Fprintf(&w, "://%s", prettyAddr)
 	log.Info(w.String())
 
-	gracehttp.PreStartProcess(db.Close)
-	err = gracehttp.Serve(&http.Server{
-		Addr:    c.Address,
-		Handler: createRouter(),
-	})
+	err = gracehttp.ServeWithOptions(
+		[]*http.Server{
+			{
+				Addr:    c.Address,
+				Handler: createRouter(),
+			},
+		},
+		gracehttp.PreStartProcess(shutDown),
+	)
 	if err != nil {
 		return util.WrapError("error starting web server", err)
 	}
-	return
+	return shutDown()
 }
 
 func handlePanic(w http.ResponseWriter, r *http.Request, err interface{}) {
@@ -69,16 +73,18 @@ func handlePanic(w http.ResponseWriter, r *http.Request, err interface{}) {
 // Create the monolithic router for routing HTTP requests. Separated into own
 // function for easier testability.
 func createRouter() http.Handler {
-	r := httptreemux.NewContextMux()
-	r.NotFoundHandler = func(w http.ResponseWriter, _ *http.Request) {
+	mux := httptreemux.NewContextMux()
+	mux.NotFoundHandler = func(w http.ResponseWriter, _ *http.Request) {
 		text404(w)
 	}
-	r.PanicHandler = handlePanic
+	mux.PanicHandler = handlePanic
+	r := routeGroup{ContextGroup: mux.ContextGroup}
 
 	r.GET("/robots.txt", serveRobotsTXT)
 
 	api := r.NewGroup("/api")
 	api.GET("/health-check", healthCheck)
+	api.GET("/metrics", serveMetrics)
 	assets := r.NewGroup("/assets")
 	if config.Server.ImagerMode != config.NoImager {
 		// All upload images
@@ -94,6 +100,8 @@ func createRouter() http.Handler {
 		captcha.GET("/:board", serveNewCaptcha)
 		captcha.POST("/:board", authenticateCaptcha)
 		captcha.GET("/confirmation", renderCaptchaConfirmation)
+		captcha.GET("/appeal/:board", serveNewAppealCaptcha)
+		captcha.POST("/appeal/:board", authenticateAppealCaptcha)
 	}
 	if config.Server.ImagerMode != config.ImagerOnly {
 		// HTML
@@ -116,7 +124,40 @@ func createRouter() http.Handler {
 			// Artificially set board to "b"
 			boardHTML(w, r, "b", true, 0)
 		})
+		r.GET("/:board/search", func(w http.ResponseWriter, r *http.Request) {
+			searchHTML(w, r, extractParam(r, "board"))
+		})
+		r.GET("/:board/archive", func(w http.ResponseWriter, r *http.Request) {
+			archiveHTML(w, r, extractParam(r, "board"))
+		})
+		// Need overrides, because they conflict with crossRedirect
+		r.GET("/all/search", func(w http.ResponseWriter, r *http.Request) {
+			searchHTML(w, r, "all")
+		})
+		r.GET("/b/search", func(w http.ResponseWriter, r *http.Request) {
+			searchHTML(w, r, "b")
+		})
+		r.GET("/all/archive", func(w http.ResponseWriter, r *http.Request) {
+			archiveHTML(w, r, "all")
+		})
+		r.GET("/b/archive", func(w http.ResponseWriter, r *http.Request) {
+			archiveHTML(w, r, "b")
+		})
 		r.GET("/:board/:thread", threadHTML)
+
+		// Atom feeds
+		r.GET("/:board/feed.atom", func(w http.ResponseWriter, r *http.Request) {
+			boardFeed(w, r, extractParam(r, "board"))
+		})
+		r.GET("/:board/:thread/feed.atom", threadFeed)
+		// Need overrides, because they conflict with crossRedirect
+		r.GET("/all/feed.atom", func(w http.ResponseWriter, r *http.Request) {
+			boardFeed(w, r, "all")
+		})
+		r.GET("/b/feed.atom", func(w http.ResponseWriter, r *http.Request) {
+			boardFeed(w, r, "b")
+		})
+
 		r.GET("/all/:id", crossRedirect)
 		r.GET("/b/:id", crossRedirect)
 
@@ -131,11 +172,13 @@ func createRouter() http.Handler {
 		html.GET("/set-banners", bannerSettingForm)
 		html.GET("/set-loading", loadingAnimationForm)
 		html.GET("/bans/:board", banList)
+		html.GET("/appeals/:board", appealList)
 		html.GET("/mod-log/", modLog)
 		html.GET("/mod-log/:board", modLog)
 		html.GET("/report/:id", reportForm)
 		html.GET("/reports/", reportList)
 		html.GET("/reports/:board", reportList)
+		html.GET("/post-history/:post", postHistory)
 
 		// JSON API
 		json := r.NewGroup("/json")
@@ -153,8 +196,11 @@ func createRouter() http.Handler {
 		) {
 			boardJSON(w, r, true, 1)
 		})
+		boards.GET("/:board/archive", archiveJSON)
 		boards.GET("/:board/:thread", threadJSON)
 		json.GET("/post/:post", servePost)
+		json.GET("/post/:post/history", servePostHistory)
+		json.GET("/search", searchJSON)
 		json.GET("/config", serveConfigs)
 		json.GET("/extensions", serveExtensionMap)
 		json.GET("/board-config/:board", serveBoardConfigs)
@@ -169,6 +215,7 @@ func createRouter() http.Handler {
 				httpError(w, r, err)
 			}
 		})
+		api.GET("/events/:thread", serveThreadEvents)
 		api.GET("/bitchute-title/:id", bitChuteTitle)
 		api.POST("/register", register)
 		api.POST("/login", login)
@@ -192,9 +239,13 @@ func createRouter() http.Handler {
 		api.POST("/sticky", setThreadSticky)
 		api.POST("/lock-thread", setThreadLock)
 		api.POST("/unban/:board", unban)
+		api.POST("/appeal", appeal)
+		api.POST("/decide-appeal/:board", decideAppeal)
 		api.POST("/set-banners", setBanners)
 		api.POST("/set-loading", setLoadingAnimation)
 		api.POST("/report", report)
+		api.POST("/edit-post", editPost)
+		api.POST("/self-delete", selfDeletePost)
 		api.POST("/purge-post", purgePost)
 
 		redir := api.NewGroup("/redirect")
@@ -207,7 +258,7 @@ func createRouter() http.Handler {
 		assets.GET("/*path", serveAssets)
 	}
 
-	return r
+	return mux
 }
 
 // Redirects to / requests to /all/ board
@@ -262,6 +313,19 @@ func crossRedirect(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, url.String(), 301)
 }
 
+// Stream live updates of a thread as Server-Sent Events
+func serveThreadEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(extractParam(r, "thread"), 10, 64)
+	if err != nil {
+		text404(w)
+		return
+	}
+	err = websockets.EventsHandler(w, r, id)
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
+
 // Health check to ensure server is still online
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Write(healthCheckMsg)
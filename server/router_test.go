@@ -9,6 +9,7 @@ import (
 
 	"github.com/bakape/meguca/config"
 	"github.com/bakape/meguca/db"
+	imgassets "github.com/bakape/meguca/imager/assets"
 	"github.com/bakape/meguca/lang"
 	"github.com/bakape/meguca/templates"
 	"github.com/bakape/meguca/util"
@@ -34,7 +35,7 @@ func TestMain(m *testing.M) {
 	log.AddHandler(con, log.AllLevels...)
 	router = createRouter()
 	webRoot = "testdata"
-	imageWebRoot = "testdata"
+	imgassets.SetStorage(imgassets.LocalStorage{Root: "testdata"})
 
 	config.Set(config.Configs{
 		Public: config.Public{
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/templates"
+)
+
+// Format of dates accepted by the search form's date inputs
+const searchDateFormat = "2006-01-02"
+
+// Parse full-text search query and filters from the request query string
+func parseSearchParams(r *http.Request, board string) (
+	p db.SearchParams, err error,
+) {
+	q := r.URL.Query()
+	p.Board = board
+	p.Query = q.Get("q")
+	p.Name = q.Get("name")
+	p.Trip = q.Get("trip")
+	p.HasImage = q.Get("image") == "true"
+
+	if s := q.Get("page"); s != "" {
+		p.Page, err = strconv.Atoi(s)
+		if err != nil || p.Page < 0 {
+			err = common.ErrInvalidInput("page")
+			return
+		}
+	}
+	if s := q.Get("thread"); s != "" {
+		p.Thread, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			err = common.ErrInvalidInput("thread")
+			return
+		}
+	}
+	if s := q.Get("fileType"); s != "" {
+		for typ, ext := range common.Extensions {
+			if ext == s {
+				t := typ
+				p.FileType = &t
+				break
+			}
+		}
+		if p.FileType == nil {
+			err = common.ErrInvalidInput("file type")
+			return
+		}
+	}
+	p.From, err = parseSearchTime(q.Get("from"), false)
+	if err != nil {
+		return
+	}
+	p.To, err = parseSearchTime(q.Get("to"), true)
+	return
+}
+
+// Parse either a Unix timestamp or a date. If end is true, dates are
+// interpreted as the last second of that day.
+func parseSearchTime(s string, end bool) (t int64, err error) {
+	if s == "" {
+		return
+	}
+	t, err = strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return
+	}
+	d, err := time.Parse(searchDateFormat, s)
+	if err != nil {
+		err = common.ErrInvalidInput("date")
+		return
+	}
+	if end {
+		d = d.Add(24*time.Hour - time.Second)
+	}
+	t = d.Unix()
+	return
+}
+
+// Serve full-text post search results as JSON
+func searchJSON(w http.ResponseWriter, r *http.Request) {
+	err := func() (err error) {
+		board := r.URL.Query().Get("board")
+		if board != "" && !auth.IsBoard(board) {
+			return common.ErrInvalidBoard(board)
+		}
+		p, err := parseSearchParams(r, board)
+		if err != nil {
+			return
+		}
+		res, err := db.SearchPosts(p)
+		if err != nil {
+			return
+		}
+		serveJSON(w, r, "", res)
+		return
+	}()
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
+
+// Render the full-text post search page of a board
+func searchHTML(w http.ResponseWriter, r *http.Request, board string) {
+	if !auth.IsBoard(board) {
+		text404(w)
+		return
+	}
+	pos, ok := extractPosition(w, r)
+	if !ok {
+		return
+	}
+
+	err := func() (err error) {
+		p, err := parseSearchParams(r, board)
+		if err != nil {
+			return
+		}
+		res, err := db.SearchPosts(p)
+		if err != nil {
+			return
+		}
+
+		setHTMLHeaders(w)
+		templates.Search(w, board, resolveTheme(r, board), r.URL.Query(),
+			res.Posts, res.Page, res.Pages, pos)
+		return
+	}()
+	if err != nil {
+		httpError(w, r, err)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestParseSearchParams(t *testing.T) {
+	t.Parallel()
+
+	day, err := time.Parse(searchDateFormat, "2019-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webm := common.WEBM
+
+	cases := [...]struct {
+		name, query string
+		std         db.SearchParams
+		err         bool
+	}{
+		{
+			name:  "query only",
+			query: "q=foo",
+			std: db.SearchParams{
+				Board: "a",
+				Query: "foo",
+			},
+		},
+		{
+			name: "all filters",
+			query: "q=foo&thread=2&name=bar&trip=baz&image=true" +
+				"&fileType=webm&from=2019-01-02&to=2019-01-02&page=3",
+			std: db.SearchParams{
+				HasImage: true,
+				FileType: &webm,
+				Thread:   2,
+				From:     day.Unix(),
+				To:       day.Add(24*time.Hour - time.Second).Unix(),
+				Page:     3,
+				Board:    "a",
+				Query:    "foo",
+				Name:     "bar",
+				Trip:     "baz",
+			},
+		},
+		{
+			name:  "unix timestamps",
+			query: "q=foo&from=10&to=20",
+			std: db.SearchParams{
+				From:  10,
+				To:    20,
+				Board: "a",
+				Query: "foo",
+			},
+		},
+		{
+			name:  "invalid page",
+			query: "q=foo&page=-1",
+			err:   true,
+		},
+		{
+			name:  "invalid thread",
+			query: "q=foo&thread=a",
+			err:   true,
+		},
+		{
+			name:  "invalid file type",
+			query: "q=foo&fileType=exe",
+			err:   true,
+		},
+		{
+			name:  "invalid date",
+			query: "q=foo&from=yesterday",
+			err:   true,
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := parseSearchParams(newRequest("/a/search?"+c.query), "a")
+			if c.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertEquals(t, p, c.std)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/imager"
+	"github.com/bakape/meguca/websockets"
+	"github.com/go-playground/log"
+)
+
+// Maximum time to wait for clients and thumbnailing jobs on shutdown
+const shutdownTimeout = 30 * time.Second
+
+var shutdownOnce sync.Once
+
+// Drain websocket clients, open posts and pending work, before the process
+// exits or hands over to its successor on graceful restart. Only runs once.
+func shutDown() (err error) {
+	shutdownOnce.Do(func() {
+		log.Info("shutting down")
+		deadline := time.Now().Add(shutdownTimeout)
+
+		if config.Server.ImagerMode != config.ImagerOnly {
+			if !websockets.Drain(shutdownTimeout) {
+				log.Error("shutdown: timed out draining websocket clients")
+			}
+		}
+		if config.Server.ImagerMode != config.NoImager {
+			if !imager.WaitForJobs(time.Until(deadline)) {
+				log.Error("shutdown: timed out waiting for thumbnailing")
+			}
+		}
+
+		// Flush after all posts have been closed, as closing posts can
+		// still increment spam scores
+		err = db.FlushSpamScores()
+		if err != nil {
+			log.Errorf("shutdown: flushing spam scores: %s\n", err)
+		}
+
+		// Release BoltDB for the successor process
+		err = db.Close()
+	})
+	return
+}
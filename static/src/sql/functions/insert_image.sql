@@ -1,19 +1,28 @@
--- Inserts image into existing post and return image json
-create function insert_image(post_id bigint, token char(86), name varchar(200),
-	spoiler bool)
+-- Inserts image into existing post and return image json. If the post already
+-- has an image, the file is appended to the post's additional files.
+create or replace function insert_image(post_id bigint, token char(86),
+	name varchar(200), spoiler bool)
 returns jsonb as $$
 declare
 	image_id char(40);
 	data jsonb;
 begin
+	image_id = use_image_token(insert_image.token);
 	update posts
-		set sha1 = use_image_token(insert_image.token),
+		set sha1 = image_id,
 			imageName = insert_image.name,
 			spoiler = insert_image.spoiler
 		where id = post_id
-		returning posts.sha1 into image_id;
-	if image_id is null then
-		raise exception 'post not found';
+			and sha1 is null;
+	if not found then
+		if not exists (select 1 from posts p where p.id = post_id) then
+			raise exception 'post not found';
+		end if;
+		insert into post_files (post_id, position, sha1, name, spoiler)
+			select insert_image.post_id, coalesce(max(f.position), 0) + 1,
+				image_id, insert_image.name, insert_image.spoiler
+			from post_files f
+			where f.post_id = insert_image.post_id;
 	end if;
 
 	select to_jsonb(i) into data
@@ -6,7 +6,7 @@ begin
 	select exists (select 1
 					from post_moderation pm
 					where pm.post_id = is_deleted.id
-						and pm.type = 2)
+						and pm.type in (2, 12))
 		into deleted;
 	return deleted;
 end;
@@ -22,6 +22,9 @@ begin
 		update posts as p
 			set spoiler = true
 			where p.id = post_id;
+		update post_files as f
+			set spoiler = true
+			where f.post_id = post_id;
 		insert into mod_log (type, board, post_id, "by")
 			values (4, board, post_id, account);
 	end loop;
@@ -0,0 +1,11 @@
+create or replace function after_links_insert()
+returns trigger as $$
+begin
+	-- Invalidate caches of the linked thread, as it now displays a backlink
+	-- to another board
+	if post_board(new.source) != post_board(new.target) then
+		perform bump_thread(post_op(new.target));
+	end if;
+	return null;
+end;
+$$ language plpgsql;
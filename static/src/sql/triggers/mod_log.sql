@@ -3,7 +3,8 @@ returns trigger as $$
 declare
 	op bigint;
 begin
-	if new.post_id != 0 then
+	-- Ban appeal decisions only reference the post the ban was issued for
+	if new.post_id != 0 and new.type not in (10, 11) then
 		insert into post_moderation (post_id, type, "by", length, data)
 			values (new.post_id, new.type, new."by", new.length, new.data);
 		update posts
@@ -14,7 +15,7 @@ begin
 			concat_ws(',', op, new.id));
 
 		-- Posts bump threads only on creation and closure
-		if (new.type = 2) then
+		if (new.type in (2, 12)) then
 			perform bump_thread(op, true, true, new.post_id);
 		--else
 		--	perform bump_thread(op, true);
@@ -0,0 +1,8 @@
+create or replace function after_poll_votes_insert()
+returns trigger as $$
+begin
+	-- Invalidate caches of the thread, as the poll's tallies changed
+	perform bump_thread(post_op(new.post_id));
+	return null;
+end;
+$$ language plpgsql;
@@ -0,0 +1,11 @@
+create or replace function after_post_files_insert()
+returns trigger as $$
+declare
+	op bigint;
+begin
+	-- Image count of thread changed
+	op = post_op(new.post_id);
+	perform pg_notify('thread_updated', post_board(op) || ',' || op);
+	return null;
+end;
+$$ language plpgsql;
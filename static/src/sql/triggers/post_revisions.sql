@@ -0,0 +1,8 @@
+create or replace function after_post_revisions_insert()
+returns trigger as $$
+begin
+	-- Invalidate caches of the thread, as the post body changed
+	perform bump_thread(post_op(new.post_id));
+	return null;
+end;
+$$ language plpgsql;
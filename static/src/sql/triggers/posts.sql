@@ -15,7 +15,7 @@ begin
 		where board = (select t.board
 						from threads t
 						where t.id = new.op)
-			and b.ip = new.ip
+			and b.ip >>= new.ip
 			and b.type = 'shadow'
 			and b.expires > now() at time zone 'UTC';
 	if to_delete_by is not null then
@@ -26,6 +26,11 @@ begin
 		new.moderated = true;
 	end if;
 
+	-- Open post bodies are indexed on closing
+	if not new.editing then
+		new.body_tsv = to_tsvector('simple', new.body);
+	end if;
+
 	return new;
 end;
 $$ language plpgsql;
@@ -36,6 +41,32 @@ begin
 	if new.editing != old.editing then
 		perform bump_thread(new.op, not new.sage);
 	end if;
+	-- Image count of thread changed
+	if new.sha1 is distinct from old.sha1 then
+		perform pg_notify('thread_updated',
+			post_board(new.op) || ',' || new.op);
+	end if;
+	-- Additional files are only kept together with the post's image
+	if new.sha1 is null and old.sha1 is not null then
+		delete from post_files
+			where post_id = new.id;
+	end if;
 	return null;
 end;
 $$ language plpgsql;
+
+create or replace function before_posts_update()
+returns trigger as $$
+begin
+	-- Only index closed post bodies. Open post bodies are not final.
+	if new.body is distinct from old.body
+		or new.editing != old.editing then
+		if new.editing then
+			new.body_tsv = null;
+		else
+			new.body_tsv = to_tsvector('simple', new.body);
+		end if;
+	end if;
+	return new;
+end;
+$$ language plpgsql;
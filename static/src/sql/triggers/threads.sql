@@ -2,6 +2,7 @@ create or replace function after_threads_insert()
 returns trigger as $$
 begin
 	perform bump_thread(new.id);
+	perform pg_notify('thread_updated', new.board || ',' || new.id);
 
 	-- Init Russian roulette
 	insert into roulette (id, scount, rcount) values (new.id, 6, 0);
@@ -17,6 +18,14 @@ begin
 	if new.update_time != old.update_time then
 		perform bump_thread(new.id);
 	end if;
+	-- Only notify about changes to the thread's catalog entry. Duplicate
+	-- notifications in the same transaction are merged.
+	if (new.sticky, new.locked, new.archived, new.subject, new.update_time,
+			new.bump_time)
+		is distinct from (old.sticky, old.locked, old.archived, old.subject,
+			old.update_time, old.bump_time) then
+		perform pg_notify('thread_updated', new.board || ',' || new.id);
+	end if;
 	return null;
 end;
 $$ language plpgsql;
@@ -119,6 +119,14 @@ func extractBacklinks(cap int, threads ...common.Thread) backlinks {
 		for _, p := range t.Posts {
 			register(p, t.ID, t.Board)
 		}
+		for _, bl := range t.CrossBoardBacklinks {
+			m, ok := bls[bl.Target]
+			if !ok {
+				m = make(map[uint64]common.Link, 4)
+				bls[bl.Target] = m
+			}
+			m[bl.ID] = bl.Link
+		}
 	}
 
 	return bls
@@ -142,6 +150,10 @@ func streampostModeration(qw *quicktemplate.Writer, e common.ModerationEntry) {
 		fmt.Fprintf(w, f["deleted"], e.By)
 	case common.DeleteImage:
 		fmt.Fprintf(w, f["imageDeleted"], e.By)
+	case common.SelfDeletePost:
+		fmt.Fprint(w, f["selfDeleted"])
+	case common.SelfDeleteImage:
+		fmt.Fprint(w, f["imageSelfDeleted"])
 	case common.SpoilerImage:
 		fmt.Fprintf(w, f["imageSpoilered"], e.By)
 	case common.LockThread:
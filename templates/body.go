@@ -32,8 +32,9 @@ const (
 )
 
 var (
-	linkRegexp      = regexp.MustCompile(`^>>(>*)(\d+)$`)
-	referenceRegexp = regexp.MustCompile(`^>>>(>*)\/(\w+)\/$`)
+	linkRegexp           = regexp.MustCompile(`^>>(>*)(\d+)$`)
+	crossBoardLinkRegexp = regexp.MustCompile(`^>>>(>*)\/(\w+)\/(\d+)$`)
+	referenceRegexp      = regexp.MustCompile(`^>>>(>*)\/(\w+)\/$`)
 
 	providers = map[int]string{
 		youTube:    "YouTube",
@@ -94,6 +95,7 @@ type bodyContext struct {
 		spoiler, quote, code, bold, italic, red, blue, purple, rbText, pyu bool
 		successiveNewlines                                         uint
 		iDice                                                      int
+		pollLines                                                  int
 	}
 	common.Post
 	OP    uint64
@@ -129,6 +131,12 @@ func streambody(
 	}
 
 	for i, l := range strings.Split(c.Body, "\n") {
+		// Options of a #poll are rendered by the command itself
+		if c.state.pollLines != 0 {
+			c.state.pollLines--
+			continue
+		}
+
 		c.state.quote = false
 
 		// Prevent successive empty lines
@@ -435,6 +443,12 @@ func (c *bodyContext) parseFragment(frag string) {
 		if leadPunct != 0 {
 			c.byte(leadPunct)
 		}
+		// Exploding dice end with '!'
+		if trailPunct == '!' && len(word) != 0 && word[0] == '#' &&
+			common.CommandRegexp.MatchString(word+"!") {
+			word += "!"
+			trailPunct = 0
+		}
 		if (strings.Count(word, "(") == strings.Count(word, ")")+1) &&
 			(trailPunct == ')') && (strings.Contains(word, "http")) {
 			word += ")"
@@ -458,6 +472,10 @@ func (c *bodyContext) parseFragment(frag string) {
 				// Post links
 				c.parsePostLink(m)
 				goto end
+			} else if m := crossBoardLinkRegexp.FindStringSubmatch(word); m != nil {
+				// Post links with board verification
+				c.parseCrossBoardLink(m)
+				goto end
 			} else if m := referenceRegexp.FindStringSubmatch(word); m != nil {
 				// Internal and custom reference URLs
 				c.parseReference(m)
@@ -521,7 +539,32 @@ func (c *bodyContext) parsePostLink(m []string) {
 	if len(m[1]) != 0 { // Write extra quotes
 		c.string(m[1])
 	}
-	streampostLink(&c.Writer, data, c.index || data.OP != c.OP, c.index)
+	c.writePostLink(data, false)
+}
+
+// Parse a potential link to a post on a specific board
+func (c *bodyContext) parseCrossBoardLink(m []string) {
+	id, _ := strconv.ParseUint(m[3], 10, 64)
+	var data common.Link
+	for _, l := range c.Links {
+		if l.ID == id && l.Board == m[2] {
+			data = l
+			break
+		}
+	}
+	if data.ID == 0 {
+		c.string(m[0])
+		return
+	}
+
+	if len(m[1]) != 0 {
+		c.string(m[1])
+	}
+	c.writePostLink(data, true)
+}
+
+func (c *bodyContext) writePostLink(l common.Link, crossBoard bool) {
+	streampostLink(&c.Writer, l, c.index || l.OP != c.OP, c.index, crossBoard)
 }
 
 // Parse internal or customly set reference URL
@@ -686,6 +729,14 @@ func (c *bodyContext) parseCommands(bit string) {
 		default:
 			c.writeInvalidCommand(bit)
 		}
+	case "poll":
+		if val.Type != common.Poll {
+			c.writeInvalidCommand(bit)
+			return
+		}
+		c.formatPoll(val.Poll)
+		c.state.iDice++
+		return
 	case "roulette":
 		inner = strconv.AppendUint(inner, uint64(val.Roulette[0]), 10)
 		inner = append(inner, "/"...)
@@ -703,22 +754,18 @@ func (c *bodyContext) parseCommands(bit string) {
 		}
 
 		// Validate dice
-		m := common.DiceRegexp.FindStringSubmatch(bit)
-		rolls := 1
-		if m[1] != "" {
-			var err error
-			if rolls, err = strconv.Atoi(m[1]); err != nil || rolls > 10 {
-				c.writeInvalidCommand(bit)
-				return
-			}
-		}
-		sides, err := strconv.Atoi(m[2])
-		if err != nil || sides > common.MaxDiceSides {
+		expr, err := common.ParseDice(bit)
+		if err != nil || val.Type != common.Dice ||
+			expr.IsSimple() != (val.DiceRoll == nil) {
 			c.writeInvalidCommand(bit)
 			return
 		}
 
 		c.state.iDice++
+		if val.DiceRoll != nil {
+			inner = appendDiceRoll(inner, *val.DiceRoll)
+			break
+		}
 		var sum uint64
 		for i, roll := range val.Dice {
 			if i != 0 {
@@ -732,7 +779,8 @@ func (c *bodyContext) parseCommands(bit string) {
 			inner = strconv.AppendUint(inner, sum, 10)
 		}
 
-		formatting = getRollFormatting(uint64(rolls), uint64(sides), sum)
+		formatting = getRollFormatting(uint64(expr[0].Count),
+			uint64(expr[0].Sides), sum)
 	}
 
 	c.string(formatting)
@@ -743,6 +791,45 @@ func (c *bodyContext) parseCommands(bit string) {
 	c.string(`)</strong>`)
 }
 
+// Append the breakdown of each term of a dice expression and its total.
+// Rolls of multiple dice are grouped in brackets with dropped dice struck out.
+func appendDiceRoll(b []byte, roll common.DiceRoll) []byte {
+	for i, t := range roll.Terms {
+		switch {
+		case t.Negative:
+			b = append(b, " - "...)
+		case i != 0:
+			b = append(b, " + "...)
+		}
+		if t.Rolls == nil {
+			b = strconv.AppendInt(b, int64(t.Constant), 10)
+			continue
+		}
+
+		if len(t.Rolls) > 1 {
+			b = append(b, '[')
+		}
+		for j, r := range t.Rolls {
+			if j != 0 {
+				b = append(b, ", "...)
+			}
+			dropped := t.Dropped != nil && t.Dropped[j]
+			if dropped {
+				b = append(b, "<s>"...)
+			}
+			b = strconv.AppendUint(b, uint64(r), 10)
+			if dropped {
+				b = append(b, "</s>"...)
+			}
+		}
+		if len(t.Rolls) > 1 {
+			b = append(b, ']')
+		}
+	}
+	b = append(b, " = "...)
+	return strconv.AppendInt(b, int64(roll.Total), 10)
+}
+
 func getRollFormatting(numberOfDice uint64, facesPerDie uint64, sum uint64) string {
 	maxRoll := numberOfDice * facesPerDie
 	// no special formatting for small rolls
@@ -802,6 +889,27 @@ func (c *bodyContext) formatSyncwatch(val [5]uint64) {
 	c.string(`>⌚: ???</strong></em>`)
 }
 
+// Render a #poll with its options on the following lines and skip the lines
+// of the options in the body
+func (c *bodyContext) formatPoll(poll common.PollState) {
+	c.string(`<span class="poll"><strong>#poll</strong>`)
+	for i, o := range poll.Options {
+		c.string(`<br><a class="poll-option" data-option=`)
+		c.uint64(uint64(i))
+		c.byte('>')
+		c.escape(o)
+		c.string(`</a> <strong>(`)
+		if i < len(poll.Votes) {
+			c.uint64(uint64(poll.Votes[i]))
+		} else {
+			c.byte('0')
+		}
+		c.string(`)</strong>`)
+	}
+	c.string(`</span>`)
+	c.state.pollLines = len(poll.Options)
+}
+
 func (c *bodyContext) uint64(i uint64) {
 	c.string(strconv.FormatUint(i, 10))
 }
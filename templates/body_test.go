@@ -240,6 +240,73 @@ func TestRenderBody(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "keep highest dice with modifier",
+			in:   "#2d20kh1+5",
+			out:  "<strong>#2d20kh1+5 ([17, <s>4</s>] + 5 = 22)</strong>",
+			commands: []common.Command{
+				{
+					Type: common.Dice,
+					Dice: []uint16{17, 4},
+					DiceRoll: &common.DiceRoll{
+						Terms: []common.DiceTermRoll{
+							{
+								Rolls:   []uint16{17, 4},
+								Dropped: []bool{false, true},
+							},
+							{Constant: 5},
+						},
+						Total: 22,
+					},
+				},
+			},
+		},
+		{
+			name: "exploding dice",
+			in:   "#d6!-1!",
+			out:  "<strong>#d6!-1 ([6, 2] - 1 = 7)</strong>!",
+			commands: []common.Command{
+				{
+					Type: common.Dice,
+					Dice: []uint16{6, 2},
+					DiceRoll: &common.DiceRoll{
+						Terms: []common.DiceTermRoll{
+							{Rolls: []uint16{6, 2}},
+							{Negative: true, Constant: 1},
+						},
+						Total: 7,
+					},
+				},
+			},
+		},
+		{
+			name: "trailing exploding dice",
+			in:   "#d6!",
+			out:  "<strong>#d6! ([6, 2] = 8)</strong>",
+			commands: []common.Command{
+				{
+					Type: common.Dice,
+					Dice: []uint16{6, 2},
+					DiceRoll: &common.DiceRoll{
+						Terms: []common.DiceTermRoll{
+							{Rolls: []uint16{6, 2}},
+						},
+						Total: 8,
+					},
+				},
+			},
+		},
+		{
+			name: "extended dice without breakdown",
+			in:   "#4d6dl1",
+			out:  "#4d6dl1",
+			commands: []common.Command{
+				{
+					Type: common.Dice,
+					Dice: []uint16{1, 2, 3, 4},
+				},
+			},
+		},
 		{
 			name: "no valid commands",
 			in:   "#flip",
@@ -256,6 +323,35 @@ func TestRenderBody(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "#poll",
+			in:   "vote #poll\nfoo\n<bar>\n\n#flip",
+			out:  `vote <span class="poll"><strong>#poll</strong><br><a class="poll-option" data-option=0>foo</a> <strong>(2)</strong><br><a class="poll-option" data-option=1>&lt;bar&gt;</a> <strong>(0)</strong></span><br><br><strong>#flip (flap)</strong>`,
+			commands: []common.Command{
+				{
+					Type: common.Poll,
+					Poll: common.PollState{
+						Options: []string{"foo", "<bar>"},
+						Votes:   []uint32{2, 0},
+					},
+				},
+				{
+					Type: common.Flip,
+					Flip: true,
+				},
+			},
+		},
+		{
+			name: "#poll with wrong command type",
+			in:   "#poll\nfoo\nbar",
+			out:  "#poll<br>foo<br>bar",
+			commands: []common.Command{
+				{
+					Type: common.Flip,
+					Flip: true,
+				},
+			},
+		},
 		{
 			name: "no links in post",
 			in:   ">>20",
@@ -288,6 +384,20 @@ func TestRenderBody(t *testing.T) {
 			op:    20,
 			links: []common.Link{{21, 22, "c"}},
 		},
+		{
+			name:  "valid cross-board link",
+			in:    ">>>/c/21",
+			out:   `<em><a class="post-link" data-id="21" href="/c/22#p21">>>>/c/21 ➡</a><a class="hash-link" href="/c/22#p21"> #</a></em>`,
+			op:    20,
+			links: []common.Link{{21, 22, "c"}},
+		},
+		{
+			name:  "cross-board link to wrong board",
+			in:    ">>>/a/21",
+			out:   `<em>>>>/a/21</em>`,
+			op:    20,
+			links: []common.Link{{21, 22, "c"}},
+		},
 		{
 			name: "invalid reference",
 			in:   ">>>/fufufu/",
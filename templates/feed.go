@@ -0,0 +1,199 @@
+package templates
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/lang"
+	"github.com/valyala/quicktemplate"
+)
+
+// Root element of an Atom feed document
+type atomFeed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	// Resolves relative links in rendered post bodies
+	Base    string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// BoardFeed renders an Atom feed of the newest threads of a board
+func BoardFeed(board string, threads []common.Thread) ([]byte, error) {
+	root := config.Get().RootURL
+	url := fmt.Sprintf("%s/%s/", root, board)
+	title := fmt.Sprintf("/%s/", board)
+	if board != "all" && board != "b" {
+		title += " - " + config.GetBoardConfigs(board).Title
+	}
+
+	entries := make([]atomEntry, 0, len(threads))
+	var updated int64
+	for _, t := range threads {
+		if t.IsDeleted() {
+			continue
+		}
+		if t.Time > updated {
+			updated = t.Time
+		}
+		entries = append(entries,
+			feedEntry(t.Post, t.ID, t.Board, t.Subject, root))
+	}
+
+	return renderFeed(atomFeed{
+		Base:    root + "/",
+		ID:      url,
+		Title:   title,
+		Updated: formatFeedTime(updated),
+		Links: []atomLink{
+			{
+				Rel:  "self",
+				Type: "application/atom+xml",
+				Href: url + "feed.atom",
+			},
+			{
+				Rel:  "alternate",
+				Type: "text/html",
+				Href: url,
+			},
+		},
+		Entries: entries,
+	})
+}
+
+// ThreadFeed renders an Atom feed of a thread's OP and newest replies
+func ThreadFeed(t common.Thread) ([]byte, error) {
+	root := config.Get().RootURL
+	url := fmt.Sprintf("%s/%s/%d", root, t.Board, t.ID)
+
+	entries := make([]atomEntry, 0, len(t.Posts)+1)
+	entries = append(entries, feedEntry(t.Post, t.ID, t.Board, t.Subject, root))
+	updated := t.Time
+	// Newest replies first
+	for i := len(t.Posts) - 1; i >= 0; i-- {
+		p := t.Posts[i]
+		if p.Editing || p.IsDeleted() {
+			continue
+		}
+		if p.Time > updated {
+			updated = p.Time
+		}
+		entries = append(entries, feedEntry(p, t.ID, t.Board, "", root))
+	}
+
+	return renderFeed(atomFeed{
+		Base:    root + "/",
+		ID:      url,
+		Title:   fmt.Sprintf("/%s/ - %s", t.Board, t.Subject),
+		Updated: formatFeedTime(updated),
+		Links: []atomLink{
+			{
+				Rel:  "self",
+				Type: "application/atom+xml",
+				Href: url + "/feed.atom",
+			},
+			{
+				Rel:  "alternate",
+				Type: "text/html",
+				Href: url,
+			},
+		},
+		Entries: entries,
+	})
+}
+
+// Build a feed entry from a post. If title is empty, a body excerpt or the
+// post number is used instead.
+func feedEntry(p common.Post, op uint64, board, title, root string,
+) atomEntry {
+	id := strconv.FormatUint(p.ID, 10)
+	if title == "" {
+		title = excerpt(p.Body, 60)
+	}
+	if title == "" {
+		title = ">>" + id
+	}
+
+	name := p.Name
+	if name == "" && p.Trip == "" {
+		name = lang.Get().Common.Posts["anon"]
+	}
+	if p.Trip != "" {
+		name += "!" + p.Trip
+	}
+
+	href := fmt.Sprintf("%s/%s/%d#p%s", root, board, op, id)
+	return atomEntry{
+		ID:      href,
+		Title:   title,
+		Updated: formatFeedTime(p.Time),
+		Author:  atomAuthor{name},
+		Link: atomLink{
+			Rel:  "alternate",
+			Type: "text/html",
+			Href: href,
+		},
+		Content: atomContent{
+			Type: "html",
+			Body: renderFeedBody(p, op, board),
+		},
+	}
+}
+
+// Render a post body into HTML through the regular body renderer. Links are
+// always rendered as absolute paths, to be resolved against the feed's base
+// URL.
+func renderFeedBody(p common.Post, op uint64, board string) string {
+	buf := quicktemplate.AcquireByteBuffer()
+	defer quicktemplate.ReleaseByteBuffer(buf)
+	w := quicktemplate.AcquireWriter(buf)
+	defer quicktemplate.ReleaseWriter(w)
+
+	conf := config.GetBoardConfigs(board)
+	streambody(w, p, op, board, true, conf.RbText, conf.Pyu)
+	return string(buf.B)
+}
+
+// Format a Unix timestamp as an RFC 3339 date, as required by Atom
+func formatFeedTime(sec int64) string {
+	return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
+
+// Encode feed into an XML document
+func renderFeed(f atomFeed) ([]byte, error) {
+	buf, err := xml.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), buf...), nil
+}
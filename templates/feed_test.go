@@ -0,0 +1,89 @@
+package templates
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/bakape/meguca/common"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestThreadFeed(t *testing.T) {
+	thread := common.Thread{
+		Board:   "a",
+		Subject: "<subject>",
+		Post: common.Post{
+			ID:   1,
+			Time: 1,
+			Body: "foo",
+		},
+		Posts: []common.Post{
+			{
+				ID:   2,
+				Time: 2,
+				Body: "bar & baz",
+				Name: "name",
+			},
+			{
+				ID:      3,
+				Time:    3,
+				Body:    "open",
+				Editing: true,
+			},
+			{
+				ID:   4,
+				Time: 4,
+				Moderation: []common.ModerationEntry{
+					{
+						Type: common.DeletePost,
+					},
+				},
+			},
+		},
+	}
+
+	buf, err := ThreadFeed(thread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(buf, &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertEquals(t, feed.Title, "/a/ - <subject>")
+	AssertEquals(t, feed.Updated, formatFeedTime(2))
+	AssertEquals(t, len(feed.Entries), 2)
+	AssertEquals(t, feed.Entries[0].Title, "<subject>")
+	AssertEquals(t, feed.Entries[1].Title, "bar & baz")
+	AssertEquals(t, feed.Entries[1].Author.Name, "name")
+	AssertEquals(t, feed.Entries[1].Content.Body, "bar &amp; baz")
+}
+
+func TestBoardFeed(t *testing.T) {
+	threads := []common.Thread{
+		{
+			Board:   "a",
+			Subject: "foo",
+			Post: common.Post{
+				ID:   1,
+				Time: 5,
+			},
+		},
+	}
+
+	buf, err := BoardFeed("all", threads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(buf, &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertEquals(t, feed.Title, "/all/")
+	AssertEquals(t, feed.Updated, formatFeedTime(5))
+	AssertEquals(t, len(feed.Entries), 1)
+	AssertEquals(t, feed.Entries[0].Title, "foo")
+	AssertEquals(t, feed.Entries[0].Author.Name, "Anonymous")
+}
@@ -27,6 +27,7 @@ const (
 	_shortcut
 	_range
 	_hr
+	_wordFilters
 )
 
 // Spec of an option passed into the rendering function
@@ -79,6 +80,8 @@ func (w *formWriter) input(spec inputSpec) {
 		streamrenderMap(&w.Writer, spec)
 	case _array:
 		streamrenderArray(&w.Writer, spec)
+	case _wordFilters:
+		streamrenderWordFilters(&w.Writer, spec)
 	case _shortcut:
 		w.N().S("Alt+")
 		cont = true
@@ -125,6 +125,23 @@ var specs = map[string][]inputSpec{
 		{ID: "flags"},
 		{ID: "NSFW"},
 		{ID: "rbText"},
+		{ID: "archive"},
+		{
+			ID:   "allowEdits",
+			Type: _number,
+			Min:  0,
+		},
+		{
+			ID:   "allowSelfDelete",
+			Type: _number,
+			Min:  0,
+		},
+		{
+			ID:   "maxFiles",
+			Type: _number,
+			Min:  1,
+			Max:  common.MaxNumFiles,
+		},
 		{Type: _hr},
 		{ID: "pyu"},
 		{
@@ -150,6 +167,10 @@ var specs = map[string][]inputSpec{
 			Type:      _array,
 			MaxLength: common.MaxLenEightball,
 		},
+		{
+			ID:   "wordFilters",
+			Type: _wordFilters,
+		},
 	},
 	"createBoard": {
 		{
@@ -172,6 +193,12 @@ var specs = map[string][]inputSpec{
 		{ID: "disableUserBoards"},
 		{ID: "globalDisableRobots"},
 		{Type: _hr},
+		{
+			ID:       "threadsPerPage",
+			Type:     _number,
+			Min:      1,
+			Required: true,
+		},
 		{ID: "pruneThreads"},
 		{
 			ID:       "threadExpiryMin",
@@ -185,6 +212,11 @@ var specs = map[string][]inputSpec{
 			Min:      1,
 			Required: true,
 		},
+		{
+			ID:   "archiveExpiry",
+			Type: _number,
+			Min:  0,
+		},
 		{ID: "pruneBoards"},
 		{
 			ID:       "boardExpiry",
@@ -233,6 +265,66 @@ var specs = map[string][]inputSpec{
 			Min:      0,
 			Required: true,
 		},
+		{
+			ID:       "appendRate",
+			Type:     _number,
+			Min:      0,
+			Required: true,
+		},
+		{
+			ID:       "appendBurst",
+			Type:     _number,
+			Min:      1,
+			Required: true,
+		},
+		{
+			ID:       "spliceRate",
+			Type:     _number,
+			Min:      0,
+			Required: true,
+		},
+		{
+			ID:       "spliceBurst",
+			Type:     _number,
+			Min:      1,
+			Required: true,
+		},
+		{
+			ID:       "insertPostRate",
+			Type:     _number,
+			Min:      0,
+			Required: true,
+		},
+		{
+			ID:       "insertPostBurst",
+			Type:     _number,
+			Min:      1,
+			Required: true,
+		},
+		{
+			ID:       "insertImageRate",
+			Type:     _number,
+			Min:      0,
+			Required: true,
+		},
+		{
+			ID:       "insertImageBurst",
+			Type:     _number,
+			Min:      1,
+			Required: true,
+		},
+		{
+			ID:       "reclaimRate",
+			Type:     _number,
+			Min:      0,
+			Required: true,
+		},
+		{
+			ID:       "reclaimBurst",
+			Type:     _number,
+			Min:      1,
+			Required: true,
+		},
 		{
 			ID:       "sessionExpiry",
 			Type:     _number,
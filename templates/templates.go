@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"html"
 	"io"
+	"net/url"
 	"sync"
 
 	"github.com/bakape/meguca/common"
 	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/lang"
 	"github.com/bakape/meguca/util"
 )
 
@@ -75,11 +77,34 @@ func Board(w io.Writer, b, theme string, page, total int,
 
 // Thread writes thread page HTML
 func Thread(w io.Writer, id uint64, board, title, theme string, abbrev,
-	locked bool, pos common.ModerationLevel, postHTML []byte,
+	locked, archived bool, pos common.ModerationLevel, postHTML []byte,
 ) {
 	title = html.EscapeString(fmt.Sprintf("/%s/ - %s", board, title))
 	execIndex(w, title, theme, pos, func(w io.Writer) {
-		writerenderThread(w, postHTML, id, board, abbrev, locked, pos)
+		writerenderThread(w, postHTML, id, board, abbrev, locked, archived,
+			pos)
+	})
+}
+
+// Archive writes the archived thread listing page HTML of a board
+func Archive(w io.Writer, board, theme string, pos common.ModerationLevel,
+	threads []common.Thread, page, total int,
+) {
+	title := html.EscapeString(fmt.Sprintf("/%s/ - %s", board,
+		lang.Get().UI["archive"]))
+	execIndex(w, title, theme, pos, func(w io.Writer) {
+		writerenderArchive(w, board, title, threads, page, total)
+	})
+}
+
+// Search writes the post search page HTML
+func Search(w io.Writer, board, theme string, q url.Values,
+	posts []common.StandalonePost, page, total int, pos common.ModerationLevel,
+) {
+	title := html.EscapeString(fmt.Sprintf("/%s/ - %s", board,
+		lang.Get().UI["searchPosts"]))
+	execIndex(w, title, theme, pos, func(w io.Writer) {
+		writerenderSearch(w, board, q, posts, page, total)
 	})
 }
 
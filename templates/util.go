@@ -2,6 +2,7 @@ package templates
 
 import (
 	"html"
+	"strings"
 
 	"github.com/bakape/meguca/common"
 )
@@ -22,17 +23,28 @@ func CalculateOmit(t common.Thread) (int, int) {
 	if omit != 0 {
 		imgOmit = t.ImageCount
 		if t.Image != nil {
-			imgOmit--
+			imgOmit -= 1 + uint32(len(t.Files))
 		}
 		for _, p := range t.Posts {
 			if p.Image != nil {
-				imgOmit--
+				imgOmit -= 1 + uint32(len(p.Files))
 			}
 		}
 	}
 	return omit, int(imgOmit)
 }
 
+// Returns the first n runes of s on a single line. Appends an ellipsis, if
+// truncated.
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
 func bold(s string) string {
 	s = html.EscapeString(s)
 	b := make([]byte, 3, len(s)+7)
@@ -0,0 +1,212 @@
+// Read-only thread update streaming over Server-Sent Events for clients, that
+// can not establish websocket connections
+
+package websockets
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/websockets/feeds"
+)
+
+var (
+	errNoStreaming    = errors.New("response streaming not supported")
+	errThreadNotFound = common.StatusError{errors.New("thread not found"), 404}
+)
+
+// Client subscribed to a thread feed through Server-Sent Events. Receives the
+// same messages as a websocket client, but can not send any.
+type eventClient struct {
+	ip string
+	// Messages encoded in protocol version 1 pending sending
+	send chan []byte
+	// Close the event stream
+	close chan error
+}
+
+func newEventClient(ip string) *eventClient {
+	return &eventClient{
+		ip:    ip,
+		send:  make(chan []byte, time.Second*60/feeds.TickerInterval),
+		close: make(chan error, 1),
+	}
+}
+
+// Send a message encoded in protocol version 1 to the client. Can be used
+// concurrently.
+func (c *eventClient) Send(msg []byte) {
+	c.SendMessage(common.NewMessage(msg))
+}
+
+// SendMessage sends a message to the client. Can be used concurrently.
+func (c *eventClient) SendMessage(msg *common.Message) {
+	buf, err := msg.Encode(common.MinProtocolVersion)
+	if err != nil {
+		c.Close(err)
+		return
+	}
+	select {
+	case c.send <- buf:
+	default:
+		c.Close(errors.New("send buffer overflow"))
+	}
+}
+
+// Redirect instructs the client to navigate to a board. The stream itself
+// can not be resynchronised.
+func (c *eventClient) Redirect(board string) {
+	msg, err := common.EncodeMessage(common.MessageRedirect, "/"+board+"/")
+	if err != nil {
+		c.Close(err)
+		return
+	}
+	c.Send(msg)
+}
+
+// IP returns the IP of the client
+func (c *eventClient) IP() string {
+	return c.ip
+}
+
+// LastTime returns the last post time of the client. Event stream clients
+// can not post.
+func (c *eventClient) LastTime() int64 {
+	return 0
+}
+
+// Close closes the event stream
+func (c *eventClient) Close(err error) {
+	select {
+	case c.close <- err:
+	default:
+	}
+}
+
+// EventsHandler streams the update feed of a thread as Server-Sent Events.
+// Clients resuming with a Last-Event-ID header receive only the feed messages
+// they missed.
+func EventsHandler(w http.ResponseWriter, r *http.Request, thread uint64,
+) (err error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errNoStreaming
+	}
+
+	ip, err := auth.GetIP(r)
+	if err != nil {
+		return
+	}
+	board, op, err := db.GetPostParenthood(thread)
+	switch {
+	case err == sql.ErrNoRows || (err == nil && op != thread):
+		return errThreadNotFound
+	case err != nil:
+		return
+	}
+	err = db.IsBanned(board, ip)
+	if err != nil {
+		return
+	}
+	err = feeds.RegisterIP(ip)
+	if err != nil {
+		return
+	}
+	defer feeds.UnregisterIP(ip)
+
+	// Invalid IDs simply cause a full synchronization
+	lastSeq, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+
+	c := newEventClient(ip)
+	_, err = feeds.SyncClient(c, thread, board, lastSeq)
+	if err != nil {
+		return
+	}
+	defer feeds.RemoveClient(c)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-store")
+	// Disable response buffering in NGINX
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	flusher.Flush()
+
+	// Keep proxies from closing idle connections
+	ping := time.NewTicker(pingTimer)
+	defer ping.Stop()
+
+	// Headers are already sent, so errors can only be passed in the stream
+	for {
+		var werr error
+		select {
+		case <-r.Context().Done():
+			return nil
+		case err := <-c.close:
+			if err != nil {
+				msg, _ := common.EncodeMessage(common.MessageInvalid,
+					err.Error())
+				writeEvent(w, msg)
+				flusher.Flush()
+			}
+			return nil
+		case msg := <-c.send:
+			_, werr = writeEvent(w, msg)
+		case <-ping.C:
+			_, werr = w.Write([]byte(":\n\n"))
+		}
+		if werr != nil {
+			return nil
+		}
+		flusher.Flush()
+	}
+}
+
+// Write a message as an event, identified by the feed sequence number
+// contained in it, if any. Messages never contain line breaks.
+func writeEvent(w http.ResponseWriter, msg []byte) (int, error) {
+	var id string
+	if seq, ok := feedSequence(msg); ok {
+		id = fmt.Sprintf("id: %d\n", seq)
+	}
+	return fmt.Fprintf(w, "%sdata: %s\n\n", id, msg)
+}
+
+// Extract the last feed sequence number from a message, if any
+func feedSequence(msg []byte) (seq uint64, ok bool) {
+	if len(msg) < 2 {
+		return
+	}
+	typ, err := strconv.ParseUint(string(msg[:2]), 10, 8)
+	if err != nil {
+		return
+	}
+	data := msg[2:]
+
+	switch common.MessageType(typ) {
+	case common.MessageFeedSequence:
+		seq, err = strconv.ParseUint(string(data), 10, 64)
+		return seq, err == nil
+	case common.MessageConcat:
+		var msgs []string
+		if json.Unmarshal(data, &msgs) != nil {
+			return
+		}
+		prefix := strconv.Itoa(int(common.MessageFeedSequence))
+		for i := len(msgs) - 1; i >= 0; i-- {
+			if strings.HasPrefix(msgs[i], prefix) {
+				return feedSequence([]byte(msgs[i]))
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package websockets
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bakape/meguca/common"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestWriteEvent(t *testing.T) {
+	t.Parallel()
+
+	concat, err := common.EncodeMessage(common.MessageConcat,
+		[]string{`02"a"`, "4312", "34"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := [...]struct {
+		name, in, out string
+	}{
+		{"plain", `02"a"`, "data: 02\"a\"\n\n"},
+		{"sequence", "4312", "id: 12\ndata: 4312\n\n"},
+		{
+			"concatenated",
+			string(concat),
+			"id: 12\ndata: " + string(concat) + "\n\n",
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			_, err := writeEvent(w, []byte(c.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertEquals(t, w.Body.String(), c.out)
+		})
+	}
+}
+
+func TestEventClientOverflow(t *testing.T) {
+	t.Parallel()
+
+	c := newEventClient("::1")
+	for i := 0; i <= cap(c.send); i++ {
+		c.Send([]byte("34"))
+	}
+	if err := <-c.close; err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,167 @@
+// Live thread updates for board index and catalog pages
+
+package feeds
+
+import (
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/db"
+
+	"github.com/go-playground/log"
+)
+
+// Thread updates pending dispatch to board feeds
+var threadUpdates = make(chan threadUpdate, 64)
+
+// Thread created, modified or deleted on a board
+type threadUpdate struct {
+	deleted bool
+	id      uint64
+	board   string
+}
+
+// Pushes thread creation, modification and deletion to clients synced to a
+// board or the /all/ metaboard
+type boardFeed struct {
+	baseFeed
+	board string
+	send  chan []byte
+}
+
+func (f *boardFeed) start() {
+	go func() {
+		for {
+			select {
+			case c := <-f.add:
+				f.addClient(c)
+			case c := <-f.remove:
+				if f.removeClient(c) {
+					return
+				}
+			case msg := <-f.send:
+				f.sendToAll(msg)
+			}
+		}
+	}()
+}
+
+// Parse a database notification about a thread and queue it for dispatch
+func queueThreadUpdate(msg string, deleted bool) (err error) {
+	board, id, err := db.SplitBoardAndID(msg)
+	if err != nil {
+		return
+	}
+	threadUpdates <- threadUpdate{deleted, id, board}
+	return
+}
+
+// Batch thread updates and dispatch them to board feeds. Multiple updates of
+// the same thread within a batch result in a single message.
+func dispatchThreadUpdates() {
+	var (
+		t       ticker
+		changed = make(map[uint64]struct{})
+		deleted = make(map[uint64]string)
+	)
+	for {
+		select {
+		case u := <-threadUpdates:
+			if u.deleted {
+				delete(changed, u.id)
+				deleted[u.id] = u.board
+			} else {
+				changed[u.id] = struct{}{}
+			}
+			t.startIfPaused()
+		case <-t.C:
+			err := flushThreadUpdates(changed, deleted)
+			if err != nil {
+				log.Errorf("board feed: %s\n", err)
+			}
+			changed = make(map[uint64]struct{})
+			deleted = make(map[uint64]string)
+			t.pause()
+		}
+	}
+}
+
+func flushThreadUpdates(changed map[uint64]struct{}, deleted map[uint64]string,
+) (err error) {
+	feeds.mu.RLock()
+	subscribed := len(feeds.boardFeeds) != 0
+	feeds.mu.RUnlock()
+	if !subscribed {
+		return
+	}
+
+	var threads []common.Thread
+	if len(changed) != 0 {
+		ids := make([]uint64, 0, len(changed))
+		for id := range changed {
+			ids = append(ids, id)
+		}
+		var b common.Board
+		b, err = db.GetCatalogThreads(ids)
+		if err != nil {
+			return
+		}
+		threads = b.Threads
+	}
+
+	bufs, err := encodeThreadUpdates(threads, deleted)
+	if err != nil {
+		return
+	}
+
+	feeds.mu.RLock()
+	defer feeds.mu.RUnlock()
+	for board, buf := range bufs {
+		if f := feeds.boardFeeds[board]; f != nil {
+			f.send <- buf.flush()
+		}
+	}
+	return
+}
+
+// Encode thread updates into per-board message buffers. Every update is also
+// written to the /all/ metaboard and, if the board is part of it, the /b/
+// metaboard, unless hidden from these as NSFW.
+func encodeThreadUpdates(threads []common.Thread, deleted map[uint64]string,
+) (bufs map[string]*messageBuffer, err error) {
+	bufs = make(map[string]*messageBuffer)
+	write := func(board string, msg []byte) {
+		boards := [...]string{board, "all", "b"}
+		n := 3
+		switch {
+		case config.Get().HideNSFW && config.GetBoardConfigs(board).NSFW:
+			n = 1
+		case !db.IsBestBoard(board):
+			n = 2
+		}
+		for _, b := range boards[:n] {
+			buf := bufs[b]
+			if buf == nil {
+				buf = new(messageBuffer)
+				bufs[b] = buf
+			}
+			buf.write(msg)
+		}
+	}
+
+	var msg []byte
+	for _, t := range threads {
+		msg, err = common.EncodeMessage(common.MessageBoardThread, t)
+		if err != nil {
+			return
+		}
+		write(t.Board, msg)
+	}
+	for id, board := range deleted {
+		msg, err = common.EncodeMessage(common.MessageBoardThreadDeleted, id)
+		if err != nil {
+			return
+		}
+		write(board, msg)
+	}
+	return
+}
@@ -0,0 +1,55 @@
+package feeds
+
+import (
+	"testing"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/test"
+)
+
+func TestEncodeThreadUpdates(t *testing.T) {
+	if old := config.Get(); old != nil {
+		defer config.Set(*old)
+	}
+	err := config.Set(config.Configs{HideNSFW: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.SetBoardConfigs(config.BoardConfigs{
+		ID: "n",
+		BoardPublic: config.BoardPublic{
+			NSFW: true,
+		},
+	})
+	defer config.RemoveBoard("n")
+
+	threads := []common.Thread{
+		{
+			Board:     "a",
+			PostCount: 2,
+			Post: common.Post{
+				ID: 1,
+			},
+		},
+		{
+			Board:     "meta",
+			PostCount: 1,
+			Post: common.Post{
+				ID: 2,
+			},
+		},
+	}
+	bufs, err := encodeThreadUpdates(threads, map[uint64]string{3: "n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test.AssertEquals(t, len(bufs), 5)
+	test.AssertEquals(t, len(*bufs["all"]), 2)
+	msg := string(bufs["a"].flush())
+	test.AssertEquals(t, msg[:2], "33")
+	test.AssertEquals(t, string(bufs["b"].flush()),
+		string(bufs["meta"].flush()))
+	test.AssertEquals(t, string(bufs["n"].flush()), `33["463"]`)
+}
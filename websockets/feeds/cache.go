@@ -12,7 +12,7 @@ const retentionTime = 16 * time.Minute
 // Persists thread state for syncing clients to server feed
 type threadCache struct {
 	syncMessage
-	memoized []byte
+	memoized *common.Message
 }
 
 func retentionThreshold() int64 {
@@ -37,6 +37,7 @@ func newThreadCache(id uint64) (c threadCache, err error) {
 			c.Recent[p.ID] = cachedPost{
 				HasImage:  p.Image != nil,
 				Spoilered: p.Image != nil && p.Image.Spoiler,
+				Files:     len(p.Files),
 				Closed:    !p.Editing,
 				Time:      p.Time,
 				Body:      p.Body,
@@ -71,6 +72,7 @@ type syncMessage struct {
 type cachedPost struct {
 	HasImage  bool   `json:"has_image"`
 	Spoilered bool   `json:"spoilered"`
+	Files     int    `json:"files"`
 	Closed    bool   `json:"closed"`
 	Time      int64  `json:"-"`
 	Body      string `json:"body"`
@@ -80,16 +82,19 @@ type cachedPost struct {
 // feed. The client has to compare this state to it's own and resolve any
 // missing entries or conflicts.
 //
-// Returned buffer must not be modified.
-func (c *threadCache) getSyncMessage() ([]byte, error) {
+// The message is memoized together with its conversions to other protocol
+// versions.
+func (c *threadCache) getSyncMessage() (*common.Message, error) {
 	if c.memoized != nil {
 		return c.memoized, nil
 	}
 
-	var err error
-	c.memoized, err = common.EncodeMessage(common.MessageSynchronise,
-		c.syncMessage)
-	return c.memoized, err
+	buf, err := common.EncodeMessage(common.MessageSynchronise, c.syncMessage)
+	if err != nil {
+		return nil, err
+	}
+	c.memoized = common.NewMessage(buf)
+	return c.memoized, nil
 }
 
 // Clear memoized sync message JSON, if any
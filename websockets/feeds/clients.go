@@ -1,6 +1,7 @@
 package feeds
 
 import (
+	"net"
 	"sync"
 
 	"github.com/bakape/meguca/common"
@@ -69,8 +70,11 @@ func IPCount() int {
 
 // SyncClient adds a client to a the global client map and synchronizes to an
 // update feed, if any. If the client was already synced to another feed, it is
-// automatically unsubscribed.
-func SyncClient(cl common.Client, op uint64, board string) (*Feed, error) {
+// automatically unsubscribed. A non-zero lastSeq resumes synchronization
+// after the feed message with this sequence number.
+func SyncClient(cl common.Client, op uint64, board string, lastSeq uint64) (
+	*Feed, error,
+) {
 	clients.Lock()
 	old, ok := clients.clients[cl]
 	clients.clients[cl] = syncID{op, board}
@@ -79,12 +83,14 @@ func SyncClient(cl common.Client, op uint64, board string) (*Feed, error) {
 	if ok {
 		removeFromFeed(old.op, old.board, cl)
 	}
-	return addToFeed(op, board, cl)
+	return addToFeed(op, board, cl, lastSeq)
 }
 
-// RemoveClient removes a client from the global client map and any subscribed
-// to feed
+// RemoveClient removes a client from the global client map, any subscribed
+// to feed and thread watcher subscriptions
 func RemoveClient(cl common.Client) {
+	UnwatchThreads(cl)
+
 	clients.Lock()
 	old, ok := clients.clients[cl]
 	if ok {
@@ -109,28 +115,46 @@ func GetSync(cl common.Client) (synced bool, op uint64, board string) {
 	return
 }
 
-// GetByIPAndBoard retrieves all Clients that match the passed IP on a board
+// Returns a function, that matches IPs against ip, which can also be a CIDR
+// range
+func ipMatcher(ip string) func(string) bool {
+	_, n, err := net.ParseCIDR(ip)
+	if err != nil {
+		return func(s string) bool {
+			return s == ip
+		}
+	}
+	return func(s string) bool {
+		parsed := net.ParseIP(s)
+		return parsed != nil && n.Contains(parsed)
+	}
+}
+
+// GetByIPAndBoard retrieves all Clients that match the passed IP or CIDR range
+// on a board
 func GetByIPAndBoard(ip, board string) []common.Client {
 	clients.RLock()
 	defer clients.RUnlock()
 
+	match := ipMatcher(ip)
 	cls := make([]common.Client, 0, 16)
 	for cl, sync := range clients.clients {
-		if cl.IP() == ip && (board == "all" || board == "b" || sync.board == board) {
+		if match(cl.IP()) && (board == "all" || board == "b" || sync.board == board) {
 			cls = append(cls, cl)
 		}
 	}
 	return cls
 }
 
-// GetByIP returns all clients matching the specified IP
+// GetByIP returns all clients matching the specified IP or CIDR range
 func GetByIP(ip string) []common.Client {
 	clients.RLock()
 	defer clients.RUnlock()
 
+	match := ipMatcher(ip)
 	cls := make([]common.Client, 0, 16)
 	for cl := range clients.clients {
-		if cl.IP() == ip {
+		if match(cl.IP()) {
 			cls = append(cls, cl)
 		}
 	}
@@ -47,6 +47,10 @@ type Feed struct {
 	baseFeed
 	// Buffer of unsent messages
 	messageBuffer
+	// Recently sent messages for replaying to resuming clients
+	replay replayBuffer
+	// Add a client, that is resuming after the last message it received
+	resume chan resumeRequest
 	// Entire thread cached into memory
 	cache threadCache
 	// Propagates mesages to all listeners
@@ -61,10 +65,18 @@ type Feed struct {
 	spoilerImage chan message
 	// Set body of an open post
 	setOpenBody chan postBodyModMessage
+	// Replace body of an edited closed post
+	editPost chan postBodyModMessage
 	// Send message about post moderation
 	moderatePost chan moderationMessage
+	// Set synced IP count of another process
+	setRemoteCount chan remoteSyncCount
 	// Let sent sync counter
 	lastSyncCount syncCount
+	// Last synced IP count of this process published to other processes
+	lastLocalCount syncCount
+	// Synced IP counts of other processes by process ID
+	remoteCounts map[string]remoteSyncCount
 }
 
 // Start read existing posts into cache and start main loop
@@ -73,6 +85,7 @@ func (f *Feed) Start() (err error) {
 	if err != nil {
 		return
 	}
+	f.replay.init()
 
 	go func() {
 		// Stop the timer, if there are no messages and resume on new ones.
@@ -88,34 +101,42 @@ func (f *Feed) Start() (err error) {
 
 			case <-evictionTimer.C:
 				f.cache.evict()
+				f.expireRemoteCounts()
+				// Refresh count in other processes
+				publishSyncCount(f.id, f.lastLocalCount)
+				f.sendIPCount()
 
 			// Add client
 			case c := <-f.add:
 				f.addClient(c)
+				f.syncClient(c)
+				f.sendIPCount()
 
-				msg, err := f.cache.getSyncMessage()
-				if err != nil {
-					log.Errorf("sync message: %s", err)
-				}
-				c.Send(msg)
-
+			case req := <-f.resume:
+				f.addClient(req.client)
+				f.resumeClient(req.client, req.lastSeq)
 				f.sendIPCount()
 
 			// Remove client and close feed, if no clients left
 			case c := <-f.remove:
 				if f.removeClient(c) {
+					publishSyncCount(f.id, syncCount{})
 					return
 				}
 
 				f.sendIPCount()
 
+			case c := <-f.setRemoteCount:
+				f.remoteCounts[c.node] = c
+				f.sendIPCount()
+
 			// Buffer external message and prepare for sending to all clients
 			case msg := <-f.send:
 				f.bufferMessage(msg)
 
 			// Send any buffered messages to any listening clients
 			case <-f.C:
-				if buf := f.flush(); buf == nil {
+				if buf := f.flushSequenced(); buf == nil {
 					f.pause()
 				} else {
 					f.sendToAll(buf)
@@ -128,6 +149,7 @@ func (f *Feed) Start() (err error) {
 					*p = cachedPost{
 						HasImage:  src.Image != nil,
 						Spoilered: src.Image != nil && src.Image.Spoiler,
+						Files:     len(src.Files),
 						Time:      src.Time,
 						Closed:    !src.Editing,
 						Body:      src.Body,
@@ -147,8 +169,13 @@ func (f *Feed) Start() (err error) {
 
 			case msg := <-f.insertImage:
 				f.modifyPost(msg.message, func(p *cachedPost) {
-					p.HasImage = true
-					p.Spoilered = msg.spoilered
+					// Further files are appended to the post's additional files
+					if p.HasImage {
+						p.Files++
+					} else {
+						p.HasImage = true
+						p.Spoilered = msg.spoilered
+					}
 				})
 
 			case msg := <-f.spoilerImage:
@@ -156,6 +183,16 @@ func (f *Feed) Start() (err error) {
 					p.Spoilered = true
 				})
 
+			// Replace the body of an edited post, if cached, and propagate
+			case msg := <-f.editPost:
+				f.startIfPaused()
+				if p, ok := f.cache.Recent[msg.id]; ok {
+					p.Body = msg.body
+					f.cache.Recent[msg.id] = p
+					f.cache.clearMemoized()
+				}
+				f.writeSequenced(msg.msg)
+
 			case msg := <-f.closePost:
 				f.modifyPost(msg, func(p *cachedPost) {
 					p.Closed = true
@@ -168,9 +205,10 @@ func (f *Feed) Start() (err error) {
 					case common.PurgePost:
 						p.Body = ""
 						fallthrough
-					case common.DeleteImage:
+					case common.DeleteImage, common.SelfDeleteImage:
 						p.HasImage = false
 						p.Spoilered = false
+						p.Files = 0
 					case common.SpoilerImage:
 						p.Spoilered = true
 					}
@@ -192,24 +230,109 @@ func (f *Feed) modifyPost(msg message, fn func(*cachedPost)) {
 	f.cache.Recent[msg.id] = p
 
 	if msg.msg != nil {
-		f.write(msg.msg)
+		f.writeSequenced(msg.msg)
 	}
 	f.cache.clearMemoized()
 }
 
-// Send a message to all listening clients
-func (f *Feed) Send(msg []byte) {
+// Send a client the current state of the feed and the sequence number of the
+// last message reflected in it
+func (f *Feed) syncClient(c common.Client) {
+	msg, err := f.cache.getSyncMessage()
+	if err != nil {
+		log.Errorf("sync message: %s", err)
+		return
+	}
+	c.SendMessage(msg)
+	c.Send(f.encodeSequence())
+}
+
+// Replay to a client all messages it missed after lastSeq followed by the
+// sequence number of the last replayed message. If these are no longer
+// buffered, instruct it to resynchronise from scratch and send it the full
+// state of the feed.
+func (f *Feed) resumeClient(c common.Client, lastSeq uint64) {
+	missed, ok := f.replay.since(lastSeq)
+	if !ok {
+		msg, _ := common.EncodeMessage(common.MessageResync, nil)
+		c.Send(msg)
+		f.syncClient(c)
+		return
+	}
+
+	if len(missed) != 0 {
+		msg, err := common.EncodeMessage(common.MessageConcat, missed)
+		if err != nil {
+			log.Errorf("replay messages: %s", err)
+			return
+		}
+		c.Send(msg)
+	}
+	// Messages not yet flushed are sent with the next flush
+	msg, _ := common.EncodeMessage(common.MessageFeedSequence,
+		f.replay.flushed)
+	c.Send(msg)
+}
+
+// Buffer a message for sending and assign it the next sequence number
+func (f *Feed) writeSequenced(msg []byte) {
+	f.replay.push(msg)
+	f.write(msg)
+}
+
+// Flush buffered messages followed by the sequence number of the last
+// sequenced message, if it changed
+func (f *Feed) flushSequenced() []byte {
+	if len(f.messageBuffer) == 0 {
+		return nil
+	}
+	if f.replay.flushed != f.replay.seq {
+		f.replay.flushed = f.replay.seq
+		f.write(f.encodeSequence())
+	}
+	return f.flush()
+}
+
+func (f *Feed) encodeSequence() []byte {
+	msg, _ := common.EncodeMessage(common.MessageFeedSequence, f.replay.seq)
+	return msg
+}
+
+// Send a message to all listening clients in this process
+func (f *Feed) _send(msg []byte) {
 	f.send <- msg
 }
 
 // Buffer a message to be sent on the next tick
 func (f *Feed) bufferMessage(msg []byte) {
 	f.startIfPaused()
-	f.write(msg)
+	f.writeSequenced(msg)
 }
 
-// Send unique IP count to all connected clients
+// Send unique IP count across all processes to all connected clients
 func (f *Feed) sendIPCount() {
+	local := f.countIPs()
+	if local != f.lastLocalCount {
+		f.lastLocalCount = local
+		publishSyncCount(f.id, local)
+	}
+
+	new := local
+	for _, c := range f.remoteCounts {
+		new.Active += c.Active
+		new.Total += c.Total
+	}
+	if new != f.lastSyncCount {
+		f.lastSyncCount = new
+		// Only relevant at the time of sending, so not replayed
+		msg, _ := common.EncodeMessage(common.MessageSyncCount, new)
+		f.startIfPaused()
+		f.write(msg)
+	}
+}
+
+// Count unique IPs of clients synced to the feed in this process
+func (f *Feed) countIPs() syncCount {
 	var active int
 	ips := make(map[string]struct{}, len(f.clients))
 	pastHour := time.Now().Add(-time.Hour).Unix()
@@ -222,20 +345,35 @@ func (f *Feed) sendIPCount() {
 		ips[ip] = struct{}{}
 	}
 
-	new := syncCount{
+	return syncCount{
 		Active: active,
 		Total:  len(ips),
 	}
-	if new != f.lastSyncCount {
-		f.lastSyncCount = new
-		msg, _ := common.EncodeMessage(common.MessageSyncCount, new)
-		f.bufferMessage(msg)
+}
+
+// Remove IP counts of processes, that stopped refreshing them
+func (f *Feed) expireRemoteCounts() {
+	threshold := time.Now().Add(-remoteCountExpiry)
+	for node, c := range f.remoteCounts {
+		if c.updated.Before(threshold) {
+			delete(f.remoteCounts, node)
+		}
 	}
 }
 
 // InsertPost inserts a new post into the thread or reclaim an open post after disconnect
 // and propagate to listeners
 func (f *Feed) InsertPost(p common.Post, msg []byte) {
+	f._insertPost(p, msg)
+	publish(feedEvent{
+		Type:    insertPostEvent,
+		Thread:  f.id,
+		Post:    &p,
+		Message: string(msg),
+	})
+}
+
+func (f *Feed) _insertPost(p common.Post, msg []byte) {
 	f.insertPost <- postCreationMessage{
 		message: message{
 			id:  p.ID,
@@ -247,6 +385,17 @@ func (f *Feed) InsertPost(p common.Post, msg []byte) {
 
 // InsertImage inserts an image into an already allocated post
 func (f *Feed) InsertImage(id uint64, spoilered bool, msg []byte) {
+	f._insertImage(id, spoilered, msg)
+	publish(feedEvent{
+		Type:      insertImageEvent,
+		Thread:    f.id,
+		ID:        id,
+		Spoilered: spoilered,
+		Message:   string(msg),
+	})
+}
+
+func (f *Feed) _insertImage(id uint64, spoilered bool, msg []byte) {
 	f.insertImage <- imageInsertionMessage{
 		message: message{
 			id:  id,
@@ -256,8 +405,8 @@ func (f *Feed) InsertImage(id uint64, spoilered bool, msg []byte) {
 	}
 }
 
-// ClosePost closes a feed's post
-func (f *Feed) ClosePost(id uint64, msg []byte) {
+// Close a feed's post in this process
+func (f *Feed) _closePost(id uint64, msg []byte) {
 	f.closePost <- message{
 		id:  id,
 		msg: msg,
@@ -266,6 +415,16 @@ func (f *Feed) ClosePost(id uint64, msg []byte) {
 
 // SpoilerImage spoilers a feed's image
 func (f *Feed) SpoilerImage(id uint64, msg []byte) {
+	f._spoilerImage(id, msg)
+	publish(feedEvent{
+		Type:    spoilerImageEvent,
+		Thread:  f.id,
+		ID:      id,
+		Message: string(msg),
+	})
+}
+
+func (f *Feed) _spoilerImage(id uint64, msg []byte) {
 	f.spoilerImage <- message{id, msg}
 }
 
@@ -283,6 +442,17 @@ func (f *Feed) _moderatePost(id uint64, msg []byte,
 
 // SetOpenBody sets the body of an open post and send update message to clients
 func (f *Feed) SetOpenBody(id uint64, body string, msg []byte) {
+	f._setOpenBody(id, body, msg)
+	publish(feedEvent{
+		Type:    setOpenBodyEvent,
+		Thread:  f.id,
+		ID:      id,
+		Body:    body,
+		Message: string(msg),
+	})
+}
+
+func (f *Feed) _setOpenBody(id uint64, body string, msg []byte) {
 	f.setOpenBody <- postBodyModMessage{
 		message: message{
 			id:  id,
@@ -291,3 +461,14 @@ func (f *Feed) SetOpenBody(id uint64, body string, msg []byte) {
 		body: body,
 	}
 }
+
+// Replace the body of an edited post in this process
+func (f *Feed) _editPost(id uint64, body string, msg []byte) {
+	f.editPost <- postBodyModMessage{
+		message: message{
+			id:  id,
+			msg: msg,
+		},
+		body: body,
+	}
+}
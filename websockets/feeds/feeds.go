@@ -6,33 +6,58 @@ package feeds
 import (
 	"errors"
 	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
 	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/metrics"
 	"sync"
 )
 
 // Contains and manages all active update feeds
 var feeds = feedMap{
 	// 64 len map to avoid some possible reallocation as the server starts
-	feeds:   make(map[uint64]*Feed, 64),
-	tvFeeds: make(map[string]*tvFeed, 64),
+	feeds:      make(map[uint64]*Feed, 64),
+	tvFeeds:    make(map[string]*tvFeed, 64),
+	boardFeeds: make(map[string]*boardFeed, 64),
 }
 
 // Export to avoid circular dependency
 func init() {
 	common.SendTo = SendTo
 	common.ClosePost = ClosePost
+
+	metrics.Register("meguca_websocket_clients",
+		"Websocket clients synchronized to a board or thread",
+		metrics.GaugeFunc(func() float64 {
+			clients.RLock()
+			defer clients.RUnlock()
+			return float64(len(clients.clients))
+		}))
+	metrics.Register("meguca_websocket_board_feeds",
+		"Active board and catalog update feeds",
+		metrics.GaugeFunc(func() float64 {
+			feeds.mu.RLock()
+			defer feeds.mu.RUnlock()
+			return float64(len(feeds.boardFeeds))
+		}))
+	metrics.Register("meguca_websocket_feeds", "Active thread update feeds",
+		metrics.GaugeFunc(func() float64 {
+			feeds.mu.RLock()
+			defer feeds.mu.RUnlock()
+			return float64(len(feeds.feeds))
+		}))
 }
 
 // Container for managing client<->update-feed assignment and interaction
 type feedMap struct {
-	feeds   map[uint64]*Feed
-	tvFeeds map[string]*tvFeed
-	mu      sync.RWMutex
+	feeds      map[uint64]*Feed
+	tvFeeds    map[string]*tvFeed
+	boardFeeds map[string]*boardFeed
+	mu         sync.RWMutex
 }
 
 // Add client to feed and send it the current status of the feed for
 // synchronization to the feed's internal state
-func addToFeed(id uint64, board string, c common.Client) (
+func addToFeed(id uint64, board string, c common.Client, lastSeq uint64) (
 	feed *Feed, err error,
 ) {
 	feeds.mu.Lock()
@@ -44,15 +69,19 @@ func addToFeed(id uint64, board string, c common.Client) (
 		feed, ok = feeds.feeds[id]
 		if !ok {
 			feed = &Feed{
-				id:            id,
-				send:          make(chan []byte),
-				insertPost:    make(chan postCreationMessage),
-				closePost:     make(chan message),
-				spoilerImage:  make(chan message),
-				moderatePost:  make(chan moderationMessage),
-				setOpenBody:   make(chan postBodyModMessage),
-				insertImage:   make(chan imageInsertionMessage),
-				messageBuffer: make([]string, 0, 64),
+				id:             id,
+				send:           make(chan []byte),
+				insertPost:     make(chan postCreationMessage),
+				closePost:      make(chan message),
+				spoilerImage:   make(chan message),
+				moderatePost:   make(chan moderationMessage),
+				setOpenBody:    make(chan postBodyModMessage),
+				editPost:       make(chan postBodyModMessage),
+				insertImage:    make(chan imageInsertionMessage),
+				resume:         make(chan resumeRequest),
+				setRemoteCount: make(chan remoteSyncCount),
+				remoteCounts:   make(map[string]remoteSyncCount),
+				messageBuffer:  make([]string, 0, 64),
 			}
 
 			feed.baseFeed.init()
@@ -62,7 +91,23 @@ func addToFeed(id uint64, board string, c common.Client) (
 				return
 			}
 		}
-		feed.add <- c
+		if lastSeq != 0 {
+			feed.resume <- resumeRequest{c, lastSeq}
+		} else {
+			feed.add <- c
+		}
+	} else {
+		bf, ok := feeds.boardFeeds[board]
+		if !ok {
+			bf = &boardFeed{
+				board: board,
+				send:  make(chan []byte),
+			}
+			bf.init()
+			feeds.boardFeeds[board] = bf
+			bf.start()
+		}
+		bf.add <- c
 	}
 
 	return
@@ -106,6 +151,13 @@ func removeFromFeed(id uint64, board string, c common.Client) {
 		}
 	}
 
+	if feed := feeds.boardFeeds[board]; id == 0 && feed != nil {
+		feed.remove <- c
+		if nil != <-feed.remove {
+			delete(feeds.boardFeeds, feed.board)
+		}
+	}
+
 	if feed := feeds.tvFeeds[board]; feed != nil {
 		feed.remove <- c
 		if nil != <-feed.remove {
@@ -114,12 +166,17 @@ func removeFromFeed(id uint64, board string, c common.Client) {
 	}
 }
 
-// SendTo sends a message to a feed, if it exists
+// SendTo sends a message to a feed in all processes, if it exists
 func SendTo(id uint64, msg []byte) {
 	sendIfExists(id, func(f *Feed) error {
-		f.Send(msg)
+		f._send(msg)
 		return nil
 	})
+	publish(feedEvent{
+		Type:    sendEvent,
+		Thread:  id,
+		Message: string(msg),
+	})
 }
 
 // Run a send function of a feed, if it exists
@@ -133,16 +190,24 @@ func sendIfExists(id uint64, fn func(*Feed) error) error {
 	return nil
 }
 
-// InsertPostInto inserts a post into a tread feed, if it exists. Only use for
-// already closed posts.
+// InsertPostInto inserts a post into a tread feed in all processes, if it
+// exists. Only use for already closed posts.
 func InsertPostInto(post common.StandalonePost, msg []byte) {
 	sendIfExists(post.OP, func(f *Feed) error {
-		f.InsertPost(post.Post, msg)
+		f._insertPost(post.Post, msg)
 		return nil
 	})
+	notifyQuoted(post.ID, post.OP, post.Links)
+	publish(feedEvent{
+		Type:    insertPostEvent,
+		Thread:  post.OP,
+		Post:    &post.Post,
+		Message: string(msg),
+	})
+	sendCrossBoardBacklinks(post.ID, post.OP, post.Board, post.Links)
 }
 
-// ClosePost closes a post in a feed, if it exists
+// ClosePost closes a post in a feed in all processes, if it exists
 func ClosePost(id, op uint64, links []common.Link, commands []common.Command,
 ) (err error) {
 	msg, err := common.EncodeMessage(common.MessageClosePost, struct {
@@ -159,15 +224,111 @@ func ClosePost(id, op uint64, links []common.Link, commands []common.Command,
 	}
 
 	sendIfExists(op, func(f *Feed) error {
-		f.ClosePost(id, msg)
+		f._closePost(id, msg)
 		return nil
 	})
+	publish(feedEvent{
+		Type:    closePostEvent,
+		Thread:  op,
+		ID:      id,
+		Links:   links,
+		Message: string(msg),
+	})
 
+	err = notifyQuoted(id, op, links)
+	if err != nil {
+		return
+	}
+	return sendCrossBoardBacklinks(id, op, "", links)
+}
+
+// EditPost replaces the body of an edited closed post in a thread feed in all
+// processes, if it exists
+func EditPost(id, op uint64, body string, msg []byte) {
+	sendIfExists(op, func(f *Feed) error {
+		f._editPost(id, body, msg)
+		return nil
+	})
+	publish(feedEvent{
+		Type:    editPostEvent,
+		Thread:  op,
+		ID:      id,
+		Body:    body,
+		Message: string(msg),
+	})
+}
+
+// Send links to posts on other boards to the feeds of the linked threads in
+// all processes, so their clients can render backlinks. The board of the
+// linking post is looked up, if empty.
+func sendCrossBoardBacklinks(id, op uint64, board string, links []common.Link,
+) (err error) {
+	for _, l := range links {
+		// Links to other boards are always cross-thread
+		if l.OP == op {
+			continue
+		}
+		if board == "" {
+			board, _, err = db.GetPostParenthood(id)
+			if err != nil {
+				return
+			}
+		}
+		if l.Board == board {
+			continue
+		}
+
+		var msg []byte
+		msg, err = common.EncodeMessage(common.MessageBacklink,
+			common.Backlink{
+				Link: common.Link{
+					ID:    id,
+					OP:    op,
+					Board: board,
+				},
+				Target: l.ID,
+			})
+		if err != nil {
+			return
+		}
+		SendTo(l.OP, msg)
+	}
 	return
 }
 
 // Initialize internal runtime
 func Init() (err error) {
+	if config.Server.Server.MultiNode {
+		err = startPublishing()
+		if err != nil {
+			return
+		}
+	}
+	go dispatchThreadUpdates()
+	err = db.Listen("thread_updated", func(msg string) error {
+		return queueThreadUpdate(msg, false)
+	})
+	if err != nil {
+		return
+	}
+	err = db.Listen("thread_deleted", func(msg string) (err error) {
+		_, id, err := db.SplitBoardAndID(msg)
+		if err != nil {
+			return
+		}
+		err = handleWatchedThreadDeletion(id)
+		if err != nil {
+			return
+		}
+		return queueThreadUpdate(msg, true)
+	})
+	if err != nil {
+		return
+	}
+	err = db.Listen("new_post_in_thread", handleWatchedPostCount)
+	if err != nil {
+		return
+	}
 	return db.Listen("post_moderated", func(msg string) (err error) {
 		return handlePostModeration(msg)
 	})
@@ -204,4 +365,5 @@ func Clear() {
 	feeds.mu.Lock()
 	defer feeds.mu.Unlock()
 	feeds.feeds = make(map[uint64]*Feed, 32)
+	feeds.boardFeeds = make(map[string]*boardFeed, 32)
 }
@@ -0,0 +1,179 @@
+// Propagation of feed updates between processes sharing the database
+
+package feeds
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+
+	"github.com/go-playground/log"
+)
+
+// PostgreSQL notification channel of feed events
+const feedEventChannel = "feed_events"
+
+// IP counts of other processes not refreshed for this long are discarded
+const remoteCountExpiry = 3 * time.Minute
+
+var (
+	// ID of this process. Used to ignore events published by itself.
+	nodeID string
+
+	// Enabled, if feed events are propagated to other processes
+	publishing bool
+
+	// Encoded events pending publishing. Published in order from a single
+	// goroutine, so other processes receive them in the same order.
+	pendingEvents = make(chan []byte, 1<<10)
+
+	errMissingPost = errors.New("feed event: missing post")
+)
+
+type feedEventType uint8
+
+const (
+	insertPostEvent feedEventType = iota
+	insertImageEvent
+	closePostEvent
+	spoilerImageEvent
+	setOpenBodyEvent
+	sendEvent
+	syncCountEvent
+	editPostEvent
+)
+
+// Modification of a thread feed made in one process to be applied in all
+// other processes
+type feedEvent struct {
+	Type      feedEventType `json:"type"`
+	Spoilered bool          `json:"spoilered,omitempty"`
+	Thread    uint64        `json:"thread"`
+	ID        uint64        `json:"id,omitempty"`
+	Node      string        `json:"node"`
+	Body      string        `json:"body,omitempty"`
+	// Encoded message to send to clients
+	Message string        `json:"msg,omitempty"`
+	Post    *common.Post  `json:"post,omitempty"`
+	Links   []common.Link `json:"links,omitempty"`
+	Count   *syncCount    `json:"count,omitempty"`
+}
+
+// Synced IP count of a thread feed in another process
+type remoteSyncCount struct {
+	syncCount
+	node    string
+	updated time.Time
+}
+
+// Start propagating feed events between processes
+func startPublishing() (err error) {
+	nodeID, err = auth.RandomID(16)
+	if err != nil {
+		return
+	}
+	err = db.Subscribe(feedEventChannel, handleFeedEvent)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		for buf := range pendingEvents {
+			err := db.Publish(feedEventChannel, buf)
+			if err != nil {
+				log.Errorf("publishing feed event: %s\n", err)
+			}
+		}
+	}()
+	publishing = true
+	return
+}
+
+// Queue an event for propagation to other processes
+func publish(e feedEvent) {
+	if !publishing {
+		return
+	}
+	e.Node = nodeID
+	buf, err := json.Marshal(e)
+	if err != nil {
+		log.Errorf("encoding feed event: %s\n", err)
+		return
+	}
+	pendingEvents <- buf
+}
+
+// Propagate the synced IP count of a feed in this process
+func publishSyncCount(thread uint64, c syncCount) {
+	publish(feedEvent{
+		Type:   syncCountEvent,
+		Thread: thread,
+		Count:  &c,
+	})
+}
+
+// Apply an event published by another process
+func handleFeedEvent(buf []byte) (err error) {
+	var e feedEvent
+	err = json.Unmarshal(buf, &e)
+	if err != nil || e.Node == nodeID {
+		return
+	}
+	var msg []byte
+	if e.Message != "" {
+		msg = []byte(e.Message)
+	}
+
+	// Keep open post bodies available to thread reads and newly created
+	// feeds in this process
+	switch e.Type {
+	case insertPostEvent:
+		if e.Post == nil {
+			return errMissingPost
+		}
+		if e.Post.Editing {
+			err = db.SetOpenBody(e.Post.ID, []byte(e.Post.Body))
+		} else {
+			err = notifyQuoted(e.Post.ID, e.Thread, e.Post.Links)
+		}
+	case setOpenBodyEvent:
+		err = db.SetOpenBody(e.ID, []byte(e.Body))
+	case closePostEvent:
+		err = notifyQuoted(e.ID, e.Thread, e.Links)
+	}
+	if err != nil {
+		return
+	}
+
+	return sendIfExists(e.Thread, func(f *Feed) error {
+		switch e.Type {
+		case insertPostEvent:
+			f._insertPost(*e.Post, msg)
+		case insertImageEvent:
+			f._insertImage(e.ID, e.Spoilered, msg)
+		case closePostEvent:
+			f._closePost(e.ID, msg)
+		case spoilerImageEvent:
+			f._spoilerImage(e.ID, msg)
+		case setOpenBodyEvent:
+			f._setOpenBody(e.ID, e.Body, msg)
+		case editPostEvent:
+			f._editPost(e.ID, e.Body, msg)
+		case sendEvent:
+			f._send(msg)
+		case syncCountEvent:
+			if e.Count != nil {
+				f.setRemoteCount <- remoteSyncCount{
+					syncCount: *e.Count,
+					node:      e.Node,
+					updated:   time.Now(),
+				}
+			}
+		}
+		return nil
+	})
+}
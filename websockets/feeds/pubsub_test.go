@@ -0,0 +1,49 @@
+package feeds
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bakape/meguca/test"
+)
+
+func TestRemoteSyncCounts(t *testing.T) {
+	t.Parallel()
+
+	f := Feed{
+		remoteCounts: map[string]remoteSyncCount{
+			"a": {syncCount{1, 2}, "a", time.Now()},
+			"b": {syncCount{1, 1}, "b", time.Now().Add(-time.Hour)},
+		},
+	}
+	f.baseFeed.init()
+	defer f.pause()
+
+	f.sendIPCount()
+	test.AssertEquals(t, f.lastSyncCount, syncCount{2, 3})
+
+	f.expireRemoteCounts()
+	f.sendIPCount()
+	test.AssertEquals(t, f.lastSyncCount, syncCount{1, 2})
+	if f.flush() == nil {
+		t.Fatal("sync count not sent")
+	}
+}
+
+func TestIgnoreOwnFeedEvents(t *testing.T) {
+	t.Parallel()
+
+	buf, err := json.Marshal(feedEvent{
+		Type: insertPostEvent,
+		Node: nodeID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Would fail on a missing post otherwise
+	err = handleFeedEvent(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+package feeds
+
+import (
+	"time"
+
+	"github.com/bakape/meguca/common"
+)
+
+// Number of most recent messages of a feed kept for replaying to resuming
+// clients
+const replayBufferSize = 1 << 12
+
+// Feed message with its sequence number
+type sequencedMessage struct {
+	seq uint64
+	msg string
+}
+
+// Client resuming synchronization to a feed after the last message it
+// received
+type resumeRequest struct {
+	client  common.Client
+	lastSeq uint64
+}
+
+// Bounded ring buffer of the most recent messages of a feed. Allows clients
+// to resume after connection loss by replaying only the messages they missed.
+type replayBuffer struct {
+	// Sequence number of the last message written
+	seq uint64
+	// Sequence number of the last message sent to clients
+	flushed uint64
+	// Number of messages in the buffer
+	n        int
+	messages [replayBufferSize]sequencedMessage
+}
+
+// Sequence numbers start at the creation time of the feed in microseconds,
+// so they keep increasing across feed restarts and stay within the integer
+// precision of JS.
+func (r *replayBuffer) init() {
+	r.seq = uint64(time.Now().UnixNano() / int64(time.Microsecond))
+	r.flushed = r.seq
+}
+
+// Append a message to the buffer and assign it the next sequence number
+func (r *replayBuffer) push(msg []byte) {
+	r.seq++
+	r.messages[r.seq%replayBufferSize] = sequencedMessage{r.seq, string(msg)}
+	if r.n < replayBufferSize {
+		r.n++
+	}
+}
+
+// Return all messages already sent to clients after sequence number last.
+// ok is false, if these are no longer all buffered or last is invalid.
+func (r *replayBuffer) since(last uint64) (missed []string, ok bool) {
+	oldest := r.seq - uint64(r.n) + 1
+	if last > r.seq || last+1 < oldest {
+		return
+	}
+	for s := last + 1; s <= r.flushed; s++ {
+		missed = append(missed, r.messages[s%replayBufferSize].msg)
+	}
+	return missed, true
+}
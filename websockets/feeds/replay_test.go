@@ -0,0 +1,83 @@
+package feeds
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/bakape/meguca/test"
+)
+
+func TestReplayBuffer(t *testing.T) {
+	t.Parallel()
+
+	var r replayBuffer
+	r.init()
+	start := r.seq
+
+	missed, ok := r.since(start)
+	if !ok || len(missed) != 0 {
+		t.Fatal("nothing to replay expected")
+	}
+
+	for i := 0; i < 3; i++ {
+		r.push([]byte{'a' + byte(i)})
+	}
+	r.flushed = r.seq - 1
+
+	// Unflushed messages are sent with the next flush
+	missed, ok = r.since(start)
+	if !ok {
+		t.Fatal("gap not replayable")
+	}
+	test.AssertEquals(t, missed, []string{"a", "b"})
+
+	// From the future or a previous feed
+	for _, seq := range [...]uint64{r.seq + 1, start - 1} {
+		if _, ok := r.since(seq); ok {
+			t.Fatalf("replayed from %d", seq)
+		}
+	}
+}
+
+func TestReplayBufferOverflow(t *testing.T) {
+	t.Parallel()
+
+	var r replayBuffer
+	r.init()
+	start := r.seq
+	for i := 0; i < replayBufferSize+1; i++ {
+		r.push([]byte(strconv.Itoa(i)))
+	}
+	r.flushed = r.seq
+
+	if _, ok := r.since(start); ok {
+		t.Fatal("overwritten messages replayed")
+	}
+	missed, ok := r.since(start + 1)
+	if !ok {
+		t.Fatal("gap not replayable")
+	}
+	test.AssertEquals(t, len(missed), replayBufferSize)
+	test.AssertEquals(t, missed[0], "1")
+}
+
+func TestFlushSequenced(t *testing.T) {
+	t.Parallel()
+
+	f := Feed{}
+	f.replay.init()
+	seq := f.replay.seq
+
+	// Not sequenced
+	f.write([]byte("a"))
+	test.AssertEquals(t, string(f.flushSequenced()), `33["a"]`)
+
+	f.writeSequenced([]byte("b"))
+	std := `33["b","43` + strconv.FormatUint(seq+1, 10) + `"]`
+	test.AssertEquals(t, string(f.flushSequenced()), std)
+	test.AssertEquals(t, f.replay.flushed, seq+1)
+
+	if f.flushSequenced() != nil {
+		t.Fatal("empty flush not nil")
+	}
+}
@@ -83,9 +83,11 @@ func (b *baseFeed) removeClient(c common.Client) bool {
 	return true
 }
 
-// Send a message to all connected clients
+// Send a message to all connected clients. The message is converted once for
+// each protocol version in use.
 func (b *baseFeed) sendToAll(msg []byte) {
+	m := common.NewMessage(msg)
 	for c := range b.clients {
-		c.Send(msg)
+		c.SendMessage(m)
 	}
 }
@@ -0,0 +1,192 @@
+// Thread watcher subscriptions. Clients register the threads they watch and
+// their own posts in them and receive post count changes, thread deletions and
+// replies to their posts without being synced to the thread feeds.
+
+package feeds
+
+import (
+	"sync"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+)
+
+// Maximum number of own posts a client can watch for replies
+const maxWatchedPosts = 1000
+
+var (
+	watchers = struct {
+		sync.RWMutex
+		// Clients by watched thread and post IDs
+		threads, posts map[uint64]map[common.Client]struct{}
+		// Watched thread and post IDs by client
+		clients map[common.Client]watchedIDs
+	}{
+		threads: make(map[uint64]map[common.Client]struct{}),
+		posts:   make(map[uint64]map[common.Client]struct{}),
+		clients: make(map[common.Client]watchedIDs),
+	}
+
+	errTooManyWatchedPosts = common.ErrInvalidInput("too many watched posts")
+)
+
+type watchedIDs struct {
+	threads, posts []uint64
+}
+
+// Reply to a watched post
+type quoteMessage struct {
+	ID     uint64 `json:"id"`
+	OP     uint64 `json:"op"`
+	Target uint64 `json:"target"`
+}
+
+// WatchThreads replaces the set of threads and own posts watched by a client.
+// counts maps thread IDs to the post counts known to the client. Returns the
+// difference to the actual post counts.
+func WatchThreads(c common.Client, counts map[uint64]uint64, posts []uint64,
+) (diff db.ThreadPostCountDiff, err error) {
+	if len(posts) > maxWatchedPosts {
+		err = errTooManyWatchedPosts
+		return
+	}
+	diff, err = db.DiffThreadPostCounts(counts)
+	if err != nil {
+		return
+	}
+
+	deleted := make(map[uint64]struct{}, len(diff.Deleted))
+	for _, id := range diff.Deleted {
+		deleted[id] = struct{}{}
+	}
+	ids := watchedIDs{
+		threads: make([]uint64, 0, len(counts)),
+		posts:   posts,
+	}
+	for id := range counts {
+		if _, ok := deleted[id]; !ok {
+			ids.threads = append(ids.threads, id)
+		}
+	}
+
+	watchers.Lock()
+	defer watchers.Unlock()
+
+	unwatch(c)
+	for _, id := range ids.threads {
+		addWatcher(watchers.threads, id, c)
+	}
+	for _, id := range ids.posts {
+		addWatcher(watchers.posts, id, c)
+	}
+	watchers.clients[c] = ids
+	return
+}
+
+// UnwatchThreads removes all thread watcher subscriptions of a client
+func UnwatchThreads(c common.Client) {
+	watchers.Lock()
+	defer watchers.Unlock()
+	unwatch(c)
+}
+
+// Requires lock
+func unwatch(c common.Client) {
+	ids, ok := watchers.clients[c]
+	if !ok {
+		return
+	}
+	for _, id := range ids.threads {
+		removeWatcher(watchers.threads, id, c)
+	}
+	for _, id := range ids.posts {
+		removeWatcher(watchers.posts, id, c)
+	}
+	delete(watchers.clients, c)
+}
+
+func addWatcher(m map[uint64]map[common.Client]struct{}, id uint64,
+	c common.Client,
+) {
+	set := m[id]
+	if set == nil {
+		set = make(map[common.Client]struct{})
+		m[id] = set
+	}
+	set[c] = struct{}{}
+}
+
+func removeWatcher(m map[uint64]map[common.Client]struct{}, id uint64,
+	c common.Client,
+) {
+	set := m[id]
+	delete(set, c)
+	if len(set) == 0 {
+		delete(m, id)
+	}
+}
+
+// Send a message to all clients watching a thread or post ID. Requires lock.
+func sendToWatchers(m map[uint64]map[common.Client]struct{}, id uint64,
+	typ common.MessageType, data interface{},
+) (err error) {
+	set := m[id]
+	if len(set) == 0 {
+		return
+	}
+	msg, err := common.EncodeMessage(typ, data)
+	if err != nil {
+		return
+	}
+	wrapped := common.NewMessage(msg)
+	for c := range set {
+		c.SendMessage(wrapped)
+	}
+	return
+}
+
+// Propagate the new post count of a thread to its watchers
+func handleWatchedPostCount(msg string) (err error) {
+	arr, err := db.SplitUint64s(msg, 2)
+	if err != nil {
+		return
+	}
+	op, count := arr[0], arr[1]
+
+	watchers.RLock()
+	defer watchers.RUnlock()
+	return sendToWatchers(watchers.threads, op, common.MessageWatchThreads,
+		db.ThreadPostCountDiff{
+			Changed: map[uint64]uint64{op: count},
+			Deleted: []uint64{},
+		})
+}
+
+// Notify watchers of a deleted thread and stop watching it
+func handleWatchedThreadDeletion(id uint64) (err error) {
+	watchers.Lock()
+	defer watchers.Unlock()
+
+	err = sendToWatchers(watchers.threads, id, common.MessageWatchThreads,
+		db.ThreadPostCountDiff{
+			Changed: map[uint64]uint64{},
+			Deleted: []uint64{id},
+		})
+	delete(watchers.threads, id)
+	return
+}
+
+// Notify clients watching posts linked to by post id in thread op
+func notifyQuoted(id, op uint64, links []common.Link) (err error) {
+	watchers.RLock()
+	defer watchers.RUnlock()
+
+	for _, l := range links {
+		err = sendToWatchers(watchers.posts, l.ID, common.MessageQuoted,
+			quoteMessage{id, op, l.ID})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
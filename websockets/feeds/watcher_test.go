@@ -0,0 +1,54 @@
+package feeds
+
+import (
+	"testing"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/test"
+)
+
+type mockClient struct {
+	common.Client
+	messages []string
+}
+
+func (c *mockClient) SendMessage(m *common.Message) {
+	buf, _ := m.Encode(1)
+	c.messages = append(c.messages, string(buf))
+}
+
+func TestWatchThreads(t *testing.T) {
+	var cl mockClient
+	diff, err := WatchThreads(&cl, map[uint64]uint64{1: 2}, []uint64{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UnwatchThreads(&cl)
+
+	// Thread not in post count cache
+	test.AssertEquals(t, diff.Deleted, []uint64{1})
+	watchers.RLock()
+	_, ok := watchers.threads[1]
+	watchers.RUnlock()
+	if ok {
+		t.Fatal("deleted thread watched")
+	}
+
+	err = notifyQuoted(4, 1, []common.Link{{ID: 3, OP: 1, Board: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertEquals(t, cl.messages, []string{
+		`48{"id":4,"op":1,"target":3}`,
+	})
+
+	_, err = WatchThreads(&cl, nil, make([]uint64, maxWatchedPosts+1))
+	test.AssertEquals(t, err, errTooManyWatchedPosts)
+
+	UnwatchThreads(&cl)
+	err = notifyQuoted(5, 1, []common.Link{{ID: 3, OP: 1, Board: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertEquals(t, len(cl.messages), 1)
+}
@@ -8,15 +8,24 @@ import (
 	"github.com/bakape/meguca/websockets/feeds"
 )
 
-// Decode message JSON into the supplied type. Will augment, once we switch to
-// a binary message protocol.
+// Decode message JSON into the supplied type. Binary protocol messages are
+// converted to JSON on receipt.
 func decodeMessage(data []byte, dest interface{}) error {
 	return json.Unmarshal(data, dest)
 }
 
-// Run the appropriate handler for the websocket message
-func (c *Client) runHandler(typ common.MessageType, msg []byte) error {
-	data := msg[2:]
+// Run the appropriate handler for the websocket message with its JSON
+// payload, unless the message has to be deferred for exceeding a rate limit
+func (c *Client) runHandler(typ common.MessageType, data []byte) error {
+	deferred, err := c.throttle(typ, data)
+	if deferred || err != nil {
+		return err
+	}
+	return c.handle(typ, data)
+}
+
+// Dispatch a message to its handler
+func (c *Client) handle(typ common.MessageType, data []byte) error {
 	switch typ {
 	case common.MessageSynchronise:
 		return c.synchronise(data)
@@ -41,7 +50,13 @@ func (c *Client) runHandler(typ common.MessageType, msg []byte) error {
 		return c.spoilerImage()
 	case common.MessageMeguTV:
 		return feeds.SubscribeToMeguTV(c)
+	case common.MessageWatchThreads:
+		return c.watchThreads(data)
+	case common.MessageVotePoll:
+		return c.votePoll(data)
+	case common.MessageSelfDelete:
+		return c.selfDeletePost(data)
 	default:
-		return errInvalidPayload(msg)
+		return errInvalidPayload(data)
 	}
 }
@@ -9,6 +9,7 @@ import (
 type openPost struct {
 	hasImage, isSpoilered bool
 	len, lines            int
+	extraFiles            int
 	id, op                uint64
 	time                  int64
 	body                  []byte
@@ -29,6 +30,7 @@ func (o *openPost) init(p common.StandalonePost) {
 	if p.Image != nil {
 		o.hasImage = true
 		o.isSpoilered = p.Image.Spoiler
+		o.extraFiles = len(p.Files)
 	}
 }
 
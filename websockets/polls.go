@@ -0,0 +1,53 @@
+// Voting in #poll hash commands
+
+package websockets
+
+import (
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/websockets/feeds"
+)
+
+// Request to vote for an option of the #poll in a post
+type pollVoteRequest struct {
+	ID     uint64 `json:"id"`
+	Option int    `json:"option"`
+}
+
+// Vote for an option of the #poll in a post and send the updated tallies to
+// the post's thread. Repeated votes of the same captcha session or IP are
+// ignored.
+func (c *Client) votePoll(data []byte) (err error) {
+	var req pollVoteRequest
+	err = decodeMessage(data, &req)
+	if err != nil {
+		return
+	}
+
+	board, op, err := db.GetPostParenthood(req.ID)
+	if err != nil {
+		return
+	}
+	err = db.IsBanned(board, c.ip)
+	if err != nil {
+		return
+	}
+
+	votes, err := db.VotePoll(req.ID, req.Option, c.captchaSession, c.ip)
+	if err != nil || votes == nil {
+		return
+	}
+
+	msg, err := common.EncodeMessage(common.MessageVotePoll, struct {
+		ID    uint64   `json:"id"`
+		Votes []uint32 `json:"votes"`
+	}{
+		ID:    req.ID,
+		Votes: votes,
+	})
+	if err != nil {
+		return
+	}
+	feeds.SendTo(op, msg)
+	return
+}
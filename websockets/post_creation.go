@@ -21,6 +21,9 @@ var (
 	errInvalidImageToken = common.ErrInvalidInput("image token")
 	errImageNameTooLong  = common.ErrTooLong("image name")
 	errNoTextOrImage     = common.ErrInvalidInput("no text or image")
+	errTooManyFiles      = common.ErrInvalidInput("too many files")
+	errFilesWithoutImage = common.ErrInvalidInput(
+		"additional files without main image")
 )
 
 // ThreadCreationRequest contains data for creating a new thread
@@ -35,6 +38,7 @@ type ThreadCreationRequest struct {
 type ReplyCreationRequest struct {
 	Sage, Open bool
 	Image      ImageRequest
+	Files      []ImageRequest
 	auth.SessionCreds
 	Name, Password, Body string
 }
@@ -50,6 +54,10 @@ type ImageRequest struct {
 func CreateThread(req ThreadCreationRequest, ip string) (
 	post db.Post, err error,
 ) {
+	if isDraining() {
+		err = errShuttingDown
+		return
+	}
 	if !auth.IsNonMetaBoard(req.Board) {
 		err = common.ErrInvalidBoard(req.Board)
 		return
@@ -62,7 +70,7 @@ func CreateThread(req ThreadCreationRequest, ip string) (
 	if err != nil {
 		return
 	}
-	post, err = constructPost(req.ReplyCreationRequest, conf, ip)
+	post, flag, err := constructPost(req.ReplyCreationRequest, conf, ip)
 	if err != nil {
 		return
 	}
@@ -70,6 +78,12 @@ func CreateThread(req ThreadCreationRequest, ip string) (
 	if err != nil {
 		return
 	}
+	subject, flagSubject, err := parser.FilterText(conf.ID,
+		config.FilterSubject, subject)
+	if err != nil {
+		return
+	}
+	flag = flag || flagSubject
 
 	// Must ensure image token usage is done atomically, as not to cause
 	// possible data races with unused image cleanup
@@ -82,18 +96,39 @@ func CreateThread(req ThreadCreationRequest, ip string) (
 
 		if !conf.TextOnly && req.Image.Token != "" &&
 			req.Image.Name != "" {
-			err = insertImage(tx, req.Image, &post)
+			err = insertImages(tx, req.ReplyCreationRequest, &post)
 			if err != nil {
 				return
 			}
 		}
 		return
 	})
+	if err == nil && flag {
+		err = flagPost(post.ID, conf.ID, ip)
+	}
 
 	return
 }
 
-// Insert image into a post on post creation
+// Insert the main image and any additional files into a post on post creation
+func insertImages(tx *sql.Tx, req ReplyCreationRequest, p *db.Post) (
+	err error,
+) {
+	err = insertImage(tx, req.Image, p)
+	if err != nil {
+		return
+	}
+	for _, f := range req.Files {
+		err = insertImage(tx, f, p)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Insert image into a post on post creation. If the post already has an image,
+// the file is appended to the post's additional files.
 func insertImage(tx *sql.Tx, req ImageRequest, p *db.Post) (err error) {
 	err = formatImageName(&req.Name)
 	if err != nil {
@@ -106,16 +141,30 @@ func insertImage(tx *sql.Tx, req ImageRequest, p *db.Post) (err error) {
 	if err != nil {
 		return
 	}
-	err = json.Unmarshal(buf, &p.Image)
+	var img common.Image
+	err = json.Unmarshal(buf, &img)
 	if err != nil {
 		return
 	}
 
-	p.Image.Name = req.Name
-	p.Image.Spoiler = req.Spoiler
+	img.Name = req.Name
+	img.Spoiler = req.Spoiler
+	if p.Image == nil {
+		p.Image = &img
+	} else {
+		p.Files = append(p.Files, img)
+	}
 	return
 }
 
+// Returns the maximum amount of files a post on the board can have
+func maxFiles(conf config.BoardConfigs) int {
+	if conf.MaxFiles == 0 {
+		return 1
+	}
+	return int(conf.MaxFiles)
+}
+
 // CreatePost creates a new post and writes it to the database.
 // open specifies, if the post should stay open after creation.
 func CreatePost(
@@ -125,6 +174,10 @@ func CreatePost(
 ) (
 	post db.Post, msg []byte, err error,
 ) {
+	if isDraining() {
+		err = errShuttingDown
+		return
+	}
 	err = db.IsBanned(board, ip)
 	if err != nil {
 		return
@@ -163,7 +216,7 @@ func CreatePost(
 		req.Open = !disabled
 	}*/
 
-	post, err = constructPost(req, conf, ip)
+	post, flag, err := constructPost(req, conf, ip)
 	if err != nil {
 		return
 	}
@@ -179,7 +232,7 @@ func CreatePost(
 		}
 
 		if hasImage {
-			err = insertImage(tx, req.Image, &post)
+			err = insertImages(tx, req, &post)
 			if err != nil {
 				return
 			}
@@ -187,6 +240,15 @@ func CreatePost(
 
 		return
 	})
+	if err != nil {
+		return
+	}
+	if flag {
+		err = flagPost(post.ID, board, ip)
+		if err != nil {
+			return
+		}
+	}
 
 	msg, err = common.EncodeMessage(common.MessageInsertPost, post.Post)
 	return
@@ -265,13 +327,15 @@ func getBoardConfig(board string) (conf config.BoardConfigs, err error) {
 	return
 }
 
-// Construct the common parts of the new post for both threads and replies
+// Construct the common parts of the new post for both threads and replies.
+// Returns flag, if the post matched a flagging word filter and should be
+// reported after insertion.
 func constructPost(
 	req ReplyCreationRequest,
 	conf config.BoardConfigs,
 	ip string,
 ) (
-	post db.Post, err error,
+	post db.Post, flag bool, err error,
 ) {
 	post = db.Post{
 		StandalonePost: common.StandalonePost{
@@ -284,24 +348,55 @@ func constructPost(
 		IP: ip,
 	}
 
+	if len(req.Files) != 0 {
+		switch {
+		case conf.TextOnly || req.Image.Token == "" || req.Image.Name == "":
+			// Would otherwise be silently dropped with the missing main image
+			err = errFilesWithoutImage
+			return
+		case 1+len(req.Files) > maxFiles(conf):
+			err = errTooManyFiles
+			return
+		}
+	}
+
 	if !conf.ForcedAnon {
 		post.Name, post.Trip, err = parser.ParseName(req.Name)
 		if err != nil {
 			return
 		}
+		post.Name, flag, err = parser.FilterText(conf.ID, config.FilterName,
+			post.Name)
+		if err != nil {
+			return
+		}
+	}
+
+	// Open posts are filtered as they are written and on closing
+	if req.Open {
+		err = parser.CheckWordFilters(conf.ID, config.FilterBody,
+			string(completeWords([]byte(req.Body))))
+	} else {
+		var flagBody bool
+		post.Body, flagBody, err = parser.FilterText(conf.ID,
+			config.FilterBody, req.Body)
+		flag = flag || flagBody
+	}
+	if err != nil {
+		return
 	}
 
 	if conf.Flags {
 		post.Flag = geoip.LookUp(ip)
 	}
 
-	if utf8.RuneCountInString(req.Body) > common.MaxLenBody {
+	if utf8.RuneCountInString(post.Body) > common.MaxLenBody {
 		err = common.ErrBodyTooLong
 		return
 	}
 
 	lines := 0
-	for _, r := range req.Body {
+	for _, r := range post.Body {
 		if r == '\n' {
 			lines++
 		}
@@ -330,11 +425,9 @@ func constructPost(
 		}
 	}
 
-	if req.Open {
-		post.Editing = true
-
-		// Posts that are committed in one action need not a password, as they
-		// are closed on commit and can not be reclaimed
+	// Posts that are committed in one action need a password only to be
+	// edited after closing, as they can not be reclaimed
+	if req.Open || req.Password != "" {
 		err = parser.VerifyPostPassword(req.Password)
 		if err != nil {
 			return
@@ -343,12 +436,16 @@ func constructPost(
 		if err != nil {
 			return
 		}
+	}
+
+	if req.Open {
+		post.Editing = true
 	} else {
 		// TODO: Move DB checks out of the parser. The parser should just parse.
 		// Return slices of pointers to links and commands that need to be
 		// validated.
 		post.Links, post.Commands, err = parser.ParseBody(
-			[]byte(req.Body),
+			[]byte(post.Body),
 			conf.ID,
 			post.OP,
 			post.ID,
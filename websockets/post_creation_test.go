@@ -275,6 +275,44 @@ func TestPostCreationValidations(t *testing.T) {
 	}
 }
 
+func TestFilesWithoutImage(t *testing.T) {
+	t.Parallel()
+
+	file := ImageRequest{
+		Name:  "foo.png",
+		Token: "abc",
+	}
+	cases := [...]struct {
+		name     string
+		textOnly bool
+		image    ImageRequest
+	}{
+		{"no main image", false, ImageRequest{}},
+		{"text only board", true, file},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := ReplyCreationRequest{
+				Body:  "foo",
+				Image: c.image,
+				Files: []ImageRequest{file},
+			}
+			conf := config.BoardConfigs{
+				ID: "a",
+				BoardPublic: config.BoardPublic{
+					TextOnly: c.textOnly,
+				},
+			}
+			_, _, err := constructPost(req, conf, "::1")
+			AssertEquals(t, err, errFilesWithoutImage)
+		})
+	}
+}
+
 func TestPostCreation(t *testing.T) {
 	feeds.Clear()
 	prepareForPostCreation(t)
@@ -363,7 +401,7 @@ func registerClient(t testing.TB, cl *Client, id uint64, board string) {
 	t.Helper()
 
 	var err error
-	cl.feed, err = feeds.SyncClient(cl, id, board)
+	cl.feed, err = feeds.SyncClient(cl, id, board, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
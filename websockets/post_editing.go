@@ -0,0 +1,217 @@
+// Editing of closed posts by their authors
+
+package websockets
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/parser"
+	"github.com/bakape/meguca/websockets/feeds"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errEditingDisabled = common.ErrAccessDenied("post editing disabled")
+	errEditExpired     = common.ErrAccessDenied("post editing time expired")
+	errNotPostOwner    = common.ErrAccessDenied("not the post's author")
+	errPostOpen        = common.ErrInvalidInput("post still open")
+	errPostDeleted     = common.ErrInvalidInput("post deleted")
+	errThreadLocked    = common.ErrInvalidInput("thread is locked")
+	errCommandsChanged = common.ErrInvalidInput(
+		"hash commands can not be added, removed or changed")
+)
+
+// PostEditRequest contains the new body of a closed post and the post
+// password proving ownership of it
+type PostEditRequest struct {
+	ID             uint64
+	Password, Body string
+}
+
+// EditPost replaces the body of a closed post, if the poster proves ownership
+// and the board's editing window has not yet passed. The previous body is
+// kept in the post's edit history and the change propagated to the thread
+// feed.
+func EditPost(req PostEditRequest, ip string) (err error) {
+	if isDraining() {
+		return errShuttingDown
+	}
+
+	post, err := db.GetPost(req.ID)
+	if err != nil {
+		return
+	}
+	err = db.IsBanned(post.Board, ip)
+	if err != nil {
+		return
+	}
+
+	window := config.GetBoardConfigs(post.Board).AllowEdits
+	switch {
+	case window == 0:
+		return errEditingDisabled
+	case post.Editing:
+		return errPostOpen
+	case post.IsDeleted():
+		return errPostDeleted
+	case time.Since(time.Unix(post.Time, 0)) > time.Duration(window)*time.Minute:
+		return errEditExpired
+	}
+
+	locked, err := db.CheckThreadLocked(post.OP)
+	switch {
+	case err != nil:
+		return
+	case locked:
+		return errThreadLocked
+	}
+
+	err = checkPostOwner(req.ID, req.Password)
+	if err != nil {
+		return
+	}
+
+	var flag bool
+	req.Body, flag, err = parser.FilterText(post.Board, config.FilterBody,
+		req.Body)
+	if err != nil {
+		return
+	}
+	err = validateEditedBody(req.Body, post.Image != nil)
+	if err != nil || req.Body == post.Body {
+		return
+	}
+
+	links, com, err := parser.ParseEditedBody(
+		[]byte(req.Body),
+		post.Board,
+		post.OP,
+		post.ID,
+		ip,
+	)
+	if err != nil {
+		return
+	}
+	err = keepCommandResults(post.Commands, com)
+	if err != nil {
+		return
+	}
+
+	edited, err := db.EditPost(post.ID, req.Body, links, com)
+	if err != nil {
+		return
+	}
+
+	msg, err := common.EncodeMessage(common.MessageEditPost, struct {
+		ID       uint64           `json:"id"`
+		Edited   int64            `json:"edited"`
+		Body     string           `json:"body"`
+		Links    []common.Link    `json:"links"`
+		Commands []common.Command `json:"commands"`
+	}{
+		ID:       post.ID,
+		Edited:   edited,
+		Body:     req.Body,
+		Links:    links,
+		Commands: com,
+	})
+	if err != nil {
+		return
+	}
+	feeds.EditPost(post.ID, post.OP, req.Body, msg)
+	if flag {
+		err = flagPost(post.ID, post.Board, ip)
+	}
+	return
+}
+
+// Assert the post password proves ownership of the post
+func checkPostOwner(id uint64, password string) (err error) {
+	hash, err := db.GetPostPassword(id)
+	switch {
+	case err != nil:
+		return
+	case hash == nil:
+		return errNotPostOwner
+	}
+	switch err = auth.BcryptCompare(password, hash); err {
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return errNotPostOwner
+	default:
+		return
+	}
+}
+
+// Apply the same constraints to an edited body as to a newly created one
+func validateEditedBody(body string, hasImage bool) error {
+	switch {
+	case body == "" && !hasImage:
+		return errNoTextOrImage
+	case utf8.RuneCountInString(body) > common.MaxLenBody:
+		return common.ErrBodyTooLong
+	}
+
+	lines := 0
+	for _, r := range body {
+		if r == '\n' {
+			lines++
+		}
+	}
+	if lines > common.MaxLinesBody {
+		return errTooManyLines
+	}
+	return nil
+}
+
+// Copy the results of the post's previous hash commands to the reparsed ones,
+// so editing can not be used to reroll dice or flip coins again. The reparsed
+// commands are only typed and have no results of their own. Edits, that
+// add, remove or reorder hash commands, are rejected, as results could not be
+// matched to the commands otherwise. The votes of a #poll are carried over by
+// db.EditPost, which also prevents changing the options of a poll, that has
+// already been voted in.
+func keepCommandResults(old, new []common.Command) error {
+	if len(old) != len(new) {
+		return errCommandsChanged
+	}
+	for i := range new {
+		if old[i].Type != new[i].Type || !sameDice(old[i], new[i]) {
+			return errCommandsChanged
+		}
+		if new[i].Type != common.Poll {
+			new[i] = old[i]
+		}
+	}
+	return nil
+}
+
+// Returns, if two dice commands are rolls of the same expression
+func sameDice(a, b common.Command) bool {
+	if a.Type != common.Dice {
+		return true
+	}
+	y, err := common.ParseDice(b.DiceExpr)
+	if err != nil {
+		return false
+	}
+	if a.DiceExpr == "" {
+		// Rolled before expressions were stored, when only plain "NdM" rolls
+		// were supported. Only the number of dice can be compared.
+		return a.DiceRoll == nil && y.IsSimple() && y[0].Count == len(a.Dice)
+	}
+	x, err := common.ParseDice(a.DiceExpr)
+	if err != nil || len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
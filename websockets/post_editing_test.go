@@ -0,0 +1,228 @@
+package websockets
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bakape/meguca/auth"
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/db"
+	. "github.com/bakape/meguca/test"
+	"github.com/bakape/meguca/test/test_db"
+	"github.com/bakape/meguca/websockets/feeds"
+)
+
+func TestKeepCommandResults(t *testing.T) {
+	t.Parallel()
+
+	old := []common.Command{
+		{Type: common.Flip, Flip: true},
+		{Type: common.Dice, Dice: []uint16{3, 5}, DiceExpr: "2d6"},
+		{Type: common.EightBall, Eightball: "yes"},
+	}
+	new := []common.Command{
+		{Type: common.Flip},
+		{Type: common.Dice, Dice: []uint16{1, 2}, DiceExpr: "2d6"},
+		{Type: common.EightBall, Eightball: "no"},
+	}
+	if err := keepCommandResults(old, new); err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, new, old)
+}
+
+func TestRejectChangedCommands(t *testing.T) {
+	t.Parallel()
+
+	old := []common.Command{
+		{Type: common.Flip, Flip: true},
+		{Type: common.Dice, Dice: []uint16{3, 5}},
+	}
+	cases := [...]struct {
+		name string
+		new  []common.Command
+	}{
+		{"removed", []common.Command{{Type: common.Flip}}},
+		{
+			"inserted",
+			[]common.Command{
+				{Type: common.EightBall},
+				{Type: common.Flip},
+				{Type: common.Dice, Dice: []uint16{1, 2}},
+			},
+		},
+		{
+			"reordered",
+			[]common.Command{
+				{Type: common.Dice, Dice: []uint16{1, 2}},
+				{Type: common.Flip},
+			},
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			AssertEquals(t, keepCommandResults(old, c.new), errCommandsChanged)
+		})
+	}
+}
+
+func TestKeepDiceResults(t *testing.T) {
+	t.Parallel()
+
+	roll := func(expr string, rolls ...uint16) common.Command {
+		return common.Command{
+			Type:     common.Dice,
+			Dice:     rolls,
+			DiceExpr: expr,
+			DiceRoll: &common.DiceRoll{
+				Terms: []common.DiceTermRoll{{Rolls: rolls}},
+			},
+		}
+	}
+
+	// Rolled before dice expressions were stored
+	legacy := func(rolls ...uint16) common.Command {
+		return common.Command{
+			Type: common.Dice,
+			Dice: rolls,
+		}
+	}
+
+	cases := [...]struct {
+		name     string
+		old, new common.Command
+		err      error
+	}{
+		{"exploding", roll("d6!", 6, 6, 2), roll("d6!", 3), nil},
+		{"same expression", roll("d20kh1", 4), roll("1d20kh1", 7), nil},
+		{"sides changed", roll("1d100", 90), roll("1d20", 3), errCommandsChanged},
+		{
+			"selection changed",
+			roll("2d20kl1", 1, 20),
+			roll("2d20kh1", 1, 20),
+			errCommandsChanged,
+		},
+		{"expression unknown", legacy(1), roll("d6", 3), nil},
+		{"expression unknown and changed", legacy(1), roll("2d6", 3, 4),
+			errCommandsChanged},
+		{"expression unknown and extended", legacy(1), roll("d6!", 3),
+			errCommandsChanged},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			new := []common.Command{c.new}
+			err := keepCommandResults([]common.Command{c.old}, new)
+			AssertEquals(t, err, c.err)
+			if err == nil {
+				AssertEquals(t, new[0], c.old)
+			}
+		})
+	}
+}
+
+func TestEditPostKeepsRoulette(t *testing.T) {
+	feeds.Clear()
+	test_db.ClearTables(t, "boards")
+	test_db.WriteSampleBoard(t)
+	test_db.WriteSampleThread(t)
+	config.ClearBoards()
+	_, err := config.SetBoardConfigs(config.BoardConfigs{
+		ID: "a",
+		BoardPublic: config.BoardPublic{
+			AllowEdits: 10,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const pw = "123"
+	hash, err := auth.BcryptHash(pw, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	com := []common.Command{
+		{Type: common.Roulette, Roulette: [2]uint8{3, 6}},
+	}
+	err = db.InTransaction(false, func(tx *sql.Tx) error {
+		return db.WritePost(tx, db.Post{
+			StandalonePost: common.StandalonePost{
+				Post: common.Post{
+					ID:       2,
+					Body:     "#roulette",
+					Time:     time.Now().Unix(),
+					Commands: com,
+				},
+				OP:    1,
+				Board: "a",
+			},
+			Password: hash,
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = EditPost(PostEditRequest{
+		ID:       2,
+		Password: pw,
+		Body:     "edited #roulette",
+	}, "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scount uint8
+	err = db.InTransaction(true, func(tx *sql.Tx) (err error) {
+		scount, err = db.GetRoulette(tx, 1)
+		return
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, scount, uint8(6))
+
+	post, err := db.GetPost(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, post.Commands, com)
+}
+
+func TestValidateEditedBody(t *testing.T) {
+	t.Parallel()
+
+	cases := [...]struct {
+		name, body string
+		hasImage   bool
+		err        error
+	}{
+		{"valid", "foo", false, nil},
+		{"empty", "", false, errNoTextOrImage},
+		{"empty with image", "", true, nil},
+		{
+			"too many lines",
+			strings.Repeat("\n", common.MaxLinesBody+1),
+			false,
+			errTooManyLines,
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			AssertEquals(t, validateEditedBody(c.body, c.hasImage), c.err)
+		})
+	}
+}
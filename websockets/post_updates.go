@@ -101,17 +101,19 @@ func (c *Client) appendRune(data []byte) (err error) {
 		return
 	case char == 0:
 		return common.ErrContainsNull
-	case char == '\n':
-		c.post.lines++
-		if c.post.lines > common.MaxLinesBody {
-			return errTooManyLines
-		}
+	case char == '\n' && c.post.lines+1 > common.MaxLinesBody:
+		return errTooManyLines
 	}
 	err = parser.IsPrintable(char, true)
 	if err != nil {
 		return
 	}
 
+	body := append(c.post.body, string(char)...)
+	if c.wordFiltered(body) {
+		return c.rejectBody()
+	}
+
 	msg, err := common.EncodeMessage(
 		common.MessageAppend,
 		[2]uint64{c.post.id, uint64(char)},
@@ -120,7 +122,10 @@ func (c *Client) appendRune(data []byte) (err error) {
 		return
 	}
 
-	c.post.body = append(c.post.body, string(char)...)
+	if char == '\n' {
+		c.post.lines++
+	}
+	c.post.body = body
 	c.post.len++
 	return c.updateBody(msg, 1)
 }
@@ -177,6 +182,10 @@ func (c *Client) closePost() (err error) {
 		links []common.Link
 		com   []common.Command
 	)
+	flag, err := c.filterBody()
+	if err != nil {
+		return
+	}
 	if c.post.len != 0 {
 		links, com, err = parser.ParseBody(c.post.body, c.post.board, c.post.op,
 			c.post.id, c.ip, false)
@@ -189,12 +198,53 @@ func (c *Client) closePost() (err error) {
 	if err != nil {
 		return
 	}
+	if flag {
+		err = flagPost(c.post.id, c.post.board, c.ip)
+		if err != nil {
+			return
+		}
+	}
 
 	err = CheckRouletteBan(com, c.post.board, c.post.op, c.post.id)
 	c.post = openPost{}
 	return
 }
 
+// Apply the board's word filters to the body of the open post before closing
+// it. Text matching rejecting filters, like a last word that was never
+// completed, is removed. Replacements are propagated as a splice of the
+// entire body. Returns flag, if the post should be reported for review.
+func (c *Client) filterBody() (flag bool, err error) {
+	old := string(c.post.body)
+	body, flag := parser.CensorText(c.post.board, config.FilterBody, old)
+	if body == old {
+		return
+	}
+	n := utf8.RuneCountInString(body)
+	if n > common.MaxLenBody {
+		err = common.ErrBodyTooLong
+		return
+	}
+
+	msg, err := common.EncodeMessage(common.MessageSplice, spliceMessage{
+		ID: c.post.id,
+		spliceRequestString: spliceRequestString{
+			spliceCoords: spliceCoords{
+				Len: uint(c.post.len),
+			},
+			Text: body,
+		},
+	})
+	if err != nil {
+		return
+	}
+	c.post.body = []byte(body)
+	c.post.len = n
+	c.post.countLines()
+	err = c.updateBody(msg, 0)
+	return
+}
+
 // CheckRouletteBan meme bans if the poster lost at #roulette
 func CheckRouletteBan(commands []common.Command, board string, thread uint64, id uint64) error {
 	for _, command := range commands {
@@ -266,8 +316,9 @@ func (c *Client) spliceText(data []byte) error {
 	}
 
 	var (
-		old = []rune(string(c.post.body))
-		end = append(req.Text, old[req.Start+req.Len:]...)
+		old  = []rune(string(c.post.body))
+		end  = append(req.Text, old[req.Start+req.Len:]...)
+		prev = c.post
 	)
 	c.post.len += -int(req.Len) + len(req.Text)
 	res := spliceMessage{
@@ -306,12 +357,19 @@ func (c *Client) spliceText(data []byte) error {
 	if c.post.lines > common.MaxLinesBody {
 		return errTooManyLines
 	}
+	if c.wordFiltered(c.post.body) {
+		// Roll back the splice. The original body was cloned above, so it
+		// was not modified.
+		c.post = prev
+		return c.rejectBody()
+	}
 
 	// +1, so you can't spam zero insert splices to infinity
 	return c.updateBody(msg, len(res.Text)+1)
 }
 
-// Insert and image into an existing open post
+// Insert and image into an existing open post. If the post already has an
+// image, the file is added to the post's additional files.
 // Note: Spam score is now incremented on image thumbnailing, not assignment to
 // post.
 func (c *Client) insertImage(data []byte) (err error) {
@@ -325,7 +383,9 @@ func (c *Client) insertImage(data []byte) (err error) {
 		return
 	case !has:
 		return errNoPostOpen
-	case c.post.hasImage:
+	}
+	conf := config.GetBoardConfigs(c.post.board).BoardConfigs
+	if c.post.hasImage && 1+c.post.extraFiles >= maxFiles(conf) {
 		// Can be caused by network latency - NOP it
 		return nil
 	}
@@ -336,7 +396,7 @@ func (c *Client) insertImage(data []byte) (err error) {
 		return
 	}
 
-	if config.GetBoardConfigs(c.post.board).TextOnly {
+	if conf.TextOnly {
 		return errTextOnly
 	}
 
@@ -353,8 +413,12 @@ func (c *Client) insertImage(data []byte) (err error) {
 	if err != nil {
 		return
 	}
-	c.post.hasImage = true
-	c.post.isSpoilered = req.Spoiler
+	if c.post.hasImage {
+		c.post.extraFiles++
+	} else {
+		c.post.hasImage = true
+		c.post.isSpoilered = req.Spoiler
+	}
 	c.feed.InsertImage(c.post.id, req.Spoiler,
 		common.PrependMessageType(common.MessageInsertImage, msg))
 
@@ -0,0 +1,229 @@
+// Token bucket rate limiting of websocket messages per IP
+
+package websockets
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+)
+
+const (
+	// Interval of removing the buckets of idle IPs
+	rateLimitCleanupInterval = time.Minute
+
+	// Maximum number of messages a client can have deferred by rate limiting
+	maxThrottledMessages = 1 << 10
+)
+
+var (
+	rateLimits = rateLimitRegistry{
+		ips: make(map[string]rateBuckets),
+	}
+
+	errThrottleOverflow = errors.New("too many rate limited messages")
+)
+
+// Message deferred for exceeding a rate limit
+type throttledMessage struct {
+	typ  common.MessageType
+	data []byte
+}
+
+// Token buckets of a single IP. Shared between all connections of the IP.
+type rateBuckets map[common.MessageType]*tokenBucket
+
+// Registry of token buckets of all IPs
+type rateLimitRegistry struct {
+	mu          sync.Mutex
+	lastCleanup time.Time
+	ips         map[string]rateBuckets
+}
+
+// Token bucket refilled continuously at a constant rate
+type tokenBucket struct {
+	// Can be negative down to -burst, when messages are waiting for tokens
+	tokens float64
+	// Refill rate in tokens per second
+	rate    float64
+	burst   float64
+	updated time.Time
+}
+
+// Returns the number of tokens in the bucket at time now
+func (b tokenBucket) tokensAt(now time.Time) float64 {
+	t := b.tokens + now.Sub(b.updated).Seconds()*b.rate
+	if t > b.burst {
+		t = b.burst
+	}
+	return t
+}
+
+// Returns the configured rate per minute and burst size for a message type.
+// A zero rate means the message type is not limited.
+func rateLimitOf(typ common.MessageType) (rate, burst uint) {
+	conf := config.Get()
+	switch typ {
+	case common.MessageAppend, common.MessageBackspace:
+		return conf.AppendRate, conf.AppendBurst
+	case common.MessageSplice:
+		return conf.SpliceRate, conf.SpliceBurst
+	case common.MessageInsertPost:
+		return conf.InsertPostRate, conf.InsertPostBurst
+	case common.MessageInsertImage:
+		return conf.InsertImageRate, conf.InsertImageBurst
+	case common.MessageReclaim:
+		return conf.ReclaimRate, conf.ReclaimBurst
+	}
+	return
+}
+
+// Take a token for a message of type typ from the buckets of ip and return,
+// how long the message must wait before being processed. rate is the number
+// of messages allowed per minute.
+func (r *rateLimitRegistry) reserve(ip string, typ common.MessageType,
+	rate, burst uint, now time.Time,
+) time.Duration {
+	if rate == 0 {
+		return 0
+	}
+	if burst == 0 {
+		burst = 1
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastCleanup) >= rateLimitCleanupInterval {
+		r.cleanUp(now)
+	}
+
+	buckets := r.ips[ip]
+	if buckets == nil {
+		buckets = make(rateBuckets)
+		r.ips[ip] = buckets
+	}
+	b := buckets[typ]
+	if b == nil {
+		b = &tokenBucket{
+			tokens:  float64(burst),
+			updated: now,
+		}
+		buckets[typ] = b
+	}
+
+	// Apply configuration changes
+	b.rate = float64(rate) / 60
+	b.burst = float64(burst)
+
+	b.tokens = b.tokensAt(now) - 1
+	if b.tokens < -b.burst {
+		b.tokens = -b.burst
+	}
+	b.updated = now
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Remove buckets, that have been refilled completely. Requires lock.
+func (r *rateLimitRegistry) cleanUp(now time.Time) {
+	r.lastCleanup = now
+	for ip, buckets := range r.ips {
+		for typ, b := range buckets {
+			if b.tokensAt(now) >= b.burst {
+				delete(buckets, typ)
+			}
+		}
+		if len(buckets) == 0 {
+			delete(r.ips, ip)
+		}
+	}
+}
+
+// Defer processing of a message, if the client's IP has exceeded the rate
+// limit of the message type. Messages received while others are deferred are
+// queued behind them to preserve their order. The client is notified once per
+// exceeded limit instead of being disconnected. Returns, if the message was
+// deferred.
+func (c *Client) throttle(typ common.MessageType, data []byte) (
+	deferred bool, err error,
+) {
+	if len(c.throttled) != 0 {
+		if len(c.throttled) >= maxThrottledMessages {
+			return false, errThrottleOverflow
+		}
+		c.throttled = append(c.throttled, throttledMessage{typ, data})
+		return true, nil
+	}
+
+	wait := c.reserve(typ)
+	if wait == 0 {
+		return
+	}
+	c.throttled = append(c.throttled, throttledMessage{typ, data})
+	c.throttleTimer = time.NewTimer(wait)
+	return true, c.notifyRateLimited(typ, wait)
+}
+
+// Process deferred messages, once the first one's wait has passed. Stops at
+// the next message, that has to wait for its rate limit.
+func (c *Client) runThrottled() error {
+	c.throttleTimer = nil
+	for i := 0; len(c.throttled) != 0; i++ {
+		m := c.throttled[0]
+		// The first message has already taken its token
+		if i != 0 {
+			if wait := c.reserve(m.typ); wait != 0 {
+				c.throttleTimer = time.NewTimer(wait)
+				return c.notifyRateLimited(m.typ, wait)
+			}
+		}
+		c.throttled = c.throttled[1:]
+		if err := c.handle(m.typ, m.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the channel of the timer of the first deferred message or nil, if
+// no messages are deferred
+func (c *Client) throttleC() <-chan time.Time {
+	if c.throttleTimer == nil {
+		return nil
+	}
+	return c.throttleTimer.C
+}
+
+// Take a token for a message of type typ from the buckets of the client's IP
+// and return, how long the message must wait before being processed
+func (c *Client) reserve(typ common.MessageType) time.Duration {
+	rate, burst := rateLimitOf(typ)
+	wait := rateLimits.reserve(c.ip, typ, rate, burst, time.Now())
+	if wait == 0 {
+		c.rateLimited = false
+	}
+	return wait
+}
+
+// Notify the client, that its messages of type typ are being slowed down, if
+// not already notified
+func (c *Client) notifyRateLimited(typ common.MessageType, wait time.Duration,
+) error {
+	if c.rateLimited {
+		return nil
+	}
+	c.rateLimited = true
+	return c.sendMessage(common.MessageRateLimited, struct {
+		Type common.MessageType `json:"type"`
+		Wait int64              `json:"wait"`
+	}{
+		Type: typ,
+		Wait: int64(wait / time.Millisecond),
+	})
+}
@@ -0,0 +1,48 @@
+package websockets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	. "github.com/bakape/meguca/test"
+)
+
+func TestRateLimiting(t *testing.T) {
+	t.Parallel()
+
+	r := rateLimitRegistry{
+		ips: make(map[string]rateBuckets),
+	}
+	now := time.Now()
+	reserve := func(ip string) time.Duration {
+		return r.reserve(ip, common.MessageInsertPost, 60, 2, now)
+	}
+
+	// Burst
+	for i := 0; i < 2; i++ {
+		AssertEquals(t, reserve("::1"), time.Duration(0))
+	}
+	AssertEquals(t, reserve("::1"), time.Second)
+	AssertEquals(t, reserve("::1"), 2*time.Second)
+
+	// Debt is limited to the burst size
+	AssertEquals(t, reserve("::1"), 2*time.Second)
+
+	// Other IPs and message types are not affected
+	AssertEquals(t, reserve("::2"), time.Duration(0))
+	AssertEquals(t, r.reserve("::1", common.MessageAppend, 60, 2, now),
+		time.Duration(0))
+
+	// Refill
+	now = now.Add(3 * time.Second)
+	AssertEquals(t, reserve("::1"), time.Duration(0))
+
+	// Unlimited
+	AssertEquals(t, r.reserve("::1", common.MessageSplice, 0, 0, now),
+		time.Duration(0))
+
+	// Full buckets are removed
+	r.cleanUp(now.Add(time.Minute))
+	AssertEquals(t, len(r.ips), 0)
+}
@@ -0,0 +1,75 @@
+// Deletion of posts by their authors
+
+package websockets
+
+import (
+	"time"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/db"
+)
+
+var (
+	errSelfDeletionDisabled = common.ErrAccessDenied("post deletion disabled")
+	errSelfDeletionExpired  = common.ErrAccessDenied(
+		"post deletion time expired")
+	errNoImage = common.ErrInvalidInput("post has no image")
+)
+
+// SelfDeleteRequest contains the post to delete and the post password proving
+// ownership of it. If Image is set, only the post's image is deleted.
+type SelfDeleteRequest struct {
+	ID       uint64 `json:"id"`
+	Password string `json:"password"`
+	Image    bool   `json:"image"`
+}
+
+// SelfDeletePost deletes a closed post or only its image, if the poster proves
+// ownership and the board's self-deletion window has not yet passed. The
+// deletion is propagated to the thread feed the same way as deletions by
+// staff.
+func SelfDeletePost(req SelfDeleteRequest, ip string) (err error) {
+	if isDraining() {
+		return errShuttingDown
+	}
+
+	post, err := db.GetPost(req.ID)
+	if err != nil {
+		return
+	}
+	err = db.IsBanned(post.Board, ip)
+	if err != nil {
+		return
+	}
+
+	window := config.GetBoardConfigs(post.Board).AllowSelfDelete
+	switch {
+	case window == 0:
+		return errSelfDeletionDisabled
+	case post.Editing:
+		return errPostOpen
+	case post.IsDeleted():
+		return errPostDeleted
+	case req.Image && post.Image == nil:
+		return errNoImage
+	case time.Since(time.Unix(post.Time, 0)) > time.Duration(window)*time.Minute:
+		return errSelfDeletionExpired
+	}
+
+	err = checkPostOwner(req.ID, req.Password)
+	if err != nil {
+		return
+	}
+	return db.SelfDeletePost(req.ID, req.Image)
+}
+
+// Delete one of the client's own posts or its image
+func (c *Client) selfDeletePost(data []byte) (err error) {
+	var req SelfDeleteRequest
+	err = decodeMessage(data, &req)
+	if err != nil {
+		return
+	}
+	return SelfDeletePost(req, c.ip)
+}
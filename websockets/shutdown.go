@@ -0,0 +1,89 @@
+// Draining of clients and open posts on server shutdown
+
+package websockets
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/websockets/feeds"
+)
+
+const (
+	// Notice sent to clients before disconnecting them on shutdown
+	shutdownNotice = "Server restarting. Reconnecting shortly."
+
+	// Time clients should wait before reconnecting after a shutdown
+	reconnectDelay = 5 * time.Second
+)
+
+var (
+	// Set, when the server is shutting down and no longer accepts new posts
+	// or connections
+	draining uint32
+
+	errShuttingDown = common.StatusError{
+		errors.New("server is shutting down"),
+		503,
+	}
+)
+
+func isDraining() bool {
+	return atomic.LoadUint32(&draining) == 1
+}
+
+// Drain stops accepting new posts and connections, closes the open posts of
+// all connected clients and disconnects them with a hint to reconnect.
+// Returns false, if not all clients were drained before the timeout.
+func Drain(timeout time.Duration) bool {
+	atomic.StoreUint32(&draining, 1)
+	return drainClients(feeds.All(), timeout)
+}
+
+// Request all clients to drain and wait for them to disconnect
+func drainClients(clients []common.Client, timeout time.Duration) bool {
+	pending := make([]<-chan struct{}, 0, len(clients))
+	for _, cl := range clients {
+		c, ok := cl.(*Client)
+		if !ok {
+			// Read-only clients have no state to clean up
+			cl.Close(nil)
+			continue
+		}
+		select {
+		case c.drain <- struct{}{}:
+		default:
+			// Already draining
+		}
+		pending = append(pending, c.done)
+	}
+
+	// Clients that exited before processing the request have closed done as
+	// well
+	deadline := time.After(timeout)
+	for _, done := range pending {
+		select {
+		case <-done:
+		case <-deadline:
+			return false
+		}
+	}
+	return true
+}
+
+// Close the open post of the client, if any, and ask the client to
+// reconnect later. Not safe for concurrent use.
+func (c *Client) drainConnection() (err error) {
+	err = c.closePreviousPost()
+	if err != nil {
+		return
+	}
+	err = c.sendMessage(common.MessageNotification, shutdownNotice)
+	if err != nil {
+		return
+	}
+	return c.sendMessage(common.MessageReconnect,
+		reconnectDelay/time.Millisecond)
+}
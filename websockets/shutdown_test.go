@@ -0,0 +1,47 @@
+package websockets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bakape/meguca/common"
+)
+
+func TestDrainExitedClient(t *testing.T) {
+	t.Parallel()
+
+	// Client stopped listening without processing the drain request
+	c := &Client{
+		drain: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	close(c.done)
+
+	res := make(chan bool)
+	go func() {
+		res <- drainClients([]common.Client{c}, time.Minute)
+	}()
+	select {
+	case ok := <-res:
+		if !ok {
+			t.Fatal("client not drained")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting on exited client")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	t.Parallel()
+
+	c := &Client{
+		drain: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	if drainClients([]common.Client{c}, time.Millisecond) {
+		t.Fatal("client drained without exiting")
+	}
+
+	// Repeated requests must not block
+	drainClients([]common.Client{c}, time.Millisecond)
+}
@@ -17,7 +17,10 @@ type syncRequest struct {
 	Last100, Catalog      bool
 	Page, ProtocolVersion uint
 	Thread                uint64
-	Board                 string
+	// Sequence number of the last thread feed message received by the client
+	// before reconnecting, if any
+	Seq   uint64
+	Board string
 }
 
 type reclaimRequest struct {
@@ -25,6 +28,13 @@ type reclaimRequest struct {
 	Password string
 }
 
+type watchRequest struct {
+	// Post counts of watched threads known to the client
+	Threads map[uint64]uint64
+	// Own posts in these threads to watch for replies
+	Posts []uint64
+}
+
 // Synchronise the client to a certain thread, assign it's ID and prepare to
 // receive update messages.
 func (c *Client) synchronise(data []byte) error {
@@ -50,16 +60,12 @@ func (c *Client) synchronise(data []byte) error {
 		return err
 	}
 
-	if msg.ProtocolVersion == common.ProtocolVersion {
-		buf, err := common.EncodeMessage(common.MessageConfigs,
+	if common.IsSupportedProtocolVersion(msg.ProtocolVersion) {
+		err = c.sendMessage(common.MessageConfigs,
 			config.GetBoardConfigs(msg.Board).BoardConfigs)
 		if err != nil {
 			return err
 		}
-		err = c.send(buf)
-		if err != nil {
-			return err
-		}
 	}
 
 	return c.registerSync(msg)
@@ -74,11 +80,11 @@ func (c *Client) registerSync(req syncRequest) (err error) {
 		}
 	}
 
-	c.feed, err = feeds.SyncClient(c, req.Thread, req.Board)
+	c.feed, err = feeds.SyncClient(c, req.Thread, req.Board, req.Seq)
 	if err != nil || req.Thread != 0 {
 		return
 	}
-	if req.ProtocolVersion != common.ProtocolVersion {
+	if !common.IsSupportedProtocolVersion(req.ProtocolVersion) {
 		return c.sendMessage(common.MessageSynchronise, nil)
 	}
 
@@ -138,3 +144,19 @@ func (c *Client) reclaimPost(data []byte) error {
 
 	return c.sendMessage(common.MessageReclaim, 0)
 }
+
+// Register the threads and own posts watched by the client and send it the
+// changes of their post counts since it last checked
+func (c *Client) watchThreads(data []byte) error {
+	var req watchRequest
+	err := decodeMessage(data, &req)
+	if err != nil {
+		return err
+	}
+
+	diff, err := feeds.WatchThreads(c, req.Threads, req.Posts)
+	if err != nil {
+		return err
+	}
+	return c.sendMessage(common.MessageWatchThreads, diff)
+}
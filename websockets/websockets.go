@@ -57,6 +57,19 @@ type Client struct {
 	// Have received first message, which must be a common.MessageSynchronise
 	gotFirstMessage bool
 
+	// Websocket protocol version negotiated with the first
+	// common.MessageSynchronise
+	protocol uint8
+
+	// Last message was slowed down by rate limiting
+	rateLimited bool
+
+	// Messages deferred for exceeding a rate limit in order of receipt
+	throttled []throttledMessage
+
+	// Fires, when the first deferred message can be processed
+	throttleTimer *time.Timer
+
 	// Post currently open by the client
 	post openPost
 
@@ -88,6 +101,13 @@ type Client struct {
 	// Redirect client to target board
 	redirect chan string
 
+	// Close any open post and disconnect the client, because the server is
+	// shutting down
+	drain chan struct{}
+
+	// Closed, when the client has stopped listening and disconnected
+	done chan struct{}
+
 	// Close the client and free all used resources
 	close chan error
 }
@@ -105,6 +125,10 @@ func Handler(w http.ResponseWriter, r *http.Request) (err error) {
 		return
 	}
 
+	if isDraining() {
+		return errShuttingDown
+	}
+
 	// Prevents connection spam
 	err = db.IsBanned("all", ip)
 	if err != nil {
@@ -144,11 +168,14 @@ func newClient(
 	*Client, error,
 ) {
 	return &Client{
+		protocol:       common.MinProtocolVersion,
 		ip:             ip,
 		captchaSession: captchaSession,
 		close:          make(chan error, 2),
 		receive:        make(chan receivedMessage),
 		redirect:       make(chan string),
+		drain:          make(chan struct{}, 1),
+		done:           make(chan struct{}),
 		// Allows for ~60 seconds of messages, until the buffer overflows.
 		// A larger gap is more acceptable to shitty connections and mobile
 		// phones, especially while uploading.
@@ -162,6 +189,7 @@ func (c *Client) listen() error {
 	go c.receiverLoop()
 
 	// Clean up, when loop exits
+	defer close(c.done)
 	err := c.listenerLoop()
 	feeds.RemoveClient(c)
 	return c.closeConnections(err)
@@ -180,7 +208,7 @@ func (c *Client) listenerLoop() error {
 		case err := <-c.close:
 			return err
 		case msg := <-c.sendExternal:
-			if err := c.send(msg); err != nil {
+			if err := c.write(msg); err != nil {
 				return err
 			}
 		case <-ping.C:
@@ -193,6 +221,12 @@ func (c *Client) listenerLoop() error {
 			if err := c.handleMessage(msg.typ, msg.msg); err != nil {
 				return err
 			}
+		case <-c.throttleC():
+			if err := c.runThrottled(); err != nil {
+				return err
+			}
+		case <-c.drain:
+			return c.drainConnection()
 		case board := <-c.redirect:
 			err := c.sendMessage(common.MessageRedirect, "/"+board+"/")
 			if err != nil {
@@ -245,18 +279,45 @@ func (c *Client) closeConnections(err error) error {
 	return err
 }
 
-// Send a message to the client. Can be used concurrently.
+// Send a message encoded in protocol version 1 to the client. Can be used
+// concurrently.
 func (c *Client) Send(msg []byte) {
+	c.SendMessage(common.NewMessage(msg))
+}
+
+// SendMessage sends a message to the client in its protocol version. Can be
+// used concurrently.
+func (c *Client) SendMessage(msg *common.Message) {
+	buf, err := msg.Encode(c.ProtocolVersion())
+	if err != nil {
+		c.Close(err)
+		return
+	}
 	select {
-	case c.sendExternal <- msg:
+	case c.sendExternal <- buf:
 	default:
 		c.Close(errors.New("send buffer overflow"))
 	}
 }
 
-// Sends a message to the client. Not safe for concurrent use.
+// Sends a message encoded in protocol version 1 to the client. Not safe for
+// concurrent use.
 func (c *Client) send(msg []byte) error {
-	return c.conn.WriteMessage(websocket.TextMessage, msg)
+	msg, err := common.TranscodeMessage(c.protocol, msg)
+	if err != nil {
+		return err
+	}
+	return c.write(msg)
+}
+
+// Write a message encoded in the client's protocol version to the
+// connection. Not safe for concurrent use.
+func (c *Client) write(msg []byte) error {
+	typ := websocket.TextMessage
+	if c.protocol > common.MinProtocolVersion {
+		typ = websocket.BinaryMessage
+	}
+	return c.conn.WriteMessage(typ, msg)
 }
 
 // Format a message type as JSON and send it to the client. Not safe for
@@ -293,25 +354,26 @@ func (c *Client) receiverLoop() {
 
 // handleMessage parses a message received from the client through websockets
 func (c *Client) handleMessage(msgType int, msg []byte) (err error) {
-	if msgType != websocket.TextMessage {
-		return errInvalidFrame("only text frames allowed")
-	}
-	if len(msg) < 2 {
-		return errInvalidPayload(msg)
-	}
-
-	// First two characters of a message define its type
-	uncast, err := strconv.ParseUint(string(msg[:2]), 10, 8)
+	typ, data, err := c.decodeFrame(msgType, msg)
 	if err != nil {
-		return errInvalidPayload(msg)
+		return
 	}
-	typ := common.MessageType(uncast)
 	if !c.gotFirstMessage {
-		if typ != common.MessageSynchronise {
+		switch typ {
+		case common.MessageConfigs, common.MessageSynchronise:
+		default:
 			return errInvalidPayload(msg)
 		}
 		c.gotFirstMessage = true
 
+		if typ == common.MessageConfigs {
+			// Must be done before sending any other messages to the client
+			err = c.negotiateProtocol(data)
+			if err != nil {
+				return
+			}
+		}
+
 		// Send current server time on first synchronization
 		err = c.sendMessage(common.MessageServerTime, time.Now().Unix())
 		if err != nil {
@@ -337,9 +399,73 @@ func (c *Client) handleMessage(msgType int, msg []byte) (err error) {
 		if err != nil {
 			return
 		}
+
+		if typ == common.MessageConfigs {
+			// Only negotiates the protocol. The client synchronises next.
+			return
+		}
 	}
 
-	return c.runHandler(typ, msg)
+	return c.runHandler(typ, data)
+}
+
+// Split a received message into its type and JSON payload according to the
+// client's protocol version
+func (c *Client) decodeFrame(msgType int, msg []byte) (
+	typ common.MessageType, data []byte, err error,
+) {
+	if c.protocol > common.MinProtocolVersion {
+		if msgType != websocket.BinaryMessage {
+			err = errInvalidFrame("only binary frames allowed")
+			return
+		}
+		typ, data, err = common.DecodeBinaryMessage(msg)
+		if err != nil {
+			err = errInvalidPayload(msg)
+		}
+		return
+	}
+
+	if msgType != websocket.TextMessage {
+		err = errInvalidFrame("only text frames allowed")
+		return
+	}
+	if len(msg) < 2 {
+		err = errInvalidPayload(msg)
+		return
+	}
+
+	// First two characters of a message define its type
+	uncast, err := strconv.ParseUint(string(msg[:2]), 10, 8)
+	if err != nil {
+		err = errInvalidPayload(msg)
+		return
+	}
+	return common.MessageType(uncast), msg[2:], nil
+}
+
+// Switch the client to the protocol version requested in its first message,
+// if supported, and confirm the protocol version used from now on to the
+// client. The request is always encoded in protocol version 1.
+func (c *Client) negotiateProtocol(data []byte) (err error) {
+	var req struct {
+		ProtocolVersion uint
+	}
+	err = decodeMessage(data, &req)
+	if err != nil {
+		return
+	}
+	if common.IsSupportedProtocolVersion(req.ProtocolVersion) {
+		c.mu.Lock()
+		c.protocol = uint8(req.ProtocolVersion)
+		c.mu.Unlock()
+	}
+
+	return c.sendMessage(common.MessageConfigs, struct {
+		ProtocolVersion uint8 `json:"protocolVersion"`
+	}{
+		ProtocolVersion: c.protocol,
+	})
 }
 
 // logError writes the client's websocket error to the error log (or stdout)
@@ -394,6 +520,14 @@ func (c *Client) IP() string {
 	return c.ip
 }
 
+// ProtocolVersion returns the negotiated websocket protocol version of the
+// client
+func (c *Client) ProtocolVersion() uint8 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.protocol
+}
+
 // LastTime returns the last post time of the client connection.
 func (c *Client) LastTime() int64 {
 	c.mu.RLock()
@@ -25,6 +25,7 @@ import (
 const (
 	invalidMessage   = "invalid message:"
 	onlyText         = "only text frames allowed"
+	onlyBinary       = "only binary frames allowed"
 	closeNormal      = "websocket: close 1000"
 	invalidCharacter = "invalid character"
 )
@@ -251,6 +252,55 @@ func TestHandleMessage(t *testing.T) {
 	assertHandlerError(t, cl, msg, invalidCharacter)
 }
 
+func TestProtocolNegotiation(t *testing.T) {
+	t.Parallel()
+
+	sv := newWSServer(t)
+	defer sv.Close()
+
+	// Unsupported version
+	cl, wcl := sv.NewClient()
+	err := cl.negotiateProtocol([]byte(`{"protocolVersion":99}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, cl.ProtocolVersion(), uint8(1))
+	assertMessage(t, wcl,
+		encodeMessageType(common.MessageConfigs)+`{"protocolVersion":1}`)
+
+	err = cl.negotiateProtocol([]byte(`{"protocolVersion":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, cl.ProtocolVersion(), uint8(2))
+
+	// Confirmation is sent in the negotiated version
+	typ, msg, err := wcl.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, typ, websocket.BinaryMessage)
+	std := append([]byte{byte(common.MessageConfigs), 0x81, 0xaf},
+		"protocolVersion"...)
+	AssertEquals(t, msg, append(std, 0x02))
+
+	// Text frames no longer accepted
+	_, _, err = cl.decodeFrame(websocket.TextMessage, []byte("34"))
+	assertErrorPrefix(t, err, onlyBinary)
+
+	mTyp, data, err := cl.decodeFrame(websocket.BinaryMessage,
+		[]byte{byte(common.MessageAppend), 0x61})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEquals(t, mTyp, common.MessageAppend)
+	AssertEquals(t, string(data), "97")
+
+	_, _, err = cl.decodeFrame(websocket.BinaryMessage,
+		[]byte{byte(common.MessageAppend), 0xa3})
+	assertErrorPrefix(t, err, invalidMessage)
+}
+
 func assertHandlerError(t *testing.T, cl *Client, msg []byte, prefix string) {
 	t.Helper()
 	err := cl.handleMessage(websocket.TextMessage, msg)
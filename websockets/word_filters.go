@@ -0,0 +1,46 @@
+// Application of board word filters to posts
+
+package websockets
+
+import (
+	"bytes"
+	"unicode"
+
+	"github.com/bakape/meguca/common"
+	"github.com/bakape/meguca/config"
+	"github.com/bakape/meguca/db"
+	"github.com/bakape/meguca/parser"
+)
+
+// Report a post matching a flagging word filter for review by the board staff
+func flagPost(id uint64, board, ip string) error {
+	return db.Report(id, board, db.WordFilterReason, ip, false)
+}
+
+// Returns the part of an open post's body up to the last whitespace. The last
+// word of a body, that is still being written, can not be matched against
+// word filters, as it might still be extended into a different word.
+func completeWords(body []byte) []byte {
+	i := bytes.LastIndexFunc(body, unicode.IsSpace)
+	return body[:i+1]
+}
+
+// Returns, if body contains completed text matching a rejecting word filter
+// of the open post's board
+func (c *Client) wordFiltered(body []byte) bool {
+	return parser.CheckWordFilters(c.post.board, config.FilterBody,
+		string(completeWords(body))) != nil
+}
+
+// Notify the client, that a change to its open post's body was rejected by a
+// word filter and not applied. Rejections do not close the connection, so the
+// client can correct the text.
+func (c *Client) rejectBody() error {
+	return c.sendMessage(common.MessageRejectBody, struct {
+		ID   uint64 `json:"id"`
+		Body string `json:"body"`
+	}{
+		ID:   c.post.id,
+		Body: string(c.post.body),
+	})
+}
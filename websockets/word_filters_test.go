@@ -0,0 +1,28 @@
+package websockets
+
+import (
+	"testing"
+
+	. "github.com/bakape/meguca/test"
+)
+
+func TestCompleteWords(t *testing.T) {
+	t.Parallel()
+
+	cases := [...]struct {
+		name, in, out string
+	}{
+		{"empty", "", ""},
+		{"one word", "foo", ""},
+		{"trailing space", "foo ", "foo "},
+		{"unfinished word", "foo\nbar ba", "foo\nbar "},
+	}
+
+	for i := range cases {
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			AssertEquals(t, string(completeWords([]byte(c.in))), c.out)
+		})
+	}
+}